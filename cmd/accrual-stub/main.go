@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/spf13/pflag"
+	"gofemart/internal/accrualstub"
+	config "gofemart/internal/configuration"
+	"gofemart/internal/logger"
+	"gofemart/internal/server"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Заглушка системы начислений для локальной разработки и тестов
+func main() {
+	flags := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	address := flags.StringP("address", "a", envOrDefault("RUN_ADDRESS", "localhost:8081"), "address and port to run accrual stub")
+	scenarioPath := flags.StringP("scenario", "s", envOrDefault("SCENARIO_FILE", ""), "path to JSON or YAML scenario file")
+	logLevel := flags.String("ll", envOrDefault("LOG_LEVEL", config.DefaultLogLevel), "level of logging")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := logger.NewGlobal(*logLevel); err != nil {
+		log.Fatal(err)
+	}
+
+	scenario := accrualstub.NewDefaultScenario()
+	if *scenarioPath != "" {
+		var err error
+		if scenario, err = accrualstub.LoadScenario(*scenarioPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	logger.Log.Infow("Running accrual stub", "address", *address, "scenario", *scenarioPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serv := server.NewServer(ctx, accrualstub.NewStub(scenario).NewRouter(), *address)
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		<-stop
+		serv.Close()
+	}()
+	if err := serv.S.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Log.Error(err)
+	}
+	logger.Log.Info("End accrual stub")
+}
+
+// envOrDefault значение переменной окружения или значение по умолчанию
+func envOrDefault(key string, value string) string {
+	if env, ok := os.LookupEnv(key); ok {
+		return env
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package accrual
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"gofemart/internal/tracing"
+	"net/http"
+	"time"
+)
+
+// getOrdersBatchURL эндпоинт для получения начислений по нескольким заказам
+const getOrdersBatchURL = "/api/orders/batch"
+
+// ErrorBatchUnsupported указывает, что служба начисления не поддерживает пакетный запрос и заказы нужно проверять по одному.
+var ErrorBatchUnsupported = errors.New("accrual service does not support batch requests")
+
+// AccrualBatch запрашиваем статусы нескольких заказов одним запросом.
+// Возвращает ответы по номерам заказов, незарегистрированных заказов в результате нет.
+// Если служба ответила, что пакетный запрос не поддерживается, последующие вызовы сразу возвращают ErrorBatchUnsupported.
+func (p *Proxy) AccrualBatch(ctx context.Context, orders []*models.Order) (map[string]*payloads.Accrual, error) {
+	if p.noBatch.Load() {
+		return nil, ErrorBatchUnsupported
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "accrual.batch", trace.WithAttributes(
+		attribute.String("accrual.provider", p.name),
+		attribute.Int("accrual.orders", len(orders)),
+	))
+	var res map[string]*payloads.Accrual
+	err := p.do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = p.requestAccrualBatch(ctx, orders)
+		return err
+	})
+	tracing.End(span, err)
+	return res, err
+}
+
+// requestAccrualBatch отправляем пакетный запрос статусов заказов в систему начислений
+func (p *Proxy) requestAccrualBatch(ctx context.Context, orders []*models.Order) (map[string]*payloads.Accrual, error) {
+	body := payloads.AccrualBatchRequest{Orders: make([]string, 0, len(orders))}
+	for _, order := range orders {
+		body.Orders = append(body.Orders, order.Number)
+	}
+	logger.Log.Infow("Accrual batch", "orders", len(body.Orders))
+	start := time.Now()
+	response, err := p.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post(getOrdersBatchURL)
+	p.observe(requestKindBatch, start, response, err)
+	if err != nil {
+		return nil, err
+	}
+	switch response.StatusCode() {
+	case http.StatusOK:
+		var items []payloads.Accrual
+		if err := json.Unmarshal(response.Body(), &items); err != nil {
+			return nil, err
+		}
+		res := make(map[string]*payloads.Accrual, len(items))
+		for i := range items {
+			res[items[i].Order] = &items[i]
+		}
+		return res, nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		logger.Log.Infow("Accrual batch unsupported", "status", response.StatusCode())
+		p.noBatch.Store(true)
+		return nil, ErrorBatchUnsupported
+	case http.StatusInternalServerError:
+		logger.Log.Infow("Accrual batch check failed", "status", http.StatusInternalServerError)
+		return nil, ErrorInternalAccrual
+	case http.StatusTooManyRequests:
+		logger.Log.Infow("Too many requests", "status", http.StatusTooManyRequests)
+		return nil, p.tooManyRequestError(response)
+	default:
+		logger.Log.Infow("Unknown batch status", "status", response.StatusCode())
+		return nil, ErrorUnknownStatusRequests
+	}
+}
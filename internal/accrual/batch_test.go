@@ -0,0 +1,126 @@
+package accrual
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAccrualBatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		response []payloads.Accrual
+		header   string
+		want     []string
+		err      error
+		pause    time.Duration
+	}{
+		{
+			name:     "ok",
+			status:   http.StatusOK,
+			response: []payloads.Accrual{{Order: "1", Status: payloads.StatusAccrualProcessed}, {Order: "3", Status: payloads.StatusAccrualProcessing}},
+			want:     []string{"1", "3"},
+		},
+		{
+			name:   "not_found",
+			status: http.StatusNotFound,
+			err:    ErrorBatchUnsupported,
+		},
+		{
+			name:   "method_not_allowed",
+			status: http.StatusMethodNotAllowed,
+			err:    ErrorBatchUnsupported,
+		},
+		{
+			name:   "internal_server_error",
+			status: http.StatusInternalServerError,
+			err:    ErrorInternalAccrual,
+		},
+		{
+			name:   "too_many_requests",
+			status: http.StatusTooManyRequests,
+			header: "30",
+			err:    ErrorTooManyRequests,
+			pause:  30 * time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			router := chi.NewRouter()
+			router.Post(getOrdersBatchURL, func(writer http.ResponseWriter, request *http.Request) {
+				requests++
+				var body payloads.AccrualBatchRequest
+				if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+					t.Error(err)
+				}
+				if len(body.Orders) != 3 {
+					t.Errorf("expected 3 orders in request, got %v", body.Orders)
+				}
+				if tc.header != "" {
+					writer.Header().Set("Retry-After", tc.header)
+				}
+				writer.WriteHeader(tc.status)
+				if tc.response != nil {
+					if err := json.NewEncoder(writer).Encode(tc.response); err != nil {
+						t.Error(err)
+					}
+				}
+			})
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			proxy := &Proxy{
+				pauseDuration: time.Minute,
+				client:        resty.New().SetBaseURL(server.URL),
+			}
+			orders := []*models.Order{{Number: "1"}, {Number: "2"}, {Number: "3"}}
+			res, err := proxy.AccrualBatch(context.Background(), orders)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error %v, got %v", tc.err, err)
+				}
+				var tmrErr *TooManyRequestError
+				if errors.As(err, &tmrErr) && tmrErr.PauseDuration != tc.pause {
+					t.Errorf("expected pause %v, got %v", tc.pause, tmrErr.PauseDuration)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(res) != len(tc.want) {
+				t.Fatalf("expected %d responses, got %v", len(tc.want), res)
+			}
+			for _, number := range tc.want {
+				if res[number] == nil || res[number].Order != number {
+					t.Errorf("expected response for order %s, got %v", number, res)
+				}
+			}
+
+			// Служба без пакетного запроса больше не получает таких запросов
+			_, err = proxy.AccrualBatch(context.Background(), orders)
+			if errors.Is(tc.err, ErrorBatchUnsupported) {
+				if requests != 1 || !errors.Is(err, ErrorBatchUnsupported) {
+					t.Errorf("expected no more batch requests, got %d requests, error %v", requests, err)
+				}
+			} else if requests != 2 {
+				t.Errorf("expected 2 batch requests, got %d", requests)
+			}
+		})
+	}
+}
+
+func TestBatchUnsupportedIsNotServiceFailure(t *testing.T) {
+	if isServiceFailure(ErrorBatchUnsupported) {
+		t.Errorf("expected batch unsupported not to open the circuit breaker")
+	}
+}
@@ -0,0 +1,202 @@
+package accrual
+
+import (
+	"errors"
+	"gofemart/internal/logger"
+	"sync"
+	"time"
+)
+
+// ErrorCircuitOpen указывает, что запросы в сервис начисления временно не отправляются после серии ошибок.
+var ErrorCircuitOpen = errors.New("accrual circuit breaker is open")
+
+// BreakerState состояние автоматического выключателя
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // запросы отправляются, ошибки подсчитываются
+	StateOpen                         // запросы не отправляются до окончания CoolDown
+	StateHalfOpen                     // отправляются пробные запросы, успех закрывает выключатель, ошибка снова открывает
+)
+
+// String текстовое представление состояния для логов и API
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig Конфигурация автоматического выключателя
+type BreakerConfig struct {
+	FailureThreshold    int           // количество ошибок подряд, после которого выключатель открывается
+	CoolDown            time.Duration // время, на которое выключатель открывается
+	HalfOpenMaxRequests int           // количество одновременных пробных запросов в полуоткрытом состоянии
+}
+
+// BreakerStatus состояние автоматического выключателя для отображения
+type BreakerStatus struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+// CircuitBreaker автоматический выключатель запросов в сервис начисления.
+// После FailureThreshold ошибок подряд перестаёт пропускать запросы на CoolDown,
+// затем пропускает пробные запросы и по их результату закрывается или снова открывается.
+type CircuitBreaker struct {
+	mutex            sync.Mutex
+	cnf              BreakerConfig
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+	// generation растёт при каждой смене состояния, результаты запросов, пропущенных в другом состоянии, не учитываются
+	generation uint64
+	now        func() time.Time
+}
+
+// NewCircuitBreaker создает новый закрытый автоматический выключатель
+func NewCircuitBreaker(cnf BreakerConfig) *CircuitBreaker {
+	if cnf.FailureThreshold <= 0 {
+		cnf.FailureThreshold = 1
+	}
+	if cnf.HalfOpenMaxRequests <= 0 {
+		cnf.HalfOpenMaxRequests = 1
+	}
+	return &CircuitBreaker{
+		cnf:   cnf,
+		state: StateClosed,
+		now:   time.Now,
+	}
+}
+
+// Allow проверяем, можно ли отправить запрос, и возвращаем поколение состояния, в котором запрос пропущен.
+// Если выключатель открыт, возвращает ErrorCircuitOpen. После Allow без ошибки нужно вызвать Success или Failure с этим поколением.
+func (b *CircuitBreaker) Allow() (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == StateOpen {
+		if b.now().Before(b.openedAt.Add(b.cnf.CoolDown)) {
+			return 0, ErrorCircuitOpen
+		}
+		b.setState(StateHalfOpen)
+	}
+	if b.state == StateHalfOpen {
+		if b.halfOpenInFlight >= b.cnf.HalfOpenMaxRequests {
+			return 0, ErrorCircuitOpen
+		}
+		b.halfOpenInFlight++
+	}
+	return b.generation, nil
+}
+
+// Success регистрируем успешный запрос, пропущенный в поколении generation
+func (b *CircuitBreaker) Success(generation uint64) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.stale(generation) {
+		return
+	}
+	b.failures = 0
+	if b.state == StateHalfOpen {
+		b.halfOpenInFlight--
+		b.setState(StateClosed)
+	}
+}
+
+// Failure регистрируем неудачный запрос, пропущенный в поколении generation
+func (b *CircuitBreaker) Failure(generation uint64) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.stale(generation) {
+		return
+	}
+	b.failures++
+	switch b.state {
+	case StateHalfOpen:
+		b.halfOpenInFlight--
+		b.open()
+	case StateClosed:
+		if b.failures >= b.cnf.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// OpenFor сколько ещё выключатель будет открыт, 0 если запросы можно отправлять
+func (b *CircuitBreaker) OpenFor() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state != StateOpen {
+		return 0
+	}
+	left := b.openedAt.Add(b.cnf.CoolDown).Sub(b.now())
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Status получаем текущее состояние выключателя
+func (b *CircuitBreaker) Status() BreakerStatus {
+	if b == nil {
+		return BreakerStatus{State: StateClosed.String()}
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	status := BreakerStatus{State: b.state.String(), Failures: b.failures}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.cnf.CoolDown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// stale результат запроса, пропущенного до смены состояния, например до открытия выключателя, вызывается под блокировкой.
+// Такой запрос не был пробным, поэтому не должен ни закрывать, ни снова открывать выключатель.
+func (b *CircuitBreaker) stale(generation uint64) bool {
+	if generation == b.generation {
+		return false
+	}
+	logger.Log.Debugw("Stale accrual circuit breaker result ignored", "generation", generation, "current", b.generation, "state", b.state.String())
+	return true
+}
+
+// open открываем выключатель, вызывается под блокировкой
+func (b *CircuitBreaker) open() {
+	b.openedAt = b.now()
+	b.halfOpenInFlight = 0
+	b.setState(StateOpen)
+}
+
+// setState меняем состояние и пишем об этом в лог, вызывается под блокировкой
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	logger.Log.Warnw("Accrual circuit breaker state changed", "from", b.state.String(), "to", state.String(), "failures", b.failures, "coolDown", b.cnf.CoolDown)
+	b.state = state
+	b.generation++
+}
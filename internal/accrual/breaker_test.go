@@ -0,0 +1,127 @@
+package accrual
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 3, CoolDown: time.Minute})
+	breaker.now = func() time.Time { return now }
+	allow := func() uint64 {
+		t.Helper()
+		generation, err := breaker.Allow()
+		if err != nil {
+			t.Fatalf("expected request to be allowed, got %v", err)
+		}
+		return generation
+	}
+
+	// Ошибки ниже порога не открывают выключатель, успех сбрасывает счётчик
+	for i := 0; i < 2; i++ {
+		breaker.Failure(allow())
+	}
+	breaker.Success(allow())
+	if got := breaker.Status(); got.State != StateClosed.String() || got.Failures != 0 {
+		t.Fatalf("expected closed breaker without failures, got %+v", got)
+	}
+
+	// Порог ошибок открывает выключатель
+	for i := 0; i < 3; i++ {
+		breaker.Failure(allow())
+	}
+	if _, err := breaker.Allow(); !errors.Is(err, ErrorCircuitOpen) {
+		t.Fatalf("expected ErrorCircuitOpen, got %v", err)
+	}
+	if got := breaker.OpenFor(); got != time.Minute {
+		t.Errorf("expected open for %v, got %v", time.Minute, got)
+	}
+	status := breaker.Status()
+	if status.State != StateOpen.String() || status.RetryAt == nil || !status.RetryAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	// После паузы пропускается один пробный запрос, неудача снова открывает выключатель
+	now = now.Add(time.Minute)
+	probe := allow()
+	if _, err := breaker.Allow(); !errors.Is(err, ErrorCircuitOpen) {
+		t.Fatalf("expected only one probe request, got %v", err)
+	}
+	breaker.Failure(probe)
+	if got := breaker.Status().State; got != StateOpen.String() {
+		t.Fatalf("expected open breaker after failed probe, got %s", got)
+	}
+
+	// Удачный пробный запрос закрывает выключатель
+	now = now.Add(time.Minute)
+	probe = allow()
+	if got := breaker.Status().State; got != StateHalfOpen.String() {
+		t.Fatalf("expected half-open breaker, got %s", got)
+	}
+	breaker.Success(probe)
+	if got := breaker.Status().State; got != StateClosed.String() {
+		t.Fatalf("expected closed breaker after successful probe, got %s", got)
+	}
+	if got := breaker.OpenFor(); got != 0 {
+		t.Errorf("expected closed breaker to be available, got %v", got)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	now := time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, CoolDown: time.Minute})
+	breaker.now = func() time.Time { return now }
+
+	// Два запроса пропущены в закрытом состоянии, первый открывает выключатель
+	first, _ := breaker.Allow()
+	second, _ := breaker.Allow()
+	breaker.Failure(first)
+
+	// После паузы пропущен пробный запрос, поздний результат второго запроса его не заменяет
+	now = now.Add(time.Minute)
+	probe, err := breaker.Allow()
+	if err != nil {
+		t.Fatalf("expected probe request to be allowed, got %v", err)
+	}
+	breaker.Success(second)
+	if got := breaker.Status().State; got != StateHalfOpen.String() {
+		t.Fatalf("expected stale success to be ignored, got %s", got)
+	}
+	breaker.Failure(second)
+	if got := breaker.Status().State; got != StateHalfOpen.String() {
+		t.Fatalf("expected stale failure to be ignored, got %s", got)
+	}
+	if _, err := breaker.Allow(); !errors.Is(err, ErrorCircuitOpen) {
+		t.Fatalf("expected stale result not to free probe slot, got %v", err)
+	}
+
+	breaker.Success(probe)
+	if got := breaker.Status().State; got != StateClosed.String() {
+		t.Fatalf("expected closed breaker after successful probe, got %s", got)
+	}
+}
+
+func TestIsServiceFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "success", err: nil, want: false},
+		{name: "not_registered", err: ErrorOrderNotRegistered, want: false},
+		{name: "too_many_requests", err: &TooManyRequestError{InternalError: ErrorTooManyRequests}, want: false},
+		{name: "internal_error", err: ErrorInternalAccrual, want: true},
+		{name: "unknown_status", err: ErrorUnknownStatusRequests, want: true},
+		{name: "connection_refused", err: errors.New("dial tcp: connection refused"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isServiceFailure(tt.err); got != tt.want {
+				t.Errorf("isServiceFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package accrual
+
+import (
+	"context"
+	"gofemart/internal/logger"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+const (
+	// throttleFactor во сколько раз снижается частота запросов после ответа 429
+	throttleFactor = 0.5
+	// minRateFactor доля настроенной частоты, ниже которой частота не снижается
+	minRateFactor = 0.05
+	// recoverFactor доля настроенной частоты, на которую частота повышается за один шаг восстановления
+	recoverFactor = 0.1
+	// recoverInterval минимальное время между шагами восстановления частоты
+	recoverInterval = 10 * time.Second
+)
+
+// AdaptiveLimiter ограничитель частоты запросов по алгоритму token bucket.
+// После ответа 429 частота снижается вдвое, а затем при успешных ответах понемногу восстанавливается до настроенной.
+type AdaptiveLimiter struct {
+	mutex      sync.Mutex
+	limiter    *rate.Limiter
+	maxRate    rate.Limit
+	lastChange time.Time
+	now        func() time.Time
+}
+
+// NewAdaptiveLimiter создает ограничитель на rps запросов в секунду с допустимым всплеском burst.
+// Если rps не положительный, частота запросов не ограничивается.
+func NewAdaptiveLimiter(rps float64, burst int) *AdaptiveLimiter {
+	if rps <= 0 {
+		return &AdaptiveLimiter{limiter: rate.NewLimiter(rate.Inf, 0), maxRate: rate.Inf, now: time.Now}
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &AdaptiveLimiter{
+		limiter: rate.NewLimiter(rate.Limit(rps), burst),
+		maxRate: rate.Limit(rps),
+		now:     time.Now,
+	}
+}
+
+// Wait ждём разрешения на отправку запроса
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	return l.limiter.Wait(ctx)
+}
+
+// Limit текущая частота запросов в секунду
+func (l *AdaptiveLimiter) Limit() rate.Limit {
+	return l.limiter.Limit()
+}
+
+// Throttled снижаем частоту запросов после ответа, что запросов слишком много
+func (l *AdaptiveLimiter) Throttled() {
+	if l.maxRate == rate.Inf {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	current := l.limiter.Limit()
+	limit := max(current*throttleFactor, l.maxRate*minRateFactor)
+	if limit == current {
+		return
+	}
+	now := l.now()
+	l.limiter.SetLimitAt(now, limit)
+	l.lastChange = now
+	logger.Log.Infow("Accrual rate limit decreased", "from", float64(current), "to", float64(limit))
+}
+
+// Succeeded повышаем частоту запросов на шаг после успешного ответа, если с прошлого изменения прошло recoverInterval
+func (l *AdaptiveLimiter) Succeeded() {
+	if l.maxRate == rate.Inf {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	current := l.limiter.Limit()
+	now := l.now()
+	if current >= l.maxRate || now.Sub(l.lastChange) < recoverInterval {
+		return
+	}
+	limit := min(current+l.maxRate*recoverFactor, l.maxRate)
+	l.limiter.SetLimitAt(now, limit)
+	l.lastChange = now
+	logger.Log.Infow("Accrual rate limit increased", "from", float64(current), "to", float64(limit))
+}
@@ -0,0 +1,73 @@
+package accrual
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	now := time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC)
+	limiter := NewAdaptiveLimiter(10, 1)
+	limiter.now = func() time.Time { return now }
+
+	// После 429 частота снижается вдвое, но не ниже минимальной
+	limiter.Throttled()
+	if got := limiter.Limit(); got != 5 {
+		t.Fatalf("expected limit 5 after throttling, got %v", got)
+	}
+	for i := 0; i < 10; i++ {
+		limiter.Throttled()
+	}
+	if got := limiter.Limit(); got != 10*minRateFactor {
+		t.Fatalf("expected minimal limit %v, got %v", 10*minRateFactor, got)
+	}
+
+	// Восстановление не происходит чаще recoverInterval
+	limiter.Succeeded()
+	if got := limiter.Limit(); got != 10*minRateFactor {
+		t.Fatalf("expected limit to stay %v right after throttling, got %v", 10*minRateFactor, got)
+	}
+	now = now.Add(recoverInterval)
+	limiter.Succeeded()
+	if got := limiter.Limit(); got != rate.Limit(10*minRateFactor+10*recoverFactor) {
+		t.Fatalf("expected limit to grow by one step, got %v", got)
+	}
+
+	// Частота восстанавливается не выше настроенной
+	for i := 0; i < 20; i++ {
+		now = now.Add(recoverInterval)
+		limiter.Succeeded()
+	}
+	if got := limiter.Limit(); got != 10 {
+		t.Fatalf("expected limit to recover to 10, got %v", got)
+	}
+}
+
+func TestAdaptiveLimiterUnlimited(t *testing.T) {
+	limiter := NewAdaptiveLimiter(0, 0)
+	limiter.Throttled()
+	if got := limiter.Limit(); got != rate.Inf {
+		t.Fatalf("expected unlimited rate, got %v", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("expected no wait for unlimited rate, got %v", err)
+		}
+	}
+}
+
+func TestAdaptiveLimiterWait(t *testing.T) {
+	limiter := NewAdaptiveLimiter(1, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("expected burst request without waiting, got %v", err)
+	}
+	if err := limiter.Wait(ctx); err == nil {
+		t.Fatal("expected second request to exceed the deadline")
+	}
+}
@@ -1,14 +1,22 @@
 package accrual
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 	"gofemart/internal/logger"
+	"gofemart/internal/metrics"
 	"gofemart/internal/models"
 	"gofemart/internal/payloads"
+	"gofemart/internal/tracing"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -43,39 +51,174 @@ func (e *TooManyRequestError) Unwrap() error {
 
 // Proxy представляет клиент, который обрабатывает связь с внешней службой с возможностями ограничения скорости и паузы.
 type Proxy struct {
+	name          string
+	ctx           context.Context
 	pauseDuration time.Duration
 	client        *resty.Client
-	senderMutex   sync.RWMutex
+	pauseMutex    sync.RWMutex
+	notBefore     time.Time // общий для всех обработчиков срок, раньше которого запросы не отправляются
+	breaker       *CircuitBreaker
+	limiter       *AdaptiveLimiter
+	noBatch       atomic.Bool // служба ответила, что не поддерживает пакетный запрос
 }
 
-// NewProxy создает новый экземпляр Proxy с указанной длительностью паузы и URL-адресом службы начисления
-func NewProxy(pause time.Duration, accrualURL string) *Proxy {
+// ProxyConfig Конфигурация клиента службы начисления
+type ProxyConfig struct {
+	Name       string // имя поставщика начислений в метриках
+	CTX        context.Context
+	Pause      time.Duration // пауза в запросах, если служба ответила, что слишком много запросов
+	AccrualURL string        // адрес службы начисления
+	Breaker    BreakerConfig // конфигурация автоматического выключателя запросов
+	RateLimit  float64       // количество запросов в секунду, если не положительное, то без ограничения
+	RateBurst  int           // допустимое количество запросов сверх RateLimit за раз
+}
+
+// NewProxy создает новый экземпляр Proxy с указанной длительностью паузы, URL-адресом службы начисления,
+// автоматическим выключателем и ограничителем частоты запросов
+func NewProxy(cnf ProxyConfig) *Proxy {
 	client := resty.New()
-	client = client.SetBaseURL(accrualURL)
+	client = client.SetBaseURL(cnf.AccrualURL)
+	// Транспорт создаёт клиентские спаны и передаёт контекст трассировки в заголовках запроса
+	client = client.SetTransport(otelhttp.NewTransport(http.DefaultTransport))
+	ctx := cnf.CTX
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	return &Proxy{
-		pauseDuration: pause,
+		name:          cnf.Name,
+		ctx:           ctx,
+		pauseDuration: cnf.Pause,
 		client:        client,
-		senderMutex:   sync.RWMutex{},
+		pauseMutex:    sync.RWMutex{},
+		breaker:       NewCircuitBreaker(cnf.Breaker),
+		limiter:       NewAdaptiveLimiter(cnf.RateLimit, cnf.RateBurst),
 	}
 }
 
-// Pause Если мы попали в блок от системы, делаем паузу между запросами
+// BreakerStatus состояние автоматического выключателя запросов в сервис начисления
+func (p *Proxy) BreakerStatus() BreakerStatus {
+	return p.breaker.Status()
+}
+
+// BreakerOpenFor сколько ещё запросы в сервис начисления не будут отправляться, 0 если выключатель не открыт
+func (p *Proxy) BreakerOpenFor() time.Duration {
+	return p.breaker.OpenFor()
+}
+
+// Pause Если мы попали в блок от системы, откладываем все запросы как минимум на duration.
+// Вызов не блокируется, запросы ждут общего срока в Accrual. Более ранний срок не сокращает уже назначенный.
 func (p *Proxy) Pause(duration time.Duration) {
-	logger.Log.Infow("Pause", "duration", duration)
-	p.senderMutex.Lock()
-	defer p.senderMutex.RUnlock()
-	<-time.After(duration)
+	p.pauseMutex.Lock()
+	defer p.pauseMutex.Unlock()
+	metrics.AccrualPauses.WithLabelValues(p.name).Observe(duration.Seconds())
+	notBefore := time.Now().Add(duration)
+	if notBefore.After(p.notBefore) {
+		logger.Log.Infow("Pause", "duration", duration, "notBefore", notBefore)
+		p.notBefore = notBefore
+	}
+}
+
+// NotBefore срок, раньше которого запросы в систему начислений не отправляются
+func (p *Proxy) NotBefore() time.Time {
+	p.pauseMutex.RLock()
+	defer p.pauseMutex.RUnlock()
+	return p.notBefore
 }
 
-// Accrual запрашиваем статус заказа в системе начислений
-func (p *Proxy) Accrual(order *models.Order) (*payloads.Accrual, error) {
+// waitNotBefore ждём наступления общего срока паузы.
+// Срок может быть продлён, пока мы ждём, поэтому проверяем его снова после ожидания.
+func (p *Proxy) waitNotBefore(ctx context.Context) error {
+	for {
+		wait := time.Until(p.NotBefore())
+		if wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Accrual запрашиваем статус заказа в системе начислений через автоматический выключатель.
+// Если выключатель открыт, запрос не отправляется и возвращается ErrorCircuitOpen.
+// Перед запросом ждём окончания паузы и разрешения ограничителя частоты, после ответа 429 частота снижается.
+// Контекст ctx передаёт трассировку в запрос, ожидание прерывается и по ctx, и по контексту клиента.
+func (p *Proxy) Accrual(ctx context.Context, order *models.Order) (*payloads.Accrual, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracing.Start(ctx, "accrual.request", order.Number, attribute.String("accrual.provider", p.name))
+	var res *payloads.Accrual
+	err := p.do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = p.requestAccrual(ctx, order)
+		return err
+	})
+	tracing.End(span, err)
+	return res, err
+}
+
+// do выполняем запрос в систему начислений с учётом паузы, ограничителя частоты и автоматического выключателя
+func (p *Proxy) do(ctx context.Context, request func(ctx context.Context) error) error {
+	// Закрытие клиента прерывает ожидание и запрос так же, как отмена ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if p.ctx != nil {
+		stop := context.AfterFunc(p.ctx, cancel)
+		defer stop()
+	}
+	if err := p.waitNotBefore(ctx); err != nil {
+		return err
+	}
+	if p.limiter != nil {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	generation, err := p.breaker.Allow()
+	if err != nil {
+		return err
+	}
+	err = request(ctx)
+	if isServiceFailure(err) {
+		p.breaker.Failure(generation)
+		return err
+	}
+	p.breaker.Success(generation)
+	if p.limiter != nil {
+		var tmrErr *TooManyRequestError
+		if errors.As(err, &tmrErr) {
+			p.limiter.Throttled()
+		} else {
+			p.limiter.Succeeded()
+		}
+	}
+	return err
+}
+
+// isServiceFailure проверяем, говорит ли ошибка о неработоспособности сервиса начисления.
+// Незарегистрированный заказ, ограничение запросов и отсутствие пакетного запроса означают, что сервис отвечает.
+func isServiceFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrorOrderNotRegistered) || errors.Is(err, ErrorBatchUnsupported) {
+		return false
+	}
+	var tmrErr *TooManyRequestError
+	return !errors.As(err, &tmrErr)
+}
+
+// requestAccrual отправляем запрос статуса заказа в систему начислений
+func (p *Proxy) requestAccrual(ctx context.Context, order *models.Order) (*payloads.Accrual, error) {
 	logger.Log.Infow("Accrual", "order", order.Number)
-	p.senderMutex.RLock()
-	defer p.senderMutex.RUnlock()
 	url := getOrderURL + order.Number
-	request := p.client.R()
+	request := p.client.R().SetContext(ctx)
 	request.SetHeader("Content-Type", "application/json")
+	start := time.Now()
 	response, err := request.Get(url)
+	p.observe(requestKindOrder, start, response, err)
 	if err != nil {
 		return nil, err
 	}
@@ -88,16 +231,7 @@ func (p *Proxy) Accrual(order *models.Order) (*payloads.Accrual, error) {
 		return nil, ErrorInternalAccrual
 	case http.StatusTooManyRequests:
 		logger.Log.Infow("Too many requests", "order", order.Number, "status", http.StatusTooManyRequests)
-		pauseDuration := p.pauseDuration
-		if pauseHeader := response.Header().Get("Retry-After"); pauseHeader != "" {
-			pauseHeaderValue, err := time.ParseDuration(pauseHeader)
-			if err == nil {
-				pauseDuration = pauseHeaderValue
-			} else {
-				logger.Log.Error(err)
-			}
-		}
-		return nil, &TooManyRequestError{InternalError: ErrorTooManyRequests, PauseDuration: pauseDuration}
+		return nil, p.tooManyRequestError(response)
 	case http.StatusOK:
 		logger.Log.Infow("Order registered", "order", order.Number, "status", http.StatusOK)
 		return p.processAccrualResponse(response)
@@ -107,6 +241,35 @@ func (p *Proxy) Accrual(order *models.Order) (*payloads.Accrual, error) {
 	}
 }
 
+// Виды запросов в метриках службы начисления
+const (
+	requestKindOrder = "order"
+	requestKindBatch = "batch"
+)
+
+// observe учитываем в метриках код ответа и время запроса в службу начисления
+func (p *Proxy) observe(kind string, start time.Time, response *resty.Response, err error) {
+	code := "error"
+	if err == nil && response != nil {
+		code = strconv.Itoa(response.StatusCode())
+	}
+	metrics.AccrualResponses.WithLabelValues(p.name, kind, code).Inc()
+	metrics.AccrualDuration.WithLabelValues(p.name, kind).Observe(time.Since(start).Seconds())
+}
+
+// tooManyRequestError ошибка ограничения запросов с паузой из заголовка Retry-After или паузой по умолчанию
+func (p *Proxy) tooManyRequestError(response *resty.Response) error {
+	pauseDuration := p.pauseDuration
+	if pauseHeader := response.Header().Get("Retry-After"); pauseHeader != "" {
+		if pauseHeaderValue, ok := ParseRetryAfter(pauseHeader, time.Now()); ok {
+			pauseDuration = pauseHeaderValue
+		} else {
+			logger.Log.Infow("Invalid Retry-After header", "value", pauseHeader)
+		}
+	}
+	return &TooManyRequestError{InternalError: ErrorTooManyRequests, PauseDuration: pauseDuration}
+}
+
 // processAccrualResponse обрабатывает ответ от службы начисления и преобразует его в структуру начисления.
 func (p *Proxy) processAccrualResponse(res *resty.Response) (*payloads.Accrual, error) {
 	// Парсим тело в структуру запроса
@@ -117,3 +280,24 @@ func (p *Proxy) processAccrualResponse(res *resty.Response) (*payloads.Accrual,
 	}
 	return &body, nil
 }
+
+// ParseRetryAfter разбираем заголовок Retry-After во всех формах RFC 9110:
+// количество секунд или HTTP-дата в форматах IMF-fixdate, RFC 850 и asctime.
+// Для даты в прошлом возвращает нулевую паузу. Возвращает false, если значение не удалось разобрать.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if pause := date.Sub(now); pause > 0 {
+		return pause, true
+	}
+	return 0, true
+}
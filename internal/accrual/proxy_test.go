@@ -1,14 +1,23 @@
 package accrual
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gofemart/internal/metrics"
 	"gofemart/internal/models"
 	"gofemart/internal/payloads"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -19,7 +28,7 @@ func TestAccrual(t *testing.T) {
 		status     int
 		response   *payloads.Accrual
 		retryAfter time.Duration
-		hasRetry   bool
+		header     string
 		err        error
 	}{
 		{
@@ -39,12 +48,19 @@ func TestAccrual(t *testing.T) {
 			err:        &TooManyRequestError{InternalError: ErrorTooManyRequests, PauseDuration: time.Minute},
 		},
 		{
-			name:       "too_many_requests_with_retry_after",
+			name:       "too_many_requests_with_retry_after_seconds",
 			status:     http.StatusTooManyRequests,
 			retryAfter: 5 * time.Minute,
-			hasRetry:   true,
+			header:     "300",
 			err:        &TooManyRequestError{InternalError: ErrorTooManyRequests, PauseDuration: 5 * time.Minute},
 		},
+		{
+			name:       "too_many_requests_with_invalid_retry_after",
+			status:     http.StatusTooManyRequests,
+			retryAfter: time.Minute,
+			header:     "5m0s",
+			err:        &TooManyRequestError{InternalError: ErrorTooManyRequests, PauseDuration: time.Minute},
+		},
 		{
 			name:   "unknown_error",
 			status: http.StatusNotFound,
@@ -63,8 +79,8 @@ func TestAccrual(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			router := chi.NewRouter()
 			router.Get(getOrderURL+"1", func(writer http.ResponseWriter, request *http.Request) {
-				if tc.hasRetry {
-					writer.Header().Set("Retry-After", tc.retryAfter.String())
+				if tc.header != "" {
+					writer.Header().Set("Retry-After", tc.header)
 				}
 				writer.WriteHeader(tc.status)
 				if tc.response != nil {
@@ -88,7 +104,7 @@ func TestAccrual(t *testing.T) {
 			order := &models.Order{
 				Number: "1",
 			}
-			res, err := proxy.Accrual(order)
+			res, err := proxy.Accrual(context.Background(), order)
 			if tc.err != nil {
 				var tooManyRequestError *TooManyRequestError
 				if errors.As(err, &tooManyRequestError) && tc.retryAfter != 0 {
@@ -110,3 +126,203 @@ func TestAccrual(t *testing.T) {
 		})
 	}
 }
+
+func TestAccrualMetrics(t *testing.T) {
+	router := chi.NewRouter()
+	router.Get(getOrderURL+"1", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Retry-After", "1")
+		writer.WriteHeader(http.StatusTooManyRequests)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	proxy := &Proxy{
+		name:          "metrics_test",
+		pauseDuration: time.Minute,
+		client:        resty.New().SetBaseURL(server.URL),
+	}
+	var tmrErr *TooManyRequestError
+	if _, err := proxy.Accrual(context.Background(), &models.Order{Number: "1"}); !errors.As(err, &tmrErr) {
+		t.Fatalf("expected too many requests, got %v", err)
+	}
+	proxy.Pause(tmrErr.PauseDuration)
+	if got := testutil.ToFloat64(metrics.AccrualResponses.WithLabelValues("metrics_test", requestKindOrder, "429")); got != 1 {
+		t.Errorf("expected one 429 response in metrics, got %v", got)
+	}
+	if got := testutil.CollectAndCount(metrics.AccrualPauses); got == 0 {
+		t.Errorf("expected pause in metrics")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{
+			name:   "seconds",
+			value:  "120",
+			want:   2 * time.Minute,
+			wantOk: true,
+		},
+		{
+			name:   "zero_seconds",
+			value:  "0",
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:   "imf_fixdate",
+			value:  "Sun, 18 Oct 2026 12:01:30 GMT",
+			want:   90 * time.Second,
+			wantOk: true,
+		},
+		{
+			name:   "rfc850",
+			value:  "Sunday, 18-Oct-26 12:00:10 GMT",
+			want:   10 * time.Second,
+			wantOk: true,
+		},
+		{
+			name:   "asctime",
+			value:  "Sun Oct 18 12:05:00 2026",
+			want:   5 * time.Minute,
+			wantOk: true,
+		},
+		{
+			name:   "date_in_past",
+			value:  "Sun, 18 Oct 2026 11:00:00 GMT",
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:  "negative_seconds",
+			value: "-5",
+		},
+		{
+			name:  "go_duration",
+			value: "5m0s",
+		},
+		{
+			name:  "empty",
+			value: " ",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseRetryAfter(tc.value, now)
+			if ok != tc.wantOk {
+				t.Fatalf("expected ok %v, got %v", tc.wantOk, ok)
+			}
+			if got != tc.want {
+				t.Errorf("expected pause %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAccrualHonorsSharedPause(t *testing.T) {
+	const workers = 8
+	var mutex sync.Mutex
+	var requestedAt []time.Time
+	router := chi.NewRouter()
+	router.Get(getOrderURL+"{number}", func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		requestedAt = append(requestedAt, time.Now())
+		mutex.Unlock()
+		writer.Header().Set("Content-Type", "application/json")
+		if _, err := writer.Write([]byte(`{"order":"1","status":"PROCESSED"}`)); err != nil {
+			t.Error(err)
+		}
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	proxy := &Proxy{
+		ctx:           context.Background(),
+		pauseDuration: time.Minute,
+		client:        resty.New().SetBaseURL(server.URL),
+	}
+	// Один обработчик получил 429, пауза должна действовать на всех
+	proxy.Pause(50 * time.Millisecond)
+	// Более короткая пауза не сокращает уже назначенный срок
+	proxy.Pause(time.Millisecond)
+	notBefore := proxy.NotBefore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := proxy.Accrual(context.Background(), &models.Order{Number: "1"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(requestedAt) != workers {
+		t.Fatalf("expected %d requests, got %d", workers, len(requestedAt))
+	}
+	for _, at := range requestedAt {
+		if at.Before(notBefore) {
+			t.Errorf("request sent at %v before pause deadline %v", at, notBefore)
+		}
+	}
+}
+
+func TestAccrualPauseCanceledByContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	proxy := &Proxy{
+		ctx:           ctx,
+		pauseDuration: time.Minute,
+		client:        resty.New(),
+	}
+	proxy.Pause(time.Hour)
+	cancel()
+	if _, err := proxy.Accrual(context.Background(), &models.Order{Number: "1"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestAccrualPropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		traceparent = request.Header.Get("traceparent")
+		writer.Header().Set("Content-Type", "application/json")
+		if _, err := writer.Write([]byte(`{"order":"1","status":"PROCESSED"}`)); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "checkpool.process")
+	proxy := NewProxy(ProxyConfig{Name: "traced", CTX: context.Background(), AccrualURL: server.URL})
+	if _, err := proxy.Accrual(ctx, &models.Order{Number: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID().String()
+	if !strings.Contains(traceparent, traceID) {
+		t.Errorf("expected traceparent with trace %s, got %q", traceID, traceparent)
+	}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "accrual.request" && span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected accrual span to be a child of the caller span")
+		}
+	}
+}
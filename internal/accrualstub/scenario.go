@@ -0,0 +1,184 @@
+package accrualstub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Псевдостатусы сценария заказа, вместо ответа с начислением возвращают код ответа
+const (
+	StepNotRegistered   = "NOT_REGISTERED"    // 204, заказ не зарегистрирован в системе начислений
+	StepTooManyRequests = "TOO_MANY_REQUESTS" // 429 с заголовком Retry-After
+	StepInternalError   = "INTERNAL_ERROR"    // 500
+)
+
+// ErrorInvalidScenario Ошибка, что сценарий заглушки заполнен неверно
+var ErrorInvalidScenario = errors.New("invalid accrual stub scenario")
+
+// Duration длительность, которая в файле сценария задаётся строкой time.ParseDuration ("150ms") или числом секунд
+type Duration time.Duration
+
+// UnmarshalJSON разбираем длительность из JSON
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+// UnmarshalYAML разбираем длительность из YAML
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+// set устанавливаем длительность из строки или числа секунд
+func (d *Duration) set(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case int:
+		*d = Duration(time.Duration(v) * time.Second)
+	default:
+		return fmt.Errorf("%w: unsupported duration %v", ErrorInvalidScenario, value)
+	}
+	return nil
+}
+
+// Latency диапазон задержки ответа
+type Latency struct {
+	Min Duration `json:"min" yaml:"min"`
+	Max Duration `json:"max" yaml:"max"`
+}
+
+// Burst серия ответов 429: после каждых Every запросов следующие Length запросов получают отказ
+type Burst struct {
+	Every      int      `json:"every" yaml:"every"`
+	Length     int      `json:"length" yaml:"length"`
+	RetryAfter Duration `json:"retryAfter" yaml:"retryAfter"`
+}
+
+// OrderScenario сценарий ответов для конкретного заказа.
+// Каждый запрос переходит к следующему шагу, последний шаг повторяется.
+// Шаг — статус системы начислений или один из псевдостатусов Step*.
+type OrderScenario struct {
+	Steps   []string      `json:"steps" yaml:"steps"`
+	Accrual *models.Money `json:"accrual" yaml:"accrual"`
+}
+
+// Scenario сценарий поведения заглушки системы начислений
+type Scenario struct {
+	Seed            int64                    `json:"seed" yaml:"seed"`                       // начальное значение генератора случайных чисел, 0 — случайное
+	Latency         Latency                  `json:"latency" yaml:"latency"`                 // задержка ответа
+	ErrorRate       float64                  `json:"errorRate" yaml:"errorRate"`             // доля ответов 500
+	TooManyRequests Burst                    `json:"tooManyRequests" yaml:"tooManyRequests"` // серии ответов 429
+	AdvanceRate     float64                  `json:"advanceRate" yaml:"advanceRate"`         // вероятность перехода заказа без сценария к следующему статусу после запроса
+	InvalidRate     float64                  `json:"invalidRate" yaml:"invalidRate"`         // доля заказов без сценария, которые завершаются статусом INVALID
+	AccrualMax      float64                  `json:"accrualMax" yaml:"accrualMax"`           // верхняя граница случайного начисления
+	Orders          map[string]OrderScenario `json:"orders" yaml:"orders"`                   // сценарии конкретных заказов
+	DisableBatch    bool                     `json:"disableBatch" yaml:"disableBatch"`       // не отвечать на пакетный запрос, как служба без его поддержки
+}
+
+// Значения сценария по умолчанию
+const (
+	DefaultAdvanceRate = 0.5
+	DefaultAccrualMax  = 1000
+	DefaultRetryAfter  = 60 * time.Second
+)
+
+// NewDefaultScenario сценарий по умолчанию: заказы без ошибок проходят REGISTERED→PROCESSING→PROCESSED
+func NewDefaultScenario() *Scenario {
+	return &Scenario{
+		AdvanceRate: DefaultAdvanceRate,
+		AccrualMax:  DefaultAccrualMax,
+	}
+}
+
+// LoadScenario загружаем сценарий из файла JSON или YAML, формат определяется по расширению
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scenario := NewDefaultScenario()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, scenario)
+	default:
+		err = json.Unmarshal(data, scenario)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrorInvalidScenario, err)
+	}
+	if err = scenario.Validate(); err != nil {
+		return nil, err
+	}
+	return scenario, nil
+}
+
+// Validate проверяем значения сценария
+func (s *Scenario) Validate() error {
+	if s.Latency.Min < 0 || s.Latency.Max < 0 || (s.Latency.Max != 0 && s.Latency.Max < s.Latency.Min) {
+		return fmt.Errorf("%w: latency range %v..%v", ErrorInvalidScenario, time.Duration(s.Latency.Min), time.Duration(s.Latency.Max))
+	}
+	for name, rate := range map[string]float64{"errorRate": s.ErrorRate, "advanceRate": s.AdvanceRate, "invalidRate": s.InvalidRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%w: %s must be in [0, 1], got %v", ErrorInvalidScenario, name, rate)
+		}
+	}
+	if s.TooManyRequests.Every < 0 || s.TooManyRequests.Length < 0 || s.TooManyRequests.RetryAfter < 0 {
+		return fmt.Errorf("%w: tooManyRequests must not be negative", ErrorInvalidScenario)
+	}
+	if s.AccrualMax < 0 {
+		return fmt.Errorf("%w: accrualMax must not be negative", ErrorInvalidScenario)
+	}
+	for number, order := range s.Orders {
+		if len(order.Steps) == 0 {
+			return fmt.Errorf("%w: order %s has no steps", ErrorInvalidScenario, number)
+		}
+		for _, step := range order.Steps {
+			if !isStep(step) {
+				return fmt.Errorf("%w: order %s has unknown step %s", ErrorInvalidScenario, number, step)
+			}
+		}
+	}
+	return nil
+}
+
+// retryAfterHeader значение заголовка Retry-After в секундах, как его отдаёт система начислений
+func (s *Scenario) retryAfterHeader() string {
+	retryAfter := time.Duration(s.TooManyRequests.RetryAfter)
+	if retryAfter == 0 {
+		retryAfter = DefaultRetryAfter
+	}
+	return strconv.Itoa(int(retryAfter.Seconds()))
+}
+
+// isStep проверяем, что шаг сценария заказа известен
+func isStep(step string) bool {
+	switch step {
+	case payloads.StatusAccrualRegistered, payloads.StatusAccrualProcessing, payloads.StatusAccrualProcessed, payloads.StatusAccrualInvalid,
+		StepNotRegistered, StepTooManyRequests, StepInternalError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package accrualstub
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadScenario(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		wantErr error
+		check   func(t *testing.T, s *Scenario)
+	}{
+		{
+			name: "yaml",
+			file: "scenario.yaml",
+			content: `
+latency: {min: 10ms, max: 1}
+tooManyRequests: {every: 3, length: 1, retryAfter: 2s}
+orders:
+  "1":
+    steps: [REGISTERED, PROCESSED]
+    accrual: 729.98
+`,
+			check: func(t *testing.T, s *Scenario) {
+				if time.Duration(s.Latency.Min) != 10*time.Millisecond || time.Duration(s.Latency.Max) != time.Second {
+					t.Errorf("unexpected latency %+v", s.Latency)
+				}
+				if s.retryAfterHeader() != "2" {
+					t.Errorf("expected Retry-After 2, got %s", s.retryAfterHeader())
+				}
+				if s.Orders["1"].Accrual == nil || s.Orders["1"].Accrual.String() != "729.98" {
+					t.Errorf("unexpected accrual %v", s.Orders["1"].Accrual)
+				}
+				if s.AdvanceRate != DefaultAdvanceRate {
+					t.Errorf("expected default advance rate, got %v", s.AdvanceRate)
+				}
+			},
+		},
+		{
+			name:    "json",
+			file:    "scenario.json",
+			content: `{"errorRate": 0.5, "latency": {"max": "1s"}, "orders": {"1": {"steps": ["NOT_REGISTERED"]}}}`,
+			check: func(t *testing.T, s *Scenario) {
+				if s.ErrorRate != 0.5 || time.Duration(s.Latency.Max) != time.Second {
+					t.Errorf("unexpected scenario %+v", s)
+				}
+				if s.retryAfterHeader() != "60" {
+					t.Errorf("expected default Retry-After 60, got %s", s.retryAfterHeader())
+				}
+			},
+		},
+		{
+			name:    "unknown_step",
+			file:    "unknown.json",
+			content: `{"orders": {"1": {"steps": ["DONE"]}}}`,
+			wantErr: ErrorInvalidScenario,
+		},
+		{
+			name:    "invalid_rate",
+			file:    "rate.yml",
+			content: `errorRate: 2`,
+			wantErr: ErrorInvalidScenario,
+		},
+		{
+			name:    "invalid_latency",
+			file:    "latency.json",
+			content: `{"latency": {"min": "2s", "max": "1s"}}`,
+			wantErr: ErrorInvalidScenario,
+		},
+		{
+			name:    "invalid_duration",
+			file:    "duration.json",
+			content: `{"latency": {"min": true}}`,
+			wantErr: ErrorInvalidScenario,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			s, err := LoadScenario(path)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected error %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			tc.check(t, s)
+		})
+	}
+}
+
+func TestLoadExampleScenario(t *testing.T) {
+	if _, err := LoadScenario("../../cmd/accrual-stub/scenario.example.yaml"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package accrualstub
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// progression статусы, которые последовательно проходит заказ без сценария
+var progression = []string{payloads.StatusAccrualRegistered, payloads.StatusAccrualProcessing}
+
+// orderState текущее состояние заказа в заглушке
+type orderState struct {
+	step    int
+	final   string
+	accrual models.Money
+}
+
+// Stub заглушка системы начислений, отвечающая по сценарию
+type Stub struct {
+	scenario *Scenario
+	mutex    sync.Mutex
+	random   *rand.Rand
+	requests int
+	orders   map[string]*orderState
+	sleep    func(time.Duration)
+}
+
+// NewStub создаём заглушку системы начислений
+func NewStub(scenario *Scenario) *Stub {
+	seed := scenario.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Stub{
+		scenario: scenario,
+		random:   rand.New(rand.NewSource(seed)),
+		orders:   make(map[string]*orderState),
+		sleep:    time.Sleep,
+	}
+}
+
+// NewRouter создаём маршрутизатор заглушки с эндпоинтом системы начислений
+func (s *Stub) NewRouter() chi.Router {
+	router := chi.NewRouter()
+	router.Get("/api/orders/{number}", s.GetOrderHandler)
+	if !s.scenario.DisableBatch {
+		router.Post("/api/orders/batch", s.GetOrdersBatchHandler)
+	}
+	return router
+}
+
+// GetOrderHandler отдаём информацию о расчёте начислений по заказу
+func (s *Stub) GetOrderHandler(response http.ResponseWriter, request *http.Request) {
+	number := chi.URLParam(request, "number")
+	status, accrual, latency := s.next(number)
+	if latency > 0 {
+		s.sleep(latency)
+	}
+	logger.Log.Infow("Accrual stub response", "order", number, "status", status)
+	switch status {
+	case StepNotRegistered:
+		response.WriteHeader(http.StatusNoContent)
+	case StepTooManyRequests, StepInternalError:
+		s.writeFailure(response, status)
+	default:
+		s.writeJSON(response, &payloads.Accrual{Order: number, Status: status, Accrual: accrual})
+	}
+}
+
+// GetOrdersBatchHandler отдаём информацию о расчёте начислений по нескольким заказам.
+// Незарегистрированные заказы в ответ не попадают, отказ по любому заказу из сценария получает весь запрос.
+func (s *Stub) GetOrdersBatchHandler(response http.ResponseWriter, request *http.Request) {
+	var body payloads.AccrualBatchRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	items, failure, latency := s.nextBatch(body.Orders)
+	if latency > 0 {
+		s.sleep(latency)
+	}
+	logger.Log.Infow("Accrual stub batch response", "orders", len(body.Orders), "found", len(items), "failure", failure)
+	if failure != "" {
+		s.writeFailure(response, failure)
+		return
+	}
+	s.writeJSON(response, items)
+}
+
+// nextBatch определяем ответы по заказам пакетного запроса и задержку перед ответом
+func (s *Stub) nextBatch(numbers []string) ([]payloads.Accrual, string, time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	failure, latency := s.nextRequest()
+	if failure != "" {
+		return nil, failure, latency
+	}
+	items := make([]payloads.Accrual, 0, len(numbers))
+	for _, number := range numbers {
+		status, accrual := s.nextOrder(number)
+		switch status {
+		case StepNotRegistered:
+		case StepTooManyRequests, StepInternalError:
+			failure = status
+		default:
+			items = append(items, payloads.Accrual{Order: number, Status: status, Accrual: accrual})
+		}
+	}
+	if failure != "" {
+		return nil, failure, latency
+	}
+	return items, "", latency
+}
+
+// writeFailure отвечаем отказом 429 с заголовком Retry-After или ошибкой 500
+func (s *Stub) writeFailure(response http.ResponseWriter, status string) {
+	if status == StepTooManyRequests {
+		response.Header().Set("Retry-After", s.scenario.retryAfterHeader())
+		response.Header().Set("Content-Type", "text/plain")
+		response.WriteHeader(http.StatusTooManyRequests)
+		_, _ = response.Write([]byte("No more than N requests per minute allowed"))
+		return
+	}
+	response.WriteHeader(http.StatusInternalServerError)
+}
+
+// writeJSON отвечаем телом в формате JSON
+func (s *Stub) writeJSON(response http.ResponseWriter, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		logger.Log.Error(err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	_, _ = response.Write(body)
+}
+
+// next определяем ответ на очередной запрос по заказу и задержку перед ответом
+func (s *Stub) next(number string) (string, models.Money, time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status, latency := s.nextRequest()
+	if status != "" {
+		return status, models.Money{}, latency
+	}
+	status, accrual := s.nextOrder(number)
+	return status, accrual, latency
+}
+
+// nextRequest учитываем очередной запрос, определяем задержку и отказ, который получает весь запрос
+func (s *Stub) nextRequest() (string, time.Duration) {
+	s.requests++
+	latency := s.latency()
+	if burst := s.scenario.TooManyRequests; burst.Every > 0 && burst.Length > 0 {
+		// Каждые Every запросов отвечаем отказом на следующие Length запросов
+		if (s.requests-1)%(burst.Every+burst.Length) >= burst.Every {
+			return StepTooManyRequests, latency
+		}
+	}
+	if s.scenario.ErrorRate > 0 && s.random.Float64() < s.scenario.ErrorRate {
+		return StepInternalError, latency
+	}
+	return "", latency
+}
+
+// nextOrder следующий статус заказа по сценарию заказа или случайный
+func (s *Stub) nextOrder(number string) (string, models.Money) {
+	if order, ok := s.scenario.Orders[number]; ok {
+		return s.nextScripted(number, order)
+	}
+	return s.nextRandom(number)
+}
+
+// nextScripted следующий шаг заказа со сценарием, последний шаг повторяется
+func (s *Stub) nextScripted(number string, order OrderScenario) (string, models.Money) {
+	state := s.state(number)
+	step := order.Steps[min(state.step, len(order.Steps)-1)]
+	state.step++
+	accrual := models.Money{}
+	if step == payloads.StatusAccrualProcessed {
+		if order.Accrual != nil {
+			accrual = *order.Accrual
+		} else {
+			accrual = state.accrual
+		}
+	}
+	return step, accrual
+}
+
+// nextRandom следующий статус заказа без сценария, после ответа заказ с вероятностью AdvanceRate переходит дальше
+func (s *Stub) nextRandom(number string) (string, models.Money) {
+	state := s.state(number)
+	status := state.final
+	if state.step < len(progression) {
+		status = progression[state.step]
+		if s.random.Float64() < s.scenario.AdvanceRate {
+			state.step++
+		}
+	}
+	if status == payloads.StatusAccrualProcessed {
+		return status, state.accrual
+	}
+	return status, models.Money{}
+}
+
+// state получаем состояние заказа, при первом запросе определяем итоговый статус и сумму начисления
+func (s *Stub) state(number string) *orderState {
+	if state, ok := s.orders[number]; ok {
+		return state
+	}
+	state := &orderState{
+		final:   payloads.StatusAccrualProcessed,
+		accrual: models.NewMoney(decimal.NewFromFloat(s.random.Float64() * s.scenario.AccrualMax).Round(2)),
+	}
+	if s.scenario.InvalidRate > 0 && s.random.Float64() < s.scenario.InvalidRate {
+		state.final = payloads.StatusAccrualInvalid
+	}
+	s.orders[number] = state
+	return state
+}
+
+// latency случайная задержка ответа из диапазона сценария
+func (s *Stub) latency() time.Duration {
+	minLatency, maxLatency := time.Duration(s.scenario.Latency.Min), time.Duration(s.scenario.Latency.Max)
+	if maxLatency <= minLatency {
+		return minLatency
+	}
+	return minLatency + time.Duration(s.random.Int63n(int64(maxLatency-minLatency)))
+}
@@ -0,0 +1,159 @@
+package accrualstub
+
+import (
+	"context"
+	"errors"
+	"gofemart/internal/accrual"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestProxy создаём клиент системы начислений приложения, направленный на заглушку
+func newTestProxy(t *testing.T, stub *Stub) *accrual.Proxy {
+	server := httptest.NewServer(stub.NewRouter())
+	t.Cleanup(server.Close)
+	return accrual.NewProxy(accrual.ProxyConfig{
+		CTX:        context.Background(),
+		Pause:      time.Minute,
+		AccrualURL: server.URL,
+	})
+}
+
+func TestStubScriptedOrder(t *testing.T) {
+	accrualSum, err := models.NewMoneyFromString("729.98")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := NewDefaultScenario()
+	scenario.Orders = map[string]OrderScenario{
+		"1": {
+			Steps:   []string{StepNotRegistered, StepInternalError, StepTooManyRequests, payloads.StatusAccrualProcessing, payloads.StatusAccrualProcessed},
+			Accrual: &accrualSum,
+		},
+	}
+	scenario.TooManyRequests.RetryAfter = Duration(2 * time.Second)
+	proxy := newTestProxy(t, NewStub(scenario))
+	order := &models.Order{Number: "1"}
+
+	if _, err := proxy.Accrual(context.Background(), order); !errors.Is(err, accrual.ErrorOrderNotRegistered) {
+		t.Errorf("expected error %v, got %v", accrual.ErrorOrderNotRegistered, err)
+	}
+	if _, err := proxy.Accrual(context.Background(), order); !errors.Is(err, accrual.ErrorInternalAccrual) {
+		t.Errorf("expected error %v, got %v", accrual.ErrorInternalAccrual, err)
+	}
+	var tmrErr *accrual.TooManyRequestError
+	if _, err := proxy.Accrual(context.Background(), order); !errors.As(err, &tmrErr) || tmrErr.PauseDuration != 2*time.Second {
+		t.Errorf("expected too many requests with pause 2s, got %v", err)
+	}
+	if res, err := proxy.Accrual(context.Background(), order); err != nil || res.Status != payloads.StatusAccrualProcessing {
+		t.Errorf("expected status %s, got %v, %v", payloads.StatusAccrualProcessing, res, err)
+	}
+	// Последний шаг повторяется
+	for i := 0; i < 2; i++ {
+		res, err := proxy.Accrual(context.Background(), order)
+		if err != nil || res.Status != payloads.StatusAccrualProcessed || !res.Accrual.Equal(accrualSum.Decimal) {
+			t.Errorf("expected status %s with accrual %s, got %v, %v", payloads.StatusAccrualProcessed, accrualSum, res, err)
+		}
+	}
+}
+
+func TestStubRandomProgression(t *testing.T) {
+	scenario := NewDefaultScenario()
+	scenario.Seed = 1
+	scenario.AdvanceRate = 1
+	proxy := newTestProxy(t, NewStub(scenario))
+	order := &models.Order{Number: "1"}
+
+	want := []string{payloads.StatusAccrualRegistered, payloads.StatusAccrualProcessing, payloads.StatusAccrualProcessed, payloads.StatusAccrualProcessed}
+	var accrualSum *models.Money
+	for _, status := range want {
+		res, err := proxy.Accrual(context.Background(), order)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if res.Status != status {
+			t.Errorf("expected status %s, got %s", status, res.Status)
+		}
+		if status == payloads.StatusAccrualProcessed {
+			if accrualSum != nil && !accrualSum.Equal(res.Accrual.Decimal) {
+				t.Errorf("expected stable accrual %s, got %s", accrualSum, res.Accrual)
+			}
+			accrualSum = &res.Accrual
+		}
+	}
+}
+
+func TestStubTooManyRequestsBurst(t *testing.T) {
+	scenario := NewDefaultScenario()
+	scenario.TooManyRequests = Burst{Every: 2, Length: 1, RetryAfter: Duration(3 * time.Second)}
+	stub := NewStub(scenario)
+	server := httptest.NewServer(stub.NewRouter())
+	defer server.Close()
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i, status := range want {
+		response, err := http.Get(server.URL + "/api/orders/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+		if response.StatusCode != status {
+			t.Errorf("request %d: expected status %d, got %d", i, status, response.StatusCode)
+		}
+		if status == http.StatusTooManyRequests && response.Header.Get("Retry-After") != "3" {
+			t.Errorf("expected Retry-After 3, got %s", response.Header.Get("Retry-After"))
+		}
+	}
+}
+
+func TestStubLatency(t *testing.T) {
+	scenario := NewDefaultScenario()
+	scenario.Seed = 1
+	scenario.Latency = Latency{Min: Duration(10 * time.Millisecond), Max: Duration(20 * time.Millisecond)}
+	stub := NewStub(scenario)
+	var slept time.Duration
+	stub.sleep = func(d time.Duration) {
+		slept = d
+	}
+	stub.GetOrderHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/orders/1", nil))
+	if slept < 10*time.Millisecond || slept >= 20*time.Millisecond {
+		t.Errorf("expected latency in [10ms, 20ms), got %v", slept)
+	}
+}
+
+func TestStubBatch(t *testing.T) {
+	scenario := NewDefaultScenario()
+	scenario.Orders = map[string]OrderScenario{
+		"1": {Steps: []string{payloads.StatusAccrualProcessing}},
+		"2": {Steps: []string{StepNotRegistered}},
+		"3": {Steps: []string{payloads.StatusAccrualInvalid}},
+		"4": {Steps: []string{StepTooManyRequests, payloads.StatusAccrualInvalid}},
+	}
+	proxy := newTestProxy(t, NewStub(scenario))
+
+	res, err := proxy.AccrualBatch(context.Background(), []*models.Order{{Number: "1"}, {Number: "2"}, {Number: "3"}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(res) != 2 || res["1"].Status != payloads.StatusAccrualProcessing || res["3"].Status != payloads.StatusAccrualInvalid {
+		t.Errorf("expected statuses for orders 1 and 3, got %v", res)
+	}
+	// Отказ по одному заказу получает весь пакет
+	var tmrErr *accrual.TooManyRequestError
+	if _, err := proxy.AccrualBatch(context.Background(), []*models.Order{{Number: "1"}, {Number: "4"}}); !errors.As(err, &tmrErr) {
+		t.Errorf("expected too many requests, got %v", err)
+	}
+}
+
+func TestStubBatchDisabled(t *testing.T) {
+	scenario := NewDefaultScenario()
+	scenario.DisableBatch = true
+	proxy := newTestProxy(t, NewStub(scenario))
+	if _, err := proxy.AccrualBatch(context.Background(), []*models.Order{{Number: "1"}, {Number: "2"}}); !errors.Is(err, accrual.ErrorBatchUnsupported) {
+		t.Errorf("expected error %v, got %v", accrual.ErrorBatchUnsupported, err)
+	}
+}
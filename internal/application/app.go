@@ -3,17 +3,27 @@ package application
 import (
 	"context"
 	"errors"
+	"gofemart/internal/accrual"
 	config "gofemart/internal/configuration"
 	database "gofemart/internal/databse"
+	"gofemart/internal/databse/migrations"
+	"gofemart/internal/health"
+	"gofemart/internal/idempotency"
 	"gofemart/internal/logger"
+	"gofemart/internal/metrics"
+	"gofemart/internal/notifier"
 	"gofemart/internal/ordercheck"
 	"gofemart/internal/router"
 	"gofemart/internal/server"
+	"gofemart/internal/services"
+	"gofemart/internal/token"
+	"gofemart/internal/tracing"
 	"golang.org/x/sync/errgroup"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 // New производим старт приложения
@@ -24,6 +34,37 @@ func New(cnf *config.CliConfig) error {
 		cancel()
 	}()
 
+	// Настраиваем трассировку до создания клиентов, чтобы они взяли настроенный провайдер
+	shutdownTracing, err := tracing.Init(ctx, tracing.Config{
+		Exporter:    cnf.TracingExporter,
+		Endpoint:    cnf.TracingEndpoint,
+		ServiceName: "gofemart",
+		SampleRatio: cnf.TracingSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Контекст приложения к этому моменту отменён, отправляем оставшиеся спаны с собственным сроком
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.Log.Error(err)
+		}
+	}()
+
+	// Загружаем ключи подписи токенов до подключения к базе, чтобы ошибка в ключах останавливала запуск сразу
+	keyRing, err := token.LoadKeyRing(cnf.JWTKeysDir, cnf.JWTActiveKeyID, cnf.JWTKeys)
+	if err != nil {
+		return err
+	}
+
+	// Доставка токенов сброса пароля
+	resetNotifier, err := notifier.New(cnf.PasswordResetNotifier, cnf.PasswordResetFile)
+	if err != nil {
+		return err
+	}
+
 	pool, err := database.NewDB(cnf.DatabaseDSN, cnf.DBMaxConnections, cnf.DBMaxIdleConnections)
 	// Инициализируем базу данных
 	if err != nil {
@@ -35,20 +76,104 @@ func New(cnf *config.CliConfig) error {
 	if err = pool.Migrate(); err != nil {
 		return err
 	}
+	// Версию последней встроенной миграции считаем один раз для проверки готовности
+	latestMigration, err := migrations.Latest()
+	if err != nil {
+		return err
+	}
 
-	ordercheck.CheckPool = ordercheck.NewPool(ordercheck.PoolConfig{
+	defaultProxy := accrual.ProxyConfig{
+		Pause:      cnf.AccrualSenderPause,
+		AccrualURL: cnf.AccrualSystemAddress,
+		Breaker: accrual.BreakerConfig{
+			FailureThreshold: cnf.AccrualBreakerThreshold,
+			CoolDown:         cnf.AccrualBreakerCoolDown,
+		},
+		RateLimit: cnf.AccrualRateLimit,
+		RateBurst: cnf.AccrualRateBurst,
+	}
+	// Дополнительные поставщики начислений наследуют незаданные настройки основного
+	providers, err := ordercheck.ParseProviderConfigs(cnf.AccrualProviders, defaultProxy)
+	if err != nil {
+		return err
+	}
+	ordercheck.CheckPool, err = ordercheck.NewPool(ordercheck.PoolConfig{
 		CTX:             ctx,
 		QueueSize:       cnf.QueueSize,
 		WorkerCount:     cnf.WorkerCount,
-		Pause:           cnf.AccrualSenderPause,
-		AccrualURL:      cnf.AccrualSystemAddress,
+		Pause:           defaultProxy.Pause,
+		AccrualURL:      defaultProxy.AccrualURL,
 		DBExecutor:      pool.DBx,
 		DBCheckDuration: cnf.DBCheckDuration,
+		MaxAttempts:     cnf.OrderMaxAttempts,
+		LeaseDuration:   cnf.OrderLeaseDuration,
+		Breaker:         defaultProxy.Breaker,
+		RateLimit:       defaultProxy.RateLimit,
+		RateBurst:       defaultProxy.RateBurst,
+		BatchSize:       cnf.AccrualBatchSize,
+		Providers:       providers,
 	})
+	if err != nil {
+		return err
+	}
 	defer ordercheck.CheckPool.Close()
+	// Регистрируем метрики пула соединений базы данных и пула проверки заказов
+	if err = metrics.RegisterDB(pool.DBx.DB, "gofemart"); err != nil {
+		return err
+	}
+	if err = metrics.RegisterPool(ordercheck.CheckPool); err != nil {
+		return err
+	}
 
 	wg := new(errgroup.Group)
-	serv := server.NewServer(ctx, router.NewRouter(pool, cnf), cnf.Address)
+	// Запускаем сверку сохранённых балансов с транзакциями
+	reconciler := services.NewBalanceReconciler(ctx, pool.DBx)
+	wg.Go(func() error {
+		reconciler.Run(cnf.BalanceReconcileDuration, cnf.BalanceReconcileOnStart)
+		return nil
+	})
+	// Запускаем удаление устаревших ключей идемпотентности
+	wg.Go(func() error {
+		idempotency.RunCleanup(ctx, pool.DBx, cnf.IdempotencyKeyTTL)
+		return nil
+	})
+	probe := health.NewProbe(cnf.ReadinessCheckTimeout,
+		health.DatabaseCheck(pool.DBx.DB),
+		health.MigrationsCheck(pool.DBx.DB, latestMigration),
+		health.PoolCheck(ordercheck.CheckPool),
+		health.AccrualCheck(ordercheck.CheckPool, !cnf.ReadinessRequireAccrual),
+	)
+	// Запускаем удаление истёкших сессий
+	wg.Go(func() error {
+		services.RunSessionCleanup(ctx, pool.DBx)
+		return nil
+	})
+	// Защита входа от подбора пароля, устаревшие попытки периодически удаляются
+	loginGuard := services.NewLoginGuard(services.LoginGuardConfig{
+		Window:       cnf.LoginWindow,
+		FreeAttempts: cnf.LoginFreeAttempts,
+		BaseDelay:    cnf.LoginBaseDelay,
+		MaxDelay:     cnf.LoginMaxDelay,
+		MaxFailures:  cnf.LoginMaxFailures,
+		LockDuration: cnf.LoginLockDuration,
+	})
+	wg.Go(func() error {
+		loginGuard.RunCleanup(ctx)
+		return nil
+	})
+	// Ограничение запросов сброса пароля, устаревшие запросы периодически удаляются
+	resetLimiter := services.NewResetLimiter(services.ResetLimiterConfig{
+		Window:   cnf.PasswordResetWindow,
+		PerLogin: cnf.PasswordResetPerLogin,
+		PerIP:    cnf.PasswordResetPerIP,
+	})
+	wg.Go(func() error {
+		resetLimiter.RunCleanup(ctx)
+		return nil
+	})
+	// Фоновая доставка токенов сброса пароля завершается до остановки приложения
+	resetTasks := services.NewBackgroundTasks()
+	serv := server.NewServer(ctx, router.NewRouter(pool, cnf, probe, keyRing, loginGuard, resetLimiter, resetTasks, resetNotifier), cnf.Address)
 	// Запускаем сервер
 	wg.Go(func() error {
 		sErr := serv.S.ListenAndServe()
@@ -61,9 +186,17 @@ func New(cnf *config.CliConfig) error {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
+	// Сначала сообщаем оркестратору, что экземпляр не готов, и даём время убрать его из балансировки
+	probe.Shutdown()
+	if cnf.ShutdownDelay > 0 {
+		logger.Log.Infow("Waiting before stopping server", "delay", cnf.ShutdownDelay)
+		time.Sleep(cnf.ShutdownDelay)
+	}
 	logger.Log.Info("Stopping server")
 	cancel()
 	serv.Close()
+	// Дожидаемся отправки уже созданных токенов сброса пароля, пока база данных ещё доступна
+	resetTasks.Wait()
 
 	// Ожидаем завершения всех горутин перед завершением программы
 	if err = wg.Wait(); err != nil {
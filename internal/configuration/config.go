@@ -21,8 +21,8 @@ const (
 	DefaultPrivateKeyPath = ""
 	// DefaultPublicKeyPath Путь к публичному ключу для JWT по умолчанию
 	DefaultPublicKeyPath = ""
-	// DefaultTokenExpiration Время жизни токена авторизации по умолчанию
-	DefaultTokenExpiration = 12 * time.Hour
+	// DefaultTokenExpiration Время жизни токена доступа по умолчанию, после него токен обновляется токеном обновления
+	DefaultTokenExpiration = 15 * time.Minute
 	// DefaultAccrualSenderPause пауза в запросах к сервису начислений, если он ответил ответом, что слишком много запросов
 	DefaultAccrualSenderPause = time.Minute
 	// DefaultQueueSize количество заказов, которые одновременно могут находиться в очереди на проверке, если очередь заполнена, то они будут отложены
@@ -35,6 +35,74 @@ const (
 	DefaultDBMaxConnections = 2
 	// DefaultDBMaxIdleConnections максимальное количество бездействующих подключений к базе данных в пуле соединений
 	DefaultDBMaxIdleConnections = 2
+	// DefaultBalanceReconcileDuration период сверки сохранённых балансов пользователей с транзакциями
+	DefaultBalanceReconcileDuration = 24 * time.Hour
+	// DefaultIdempotencyKeyTTL время хранения ответов на запросы с ключом идемпотентности
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+	// DefaultOrderMaxAttempts количество неудачных проверок заказа подряд, после которого проверки заказа останавливаются
+	DefaultOrderMaxAttempts = 20
+	// DefaultAdminToken токен доступа к административному API по умолчанию, пустой токен отключает административный API
+	DefaultAdminToken = ""
+	// DefaultOrderLeaseDuration время, на которое заказ закрепляется за экземпляром приложения для проверки
+	DefaultOrderLeaseDuration = 30 * time.Second
+	// DefaultAccrualBreakerThreshold количество ошибок системы расчёта начислений подряд, после которого запросы в неё приостанавливаются
+	DefaultAccrualBreakerThreshold = 5
+	// DefaultAccrualBreakerCoolDown время, на которое приостанавливаются запросы в систему расчёта начислений после серии ошибок
+	DefaultAccrualBreakerCoolDown = 30 * time.Second
+	// DefaultAccrualRateLimit количество запросов в секунду к системе расчёта начислений, 0 без ограничения
+	DefaultAccrualRateLimit = 0
+	// DefaultAccrualRateBurst допустимое количество запросов к системе расчёта начислений сверх ограничения за раз
+	DefaultAccrualRateBurst = 1
+	// DefaultAccrualProviders дополнительные поставщики начислений в формате JSON, по умолчанию не заданы
+	DefaultAccrualProviders = ""
+	// DefaultAccrualBatchSize количество заказов в одном запросе к системе расчёта начислений, по умолчанию заказы проверяются по одному
+	DefaultAccrualBatchSize = 0
+	// DefaultTracingExporter экспортёр трассировки: none, stdout или otlp, по умолчанию трассировка выключена
+	DefaultTracingExporter = "none"
+	// DefaultTracingEndpoint адрес OTLP/HTTP коллектора трассировки
+	DefaultTracingEndpoint = "http://localhost:4318"
+	// DefaultTracingSampleRatio доля трассируемых запросов, по умолчанию трассируются все
+	DefaultTracingSampleRatio = 1
+	// DefaultReadinessCheckTimeout время на выполнение одной проверки готовности
+	DefaultReadinessCheckTimeout = 2 * time.Second
+	// DefaultReadinessRequireAccrual приложение не готово, пока выключатели открыты у всех поставщиков начислений, по умолчанию состояние только показывается
+	DefaultReadinessRequireAccrual = false
+	// DefaultShutdownDelay время между переходом в неготовое состояние и остановкой сервера, чтобы оркестратор успел убрать экземпляр из балансировки
+	DefaultShutdownDelay = 0
+	// DefaultRefreshTokenExpiration время жизни токена обновления и сессии без обновлений
+	DefaultRefreshTokenExpiration = 30 * 24 * time.Hour
+	// DefaultJWTKeysDir каталог ключей подписи JWT, по умолчанию используется единственный ключ из PKEY и PUKEY
+	DefaultJWTKeysDir = ""
+	// DefaultJWTActiveKeyID идентификатор ключа подписи новых токенов, по умолчанию ключ с наибольшим kid
+	DefaultJWTActiveKeyID = ""
+	// DefaultLoginWindow окно, в котором считаются неудачные попытки входа с одного адреса для одного логина
+	DefaultLoginWindow = 15 * time.Minute
+	// DefaultLoginFreeAttempts количество неудачных попыток входа в окне без задержки
+	DefaultLoginFreeAttempts = 3
+	// DefaultLoginBaseDelay задержка после первой лишней неудачной попытки входа, дальше удваивается
+	DefaultLoginBaseDelay = time.Second
+	// DefaultLoginMaxDelay наибольшая задержка между неудачными попытками входа
+	DefaultLoginMaxDelay = 5 * time.Minute
+	// DefaultLoginMaxFailures количество неудачных входов подряд, после которого вход пользователя блокируется
+	DefaultLoginMaxFailures = 10
+	// DefaultLoginLockDuration время блокировки входа пользователя после подбора пароля
+	DefaultLoginLockDuration = 15 * time.Minute
+	// DefaultPasswordResetNotifier способ доставки токенов сброса пароля: log или file
+	DefaultPasswordResetNotifier = "log"
+	// DefaultPasswordResetFile файл, в который дописываются уведомления о сбросе пароля при доставке file
+	DefaultPasswordResetFile = "password_reset.jsonl"
+	// DefaultPasswordResetExpiration время жизни токена сброса пароля
+	DefaultPasswordResetExpiration = time.Hour
+	// DefaultIdempotencyProcessingTimeout время, после которого незавершённый запрос с ключом идемпотентности считается брошенным
+	DefaultIdempotencyProcessingTimeout = time.Minute
+	// DefaultBalanceReconcileOnStart выполнять ли сверку сохранённых балансов с транзакциями при старте
+	DefaultBalanceReconcileOnStart = false
+	// DefaultPasswordResetWindow окно, в котором ограничивается количество запросов сброса пароля
+	DefaultPasswordResetWindow = time.Hour
+	// DefaultPasswordResetPerLogin количество запросов сброса пароля в окне для одного логина
+	DefaultPasswordResetPerLogin = 3
+	// DefaultPasswordResetPerIP количество запросов сброса пароля в окне с одного адреса
+	DefaultPasswordResetPerIP = 20
 )
 
 // DefaultPrivateKey Текстовое представление приватного ключа для JWT по умолчанию
@@ -54,43 +122,111 @@ type JWTKeys struct {
 
 // CliConfig конфигурация сервера из командной строки
 type CliConfig struct {
-	Address              string        `env:"RUN_ADDRESS"`             // адрес сервера
-	LogLevel             string        `env:"LOG_LEVEL"`               // Уровень логирования
-	DatabaseDSN          string        `env:"DATABASE_URI"`            // подключение к базе данных
-	AccrualSystemAddress string        `env:"ACCRUAL_SYSTEM_ADDRESS"`  // адрес системы расчёта начислений
-	HashKey              string        `env:"KEY"`                     // Ключ для шифрования
-	PrivateKeyPath       string        `env:"PKEYP"`                   // Путь к приватному ключу для JWT
-	PublicKeyPath        string        `env:"PUKEYP"`                  // Путь к публичному ключу для JWT
-	PrivateKey           string        `env:"PKEY"`                    // Приватный ключ для JWT
-	PublicKey            string        `env:"PUKEY"`                   // Публичный ключ для JWT
-	JWTKeys              *JWTKeys      `env:"-"`                       // Ключи для JWT
-	TokenExpiration      time.Duration `env:"TOKEN_EXPIRATION"`        // Время жизни токена авторизации
-	AccrualSenderPause   time.Duration `env:"ACCRUAL_SENDER_PAUSE"`    // пауза в запросах к сервису начислений, если он ответил ответом, что слишком много запросов
-	QueueSize            int           `env:"QUEUE_SIZE"`              // количество заказов, которые одновременно могут находиться в очереди на проверке, если очередь заполнена, то они будут отложены
-	WorkerCount          int           `env:"WORKER_COUNT"`            // количество обработчиков заказов
-	DBCheckDuration      time.Duration `env:"DB_CHECK_DURATION"`       // период в который проверяется база данных на необработанные заказы
-	DBMaxConnections     int           `env:"DB_MAX_CONNECTIONS"`      // максимальное количество подключений к базе данных в пуле соединений
-	DBMaxIdleConnections int           `env:"DB_MAX_IDLE_CONNECTIONS"` // максимальное количество бездействующих подключений к базе данных в пуле соединений
+	Address                      string        `env:"RUN_ADDRESS"`                    // адрес сервера
+	LogLevel                     string        `env:"LOG_LEVEL"`                      // Уровень логирования
+	DatabaseDSN                  string        `env:"DATABASE_URI"`                   // подключение к базе данных
+	AccrualSystemAddress         string        `env:"ACCRUAL_SYSTEM_ADDRESS"`         // адрес системы расчёта начислений
+	HashKey                      string        `env:"KEY"`                            // Ключ для проверки устаревших хэшей паролей HMAC-SHA256
+	PrivateKeyPath               string        `env:"PKEYP"`                          // Путь к приватному ключу для JWT
+	PublicKeyPath                string        `env:"PUKEYP"`                         // Путь к публичному ключу для JWT
+	PrivateKey                   string        `env:"PKEY"`                           // Приватный ключ для JWT
+	PublicKey                    string        `env:"PUKEY"`                          // Публичный ключ для JWT
+	JWTKeys                      *JWTKeys      `env:"-"`                              // Ключи для JWT
+	TokenExpiration              time.Duration `env:"TOKEN_EXPIRATION"`               // Время жизни токена авторизации
+	AccrualSenderPause           time.Duration `env:"ACCRUAL_SENDER_PAUSE"`           // пауза в запросах к сервису начислений, если он ответил ответом, что слишком много запросов
+	QueueSize                    int           `env:"QUEUE_SIZE"`                     // количество заказов, которые одновременно могут находиться в очереди на проверке, если очередь заполнена, то они будут отложены
+	WorkerCount                  int           `env:"WORKER_COUNT"`                   // количество обработчиков заказов
+	DBCheckDuration              time.Duration `env:"DB_CHECK_DURATION"`              // период в который проверяется база данных на необработанные заказы
+	DBMaxConnections             int           `env:"DB_MAX_CONNECTIONS"`             // максимальное количество подключений к базе данных в пуле соединений
+	DBMaxIdleConnections         int           `env:"DB_MAX_IDLE_CONNECTIONS"`        // максимальное количество бездействующих подключений к базе данных в пуле соединений
+	BalanceReconcileDuration     time.Duration `env:"BALANCE_RECONCILE_DURATION"`     // период сверки сохранённых балансов с транзакциями, если не положительный, то периодическая сверка отключена
+	IdempotencyKeyTTL            time.Duration `env:"IDEMPOTENCY_KEY_TTL"`            // время хранения ответов на запросы с ключом идемпотентности
+	OrderMaxAttempts             int           `env:"ORDER_MAX_ATTEMPTS"`             // количество неудачных проверок заказа подряд, после которого проверки заказа останавливаются
+	AdminToken                   string        `env:"ADMIN_TOKEN"`                    // токен доступа к административному API, если пустой, то административный API отключён
+	OrderLeaseDuration           time.Duration `env:"ORDER_LEASE_DURATION"`           // время, на которое заказ закрепляется за экземпляром приложения для проверки
+	AccrualBreakerThreshold      int           `env:"ACCRUAL_BREAKER_THRESHOLD"`      // количество ошибок системы расчёта начислений подряд, после которого запросы в неё приостанавливаются
+	AccrualBreakerCoolDown       time.Duration `env:"ACCRUAL_BREAKER_COOL_DOWN"`      // время, на которое приостанавливаются запросы в систему расчёта начислений после серии ошибок
+	AccrualRateLimit             float64       `env:"ACCRUAL_RATE_LIMIT"`             // количество запросов в секунду к системе расчёта начислений, если не положительное, то без ограничения
+	AccrualRateBurst             int           `env:"ACCRUAL_RATE_BURST"`             // допустимое количество запросов к системе расчёта начислений сверх ограничения за раз
+	AccrualProviders             string        `env:"ACCRUAL_PROVIDERS"`              // дополнительные поставщики начислений: JSON массив с name, url, prefixes, pause, breakerThreshold, breakerCoolDown, rateLimit, rateBurst
+	AccrualBatchSize             int           `env:"ACCRUAL_BATCH_SIZE"`             // количество заказов в одном запросе к системе расчёта начислений, если не больше 1, то заказы проверяются по одному
+	TracingExporter              string        `env:"TRACING_EXPORTER"`               // экспортёр трассировки: none, stdout или otlp
+	TracingEndpoint              string        `env:"TRACING_ENDPOINT"`               // адрес OTLP/HTTP коллектора трассировки
+	TracingSampleRatio           float64       `env:"TRACING_SAMPLE_RATIO"`           // доля трассируемых запросов от 0 до 1
+	ReadinessCheckTimeout        time.Duration `env:"READINESS_CHECK_TIMEOUT"`        // время на выполнение одной проверки готовности
+	ReadinessRequireAccrual      bool          `env:"READINESS_REQUIRE_ACCRUAL"`      // приложение не готово, пока выключатели открыты у всех поставщиков начислений
+	ShutdownDelay                time.Duration `env:"SHUTDOWN_DELAY"`                 // время между переходом в неготовое состояние и остановкой сервера
+	RefreshTokenExpiration       time.Duration `env:"REFRESH_TOKEN_EXPIRATION"`       // время жизни токена обновления и сессии без обновлений
+	JWTKeysDir                   string        `env:"JWT_KEYS_DIR"`                   // Каталог ключей подписи JWT в формате PEM, имя файла без расширения становится kid
+	JWTActiveKeyID               string        `env:"JWT_ACTIVE_KID"`                 // Идентификатор ключа подписи новых токенов из каталога ключей
+	LoginWindow                  time.Duration `env:"LOGIN_WINDOW"`                   // окно подсчёта неудачных попыток входа по логину и адресу
+	LoginFreeAttempts            int           `env:"LOGIN_FREE_ATTEMPTS"`            // неудачные попытки входа в окне без задержки
+	LoginBaseDelay               time.Duration `env:"LOGIN_BASE_DELAY"`               // начальная задержка между неудачными попытками входа
+	LoginMaxDelay                time.Duration `env:"LOGIN_MAX_DELAY"`                // наибольшая задержка между неудачными попытками входа
+	LoginMaxFailures             int           `env:"LOGIN_MAX_FAILURES"`             // неудачные входы подряд до блокировки пользователя
+	LoginLockDuration            time.Duration `env:"LOGIN_LOCK_DURATION"`            // время блокировки входа пользователя
+	PasswordResetNotifier        string        `env:"PASSWORD_RESET_NOTIFIER"`        // способ доставки токенов сброса пароля: log или file
+	PasswordResetFile            string        `env:"PASSWORD_RESET_FILE"`            // файл уведомлений о сбросе пароля
+	PasswordResetExpiration      time.Duration `env:"PASSWORD_RESET_EXPIRATION"`      // время жизни токена сброса пароля
+	IdempotencyProcessingTimeout time.Duration `env:"IDEMPOTENCY_PROCESSING_TIMEOUT"` // время, после которого незавершённый запрос с ключом идемпотентности считается брошенным и ключ можно занять заново
+	BalanceReconcileOnStart      bool          `env:"BALANCE_RECONCILE_ON_START"`     // выполнять сверку сохранённых балансов с транзакциями при старте приложения
+	PasswordResetWindow          time.Duration `env:"PASSWORD_RESET_WINDOW"`          // окно, в котором ограничивается количество запросов сброса пароля
+	PasswordResetPerLogin        int           `env:"PASSWORD_RESET_PER_LOGIN"`       // количество запросов сброса пароля в окне для одного логина с любых адресов
+	PasswordResetPerIP           int           `env:"PASSWORD_RESET_PER_IP"`          // количество запросов сброса пароля в окне с одного адреса для любых логинов
 }
 
 // NewDefaultConfig инициализация конфигурации приложения
 func NewDefaultConfig() *CliConfig {
 	return &CliConfig{
-		Address:              DefaultServerURL,
-		LogLevel:             DefaultLogLevel,
-		DatabaseDSN:          DefaultDatabaseDSN,
-		AccrualSystemAddress: DefaultAccrualSystemAddress,
-		HashKey:              DefaultHashKey,
-		PrivateKeyPath:       DefaultPrivateKeyPath,
-		PublicKeyPath:        DefaultPublicKeyPath,
-		TokenExpiration:      DefaultTokenExpiration,
-		PrivateKey:           DefaultPrivateKey,
-		PublicKey:            DefaultPublicKey,
-		AccrualSenderPause:   DefaultAccrualSenderPause,
-		QueueSize:            DefaultQueueSize,
-		WorkerCount:          DefaultWorkerCount,
-		DBCheckDuration:      DefaultDBCheckDuration,
-		DBMaxConnections:     DefaultDBMaxConnections,
-		DBMaxIdleConnections: DefaultDBMaxIdleConnections,
+		Address:                      DefaultServerURL,
+		LogLevel:                     DefaultLogLevel,
+		DatabaseDSN:                  DefaultDatabaseDSN,
+		AccrualSystemAddress:         DefaultAccrualSystemAddress,
+		HashKey:                      DefaultHashKey,
+		PrivateKeyPath:               DefaultPrivateKeyPath,
+		PublicKeyPath:                DefaultPublicKeyPath,
+		TokenExpiration:              DefaultTokenExpiration,
+		PrivateKey:                   DefaultPrivateKey,
+		PublicKey:                    DefaultPublicKey,
+		AccrualSenderPause:           DefaultAccrualSenderPause,
+		QueueSize:                    DefaultQueueSize,
+		WorkerCount:                  DefaultWorkerCount,
+		DBCheckDuration:              DefaultDBCheckDuration,
+		DBMaxConnections:             DefaultDBMaxConnections,
+		DBMaxIdleConnections:         DefaultDBMaxIdleConnections,
+		BalanceReconcileDuration:     DefaultBalanceReconcileDuration,
+		IdempotencyKeyTTL:            DefaultIdempotencyKeyTTL,
+		OrderMaxAttempts:             DefaultOrderMaxAttempts,
+		AdminToken:                   DefaultAdminToken,
+		OrderLeaseDuration:           DefaultOrderLeaseDuration,
+		AccrualBreakerThreshold:      DefaultAccrualBreakerThreshold,
+		AccrualBreakerCoolDown:       DefaultAccrualBreakerCoolDown,
+		AccrualRateLimit:             DefaultAccrualRateLimit,
+		AccrualRateBurst:             DefaultAccrualRateBurst,
+		AccrualProviders:             DefaultAccrualProviders,
+		AccrualBatchSize:             DefaultAccrualBatchSize,
+		TracingExporter:              DefaultTracingExporter,
+		TracingEndpoint:              DefaultTracingEndpoint,
+		TracingSampleRatio:           DefaultTracingSampleRatio,
+		ReadinessCheckTimeout:        DefaultReadinessCheckTimeout,
+		ReadinessRequireAccrual:      DefaultReadinessRequireAccrual,
+		ShutdownDelay:                DefaultShutdownDelay,
+		RefreshTokenExpiration:       DefaultRefreshTokenExpiration,
+		JWTKeysDir:                   DefaultJWTKeysDir,
+		JWTActiveKeyID:               DefaultJWTActiveKeyID,
+		LoginWindow:                  DefaultLoginWindow,
+		LoginFreeAttempts:            DefaultLoginFreeAttempts,
+		LoginBaseDelay:               DefaultLoginBaseDelay,
+		LoginMaxDelay:                DefaultLoginMaxDelay,
+		LoginMaxFailures:             DefaultLoginMaxFailures,
+		LoginLockDuration:            DefaultLoginLockDuration,
+		PasswordResetNotifier:        DefaultPasswordResetNotifier,
+		PasswordResetFile:            DefaultPasswordResetFile,
+		PasswordResetExpiration:      DefaultPasswordResetExpiration,
+		IdempotencyProcessingTimeout: DefaultIdempotencyProcessingTimeout,
+		BalanceReconcileOnStart:      DefaultBalanceReconcileOnStart,
+		PasswordResetWindow:          DefaultPasswordResetWindow,
+		PasswordResetPerLogin:        DefaultPasswordResetPerLogin,
+		PasswordResetPerIP:           DefaultPasswordResetPerIP,
 	}
 }
@@ -92,6 +92,108 @@ func parseFromEnv(params *CliConfig) error {
 	if cnf.DBMaxIdleConnections > 0 {
 		params.DBMaxIdleConnections = cnf.DBMaxIdleConnections
 	}
+	if cnf.BalanceReconcileDuration != 0 {
+		params.BalanceReconcileDuration = cnf.BalanceReconcileDuration
+	}
+	if cnf.IdempotencyKeyTTL > 0 {
+		params.IdempotencyKeyTTL = cnf.IdempotencyKeyTTL
+	}
+	if cnf.OrderMaxAttempts > 0 {
+		params.OrderMaxAttempts = cnf.OrderMaxAttempts
+	}
+	if cnf.AdminToken != "" {
+		params.AdminToken = cnf.AdminToken
+	}
+	if cnf.OrderLeaseDuration > 0 {
+		params.OrderLeaseDuration = cnf.OrderLeaseDuration
+	}
+	if cnf.AccrualBreakerThreshold > 0 {
+		params.AccrualBreakerThreshold = cnf.AccrualBreakerThreshold
+	}
+	if cnf.AccrualBreakerCoolDown > 0 {
+		params.AccrualBreakerCoolDown = cnf.AccrualBreakerCoolDown
+	}
+	if cnf.AccrualRateLimit > 0 {
+		params.AccrualRateLimit = cnf.AccrualRateLimit
+	}
+	if cnf.AccrualRateBurst > 0 {
+		params.AccrualRateBurst = cnf.AccrualRateBurst
+	}
+	if cnf.AccrualProviders != "" {
+		params.AccrualProviders = cnf.AccrualProviders
+	}
+	if cnf.AccrualBatchSize > 0 {
+		params.AccrualBatchSize = cnf.AccrualBatchSize
+	}
+	if cnf.TracingExporter != "" {
+		params.TracingExporter = cnf.TracingExporter
+	}
+	if cnf.TracingEndpoint != "" {
+		params.TracingEndpoint = cnf.TracingEndpoint
+	}
+	if cnf.TracingSampleRatio > 0 {
+		params.TracingSampleRatio = cnf.TracingSampleRatio
+	}
+	if cnf.ReadinessCheckTimeout > 0 {
+		params.ReadinessCheckTimeout = cnf.ReadinessCheckTimeout
+	}
+	if cnf.ReadinessRequireAccrual {
+		params.ReadinessRequireAccrual = cnf.ReadinessRequireAccrual
+	}
+	if cnf.ShutdownDelay > 0 {
+		params.ShutdownDelay = cnf.ShutdownDelay
+	}
+	if cnf.RefreshTokenExpiration > 0 {
+		params.RefreshTokenExpiration = cnf.RefreshTokenExpiration
+	}
+	if cnf.JWTKeysDir != "" {
+		params.JWTKeysDir = cnf.JWTKeysDir
+	}
+	if cnf.JWTActiveKeyID != "" {
+		params.JWTActiveKeyID = cnf.JWTActiveKeyID
+	}
+	if cnf.LoginWindow > 0 {
+		params.LoginWindow = cnf.LoginWindow
+	}
+	if cnf.LoginFreeAttempts > 0 {
+		params.LoginFreeAttempts = cnf.LoginFreeAttempts
+	}
+	if cnf.LoginBaseDelay > 0 {
+		params.LoginBaseDelay = cnf.LoginBaseDelay
+	}
+	if cnf.LoginMaxDelay > 0 {
+		params.LoginMaxDelay = cnf.LoginMaxDelay
+	}
+	if cnf.LoginMaxFailures > 0 {
+		params.LoginMaxFailures = cnf.LoginMaxFailures
+	}
+	if cnf.LoginLockDuration > 0 {
+		params.LoginLockDuration = cnf.LoginLockDuration
+	}
+	if cnf.PasswordResetNotifier != "" {
+		params.PasswordResetNotifier = cnf.PasswordResetNotifier
+	}
+	if cnf.PasswordResetFile != "" {
+		params.PasswordResetFile = cnf.PasswordResetFile
+	}
+	if cnf.PasswordResetExpiration > 0 {
+		params.PasswordResetExpiration = cnf.PasswordResetExpiration
+	}
+	if cnf.IdempotencyProcessingTimeout > 0 {
+		params.IdempotencyProcessingTimeout = cnf.IdempotencyProcessingTimeout
+	}
+	if cnf.BalanceReconcileOnStart {
+		params.BalanceReconcileOnStart = cnf.BalanceReconcileOnStart
+	}
+	if cnf.PasswordResetWindow > 0 {
+		params.PasswordResetWindow = cnf.PasswordResetWindow
+	}
+	if cnf.PasswordResetPerLogin > 0 {
+		params.PasswordResetPerLogin = cnf.PasswordResetPerLogin
+	}
+	if cnf.PasswordResetPerIP > 0 {
+		params.PasswordResetPerIP = cnf.PasswordResetPerIP
+	}
 	return nil
 }
 
@@ -114,6 +216,40 @@ func parseFromCli(cnf *CliConfig) error {
 	flag.DurationVar(&cnf.DBCheckDuration, "dbs", DefaultDBCheckDuration, "duration between BD checks")
 	flag.IntVar(&cnf.DBMaxConnections, "dbmc", DefaultDBMaxConnections, "max count of connections to BD")
 	flag.IntVar(&cnf.DBMaxIdleConnections, "dbmic", DefaultDBMaxIdleConnections, "max count of idle connections to BD")
+	flag.DurationVar(&cnf.BalanceReconcileDuration, "brd", DefaultBalanceReconcileDuration, "duration between balance reconciliations")
+	flag.DurationVar(&cnf.IdempotencyKeyTTL, "ikt", DefaultIdempotencyKeyTTL, "time to keep responses of requests with idempotency key")
+	flag.IntVar(&cnf.OrderMaxAttempts, "oma", DefaultOrderMaxAttempts, "max count of failed order checks in a row before the order is parked")
+	flag.StringVar(&cnf.AdminToken, "at", DefaultAdminToken, "access token for admin API, empty disables admin API")
+	flag.DurationVar(&cnf.OrderLeaseDuration, "olt", DefaultOrderLeaseDuration, "time for which an order is leased to the instance for checking")
+	flag.IntVar(&cnf.AccrualBreakerThreshold, "abt", DefaultAccrualBreakerThreshold, "count of accrual service failures in a row that opens the circuit breaker")
+	flag.DurationVar(&cnf.AccrualBreakerCoolDown, "abc", DefaultAccrualBreakerCoolDown, "duration the circuit breaker stays open before probing the accrual service")
+	flag.Float64Var(&cnf.AccrualRateLimit, "arl", DefaultAccrualRateLimit, "requests per second to the accrual service, 0 means unlimited")
+	flag.IntVar(&cnf.AccrualRateBurst, "arb", DefaultAccrualRateBurst, "burst of requests to the accrual service above the rate limit")
+	flag.StringVar(&cnf.AccrualProviders, "ap", DefaultAccrualProviders, "additional accrual providers as JSON array of objects with name, url, prefixes, pause, breakerThreshold, breakerCoolDown, rateLimit, rateBurst")
+	flag.IntVar(&cnf.AccrualBatchSize, "abs", DefaultAccrualBatchSize, "number of orders checked by one batch request to the accrual service, 0 or 1 disables batch mode")
+	flag.StringVar(&cnf.TracingExporter, "tx", DefaultTracingExporter, "tracing exporter: none, stdout or otlp")
+	flag.StringVar(&cnf.TracingEndpoint, "tep", DefaultTracingEndpoint, "OTLP/HTTP collector endpoint for tracing")
+	flag.Float64Var(&cnf.TracingSampleRatio, "tsr", DefaultTracingSampleRatio, "fraction of traced requests from 0 to 1")
+	flag.DurationVar(&cnf.ReadinessCheckTimeout, "rct", DefaultReadinessCheckTimeout, "timeout of a single readiness check")
+	flag.BoolVar(&cnf.ReadinessRequireAccrual, "rra", DefaultReadinessRequireAccrual, "report not ready while the accrual circuit is open for all providers")
+	flag.DurationVar(&cnf.ShutdownDelay, "sdl", DefaultShutdownDelay, "delay between reporting not ready and stopping the server on shutdown")
+	flag.DurationVar(&cnf.RefreshTokenExpiration, "rte", DefaultRefreshTokenExpiration, "refresh token expiration time")
+	flag.StringVar(&cnf.JWTKeysDir, "jkd", DefaultJWTKeysDir, "directory with JWT signing keys as <kid>.pem files (RSA, ECDSA P-256 or Ed25519), public-only keys are accepted for verification only")
+	flag.StringVar(&cnf.JWTActiveKeyID, "jak", DefaultJWTActiveKeyID, "kid of the key used to sign new tokens, defaults to the greatest kid with a private key")
+	flag.DurationVar(&cnf.LoginWindow, "lw", DefaultLoginWindow, "sliding window of failed login attempts per login and client address")
+	flag.IntVar(&cnf.LoginFreeAttempts, "lfa", DefaultLoginFreeAttempts, "failed login attempts in the window allowed without delay")
+	flag.DurationVar(&cnf.LoginBaseDelay, "lbd", DefaultLoginBaseDelay, "delay after the first failed login attempt over the free ones, doubled with every next attempt")
+	flag.DurationVar(&cnf.LoginMaxDelay, "lmd", DefaultLoginMaxDelay, "maximum delay between failed login attempts")
+	flag.IntVar(&cnf.LoginMaxFailures, "lmf", DefaultLoginMaxFailures, "consecutive failed logins that lock the user")
+	flag.DurationVar(&cnf.LoginLockDuration, "lld", DefaultLoginLockDuration, "how long the user login stays locked after too many failures")
+	flag.StringVar(&cnf.PasswordResetNotifier, "prn", DefaultPasswordResetNotifier, "password reset notifier: log or file")
+	flag.StringVar(&cnf.PasswordResetFile, "prf", DefaultPasswordResetFile, "file for password reset notifications of the file notifier")
+	flag.DurationVar(&cnf.PasswordResetExpiration, "pre", DefaultPasswordResetExpiration, "password reset token expiration time")
+	flag.DurationVar(&cnf.IdempotencyProcessingTimeout, "ipt", DefaultIdempotencyProcessingTimeout, "time after which an unfinished request with idempotency key is considered abandoned")
+	flag.BoolVar(&cnf.BalanceReconcileOnStart, "bro", DefaultBalanceReconcileOnStart, "reconcile balances with transactions on startup")
+	flag.DurationVar(&cnf.PasswordResetWindow, "prw", DefaultPasswordResetWindow, "window for password reset request limits")
+	flag.IntVar(&cnf.PasswordResetPerLogin, "prl", DefaultPasswordResetPerLogin, "password reset requests per login in window")
+	flag.IntVar(&cnf.PasswordResetPerIP, "pri", DefaultPasswordResetPerIP, "password reset requests per client address in window")
 
 	// Парсим переданные серверу аргументы в зарегистрированные переменные
 	flag.Parse() // Сейчас будет выход из приложения, поэтому код ниже не будет исполнен, но может пригодиться в будущем, если поменять флаг выхода или будет несколько сетов
@@ -241,6 +377,108 @@ func bindEnv() error { // TODO подумать о том, чтобы сдела
 	if err := viper.BindEnv("DBMaxIdleConnections", "DB_MAX_IDLE_CONNECTIONS"); err != nil {
 		return err
 	}
+	if err := viper.BindEnv("BalanceReconcileDuration", "BALANCE_RECONCILE_DURATION"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("IdempotencyKeyTTL", "IDEMPOTENCY_KEY_TTL"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("OrderMaxAttempts", "ORDER_MAX_ATTEMPTS"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AdminToken", "ADMIN_TOKEN"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("OrderLeaseDuration", "ORDER_LEASE_DURATION"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AccrualBreakerThreshold", "ACCRUAL_BREAKER_THRESHOLD"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AccrualBreakerCoolDown", "ACCRUAL_BREAKER_COOL_DOWN"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AccrualRateLimit", "ACCRUAL_RATE_LIMIT"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AccrualRateBurst", "ACCRUAL_RATE_BURST"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AccrualProviders", "ACCRUAL_PROVIDERS"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("AccrualBatchSize", "ACCRUAL_BATCH_SIZE"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("TracingExporter", "TRACING_EXPORTER"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("TracingEndpoint", "TRACING_ENDPOINT"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("TracingSampleRatio", "TRACING_SAMPLE_RATIO"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("ReadinessCheckTimeout", "READINESS_CHECK_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("ReadinessRequireAccrual", "READINESS_REQUIRE_ACCRUAL"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("ShutdownDelay", "SHUTDOWN_DELAY"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("RefreshTokenExpiration", "REFRESH_TOKEN_EXPIRATION"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("JWTKeysDir", "JWT_KEYS_DIR"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("JWTActiveKeyID", "JWT_ACTIVE_KID"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("LoginWindow", "LOGIN_WINDOW"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("LoginFreeAttempts", "LOGIN_FREE_ATTEMPTS"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("LoginBaseDelay", "LOGIN_BASE_DELAY"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("LoginMaxDelay", "LOGIN_MAX_DELAY"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("LoginMaxFailures", "LOGIN_MAX_FAILURES"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("LoginLockDuration", "LOGIN_LOCK_DURATION"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("PasswordResetNotifier", "PASSWORD_RESET_NOTIFIER"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("PasswordResetFile", "PASSWORD_RESET_FILE"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("PasswordResetExpiration", "PASSWORD_RESET_EXPIRATION"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("IdempotencyProcessingTimeout", "IDEMPOTENCY_PROCESSING_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("BalanceReconcileOnStart", "BALANCE_RECONCILE_ON_START"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("PasswordResetWindow", "PASSWORD_RESET_WINDOW"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("PasswordResetPerLogin", "PASSWORD_RESET_PER_LOGIN"); err != nil {
+		return err
+	}
+	if err := viper.BindEnv("PasswordResetPerIP", "PASSWORD_RESET_PER_IP"); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -262,6 +500,40 @@ func bindArg() error {
 	pflag.DurationP("DBCheckDuration", "c", DefaultDBCheckDuration, "duration between BD checks")
 	pflag.IntP("DBMaxConnections", "n", DefaultDBMaxConnections, "max count of connections to BD")
 	pflag.IntP("DBMaxIdleConnections", "i", DefaultDBMaxIdleConnections, "max count of idle connections to BD")
+	pflag.Duration("BalanceReconcileDuration", DefaultBalanceReconcileDuration, "duration between balance reconciliations")
+	pflag.Duration("IdempotencyKeyTTL", DefaultIdempotencyKeyTTL, "time to keep responses of requests with idempotency key")
+	pflag.Int("OrderMaxAttempts", DefaultOrderMaxAttempts, "max count of failed order checks in a row before the order is parked")
+	pflag.String("AdminToken", DefaultAdminToken, "access token for admin API, empty disables admin API")
+	pflag.Duration("OrderLeaseDuration", DefaultOrderLeaseDuration, "time for which an order is leased to the instance for checking")
+	pflag.Int("AccrualBreakerThreshold", DefaultAccrualBreakerThreshold, "count of accrual service failures in a row that opens the circuit breaker")
+	pflag.Duration("AccrualBreakerCoolDown", DefaultAccrualBreakerCoolDown, "duration the circuit breaker stays open before probing the accrual service")
+	pflag.Float64("AccrualRateLimit", DefaultAccrualRateLimit, "requests per second to the accrual service, 0 means unlimited")
+	pflag.Int("AccrualRateBurst", DefaultAccrualRateBurst, "burst of requests to the accrual service above the rate limit")
+	pflag.String("AccrualProviders", DefaultAccrualProviders, "additional accrual providers as JSON array of objects with name, url, prefixes, pause, breakerThreshold, breakerCoolDown, rateLimit, rateBurst")
+	pflag.Int("AccrualBatchSize", DefaultAccrualBatchSize, "number of orders checked by one batch request to the accrual service, 0 or 1 disables batch mode")
+	pflag.String("TracingExporter", DefaultTracingExporter, "tracing exporter: none, stdout or otlp")
+	pflag.String("TracingEndpoint", DefaultTracingEndpoint, "OTLP/HTTP collector endpoint for tracing")
+	pflag.Float64("TracingSampleRatio", DefaultTracingSampleRatio, "fraction of traced requests from 0 to 1")
+	pflag.Duration("ReadinessCheckTimeout", DefaultReadinessCheckTimeout, "timeout of a single readiness check")
+	pflag.Bool("ReadinessRequireAccrual", DefaultReadinessRequireAccrual, "report not ready while the accrual circuit is open for all providers")
+	pflag.Duration("ShutdownDelay", DefaultShutdownDelay, "delay between reporting not ready and stopping the server on shutdown")
+	pflag.Duration("RefreshTokenExpiration", DefaultRefreshTokenExpiration, "refresh token expiration time")
+	pflag.String("JWTKeysDir", DefaultJWTKeysDir, "directory with JWT signing keys as <kid>.pem files (RSA, ECDSA P-256 or Ed25519), public-only keys are accepted for verification only")
+	pflag.String("JWTActiveKeyID", DefaultJWTActiveKeyID, "kid of the key used to sign new tokens, defaults to the greatest kid with a private key")
+	pflag.Duration("LoginWindow", DefaultLoginWindow, "sliding window of failed login attempts per login and client address")
+	pflag.Int("LoginFreeAttempts", DefaultLoginFreeAttempts, "failed login attempts in the window allowed without delay")
+	pflag.Duration("LoginBaseDelay", DefaultLoginBaseDelay, "delay after the first failed login attempt over the free ones, doubled with every next attempt")
+	pflag.Duration("LoginMaxDelay", DefaultLoginMaxDelay, "maximum delay between failed login attempts")
+	pflag.Int("LoginMaxFailures", DefaultLoginMaxFailures, "consecutive failed logins that lock the user")
+	pflag.Duration("LoginLockDuration", DefaultLoginLockDuration, "how long the user login stays locked after too many failures")
+	pflag.String("PasswordResetNotifier", DefaultPasswordResetNotifier, "password reset notifier: log or file")
+	pflag.String("PasswordResetFile", DefaultPasswordResetFile, "file for password reset notifications of the file notifier")
+	pflag.Duration("PasswordResetExpiration", DefaultPasswordResetExpiration, "password reset token expiration time")
+	pflag.Duration("IdempotencyProcessingTimeout", DefaultIdempotencyProcessingTimeout, "time after which an unfinished request with idempotency key is considered abandoned")
+	pflag.Bool("BalanceReconcileOnStart", DefaultBalanceReconcileOnStart, "reconcile balances with transactions on startup")
+	pflag.Duration("PasswordResetWindow", DefaultPasswordResetWindow, "window for password reset request limits")
+	pflag.Int("PasswordResetPerLogin", DefaultPasswordResetPerLogin, "password reset requests per login in window")
+	pflag.Int("PasswordResetPerIP", DefaultPasswordResetPerIP, "password reset requests per client address in window")
 	pflag.Parse()
 	return viper.BindPFlags(pflag.CommandLine)
 }
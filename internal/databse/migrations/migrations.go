@@ -1,6 +1,7 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"github.com/pressly/goose/v3"
@@ -10,11 +11,37 @@ import (
 var embedMigrations embed.FS
 
 func Migrate(db *sql.DB) error {
-	goose.SetBaseFS(embedMigrations)
-
-	if err := goose.SetDialect("postgres"); err != nil {
+	if err := setup(); err != nil {
 		return err
 	}
 
 	return goose.Up(db, "sql")
 }
+
+// Latest версия последней миграции, встроенной в приложение.
+// Настраивает goose, поэтому вызывается один раз при запуске, а не в каждой проверке.
+func Latest() (int64, error) {
+	if err := setup(); err != nil {
+		return 0, err
+	}
+	migrations, err := goose.CollectMigrations("sql", 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
+
+// Current текущая версия схемы базы данных, goose должен быть уже настроен через Migrate или Latest
+func Current(ctx context.Context, db *sql.DB) (int64, error) {
+	return goose.GetDBVersionContext(ctx, db)
+}
+
+// setup настраиваем goose на встроенные миграции postgres
+func setup() error {
+	goose.SetBaseFS(embedMigrations)
+	return goose.SetDialect("postgres")
+}
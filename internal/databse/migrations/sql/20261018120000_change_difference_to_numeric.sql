@@ -0,0 +1,5 @@
+-- +goose Up
+alter table public.t_account alter column difference type numeric using difference::numeric;
+
+-- +goose Down
+alter table public.t_account alter column difference type double precision using difference::double precision;
@@ -0,0 +1,29 @@
+-- +goose Up
+create table public.t_balance
+(
+    user_id    bigint                  not null
+        constraint t_balance_pk
+            primary key
+        constraint t_balance_t_user_id_fk
+            references public.t_user,
+    current    numeric   default 0     not null,
+    withdrawn  numeric   default 0     not null,
+    version    bigint    default 0     not null,
+    updated_at timestamp default now() not null
+);
+comment on table public.t_balance is 'Текущий баланс пользователей, рассчитанный по t_account';
+comment on column public.t_balance.user_id is 'Пользователь';
+comment on column public.t_balance.current is 'Текущий баланс';
+comment on column public.t_balance.withdrawn is 'Сумма списанных средств';
+comment on column public.t_balance.version is 'Количество изменений баланса';
+comment on column public.t_balance.updated_at is 'Дата обновления записи';
+insert into public.t_balance (user_id, current, withdrawn, version)
+select user_id,
+       sum(difference),
+       sum(case when difference < 0 then abs(difference) else 0 end),
+       count(*)
+from public.t_account
+group by user_id;
+
+-- +goose Down
+drop table public.t_balance;
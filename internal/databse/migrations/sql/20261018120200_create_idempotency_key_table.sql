@@ -0,0 +1,28 @@
+-- +goose Up
+create table public.t_idempotency_key
+(
+    id              bigserial               not null
+        constraint t_idempotency_key_pk
+            primary key,
+    user_id         bigint                  not null
+        constraint t_idempotency_key_t_user_id_fk
+            references public.t_user,
+    idempotency_key varchar                 not null,
+    request_hash    varchar                 not null,
+    response_status integer,
+    response_body   bytea,
+    created_at      timestamp default now() not null,
+    expires_at      timestamp               not null
+);
+comment on table public.t_idempotency_key is 'Ключи идемпотентности запросов пользователей и сохранённые ответы на них';
+comment on column public.t_idempotency_key.user_id is 'Пользователь';
+comment on column public.t_idempotency_key.idempotency_key is 'Значение заголовка Idempotency-Key';
+comment on column public.t_idempotency_key.request_hash is 'Хэш метода, пути и тела первого запроса';
+comment on column public.t_idempotency_key.response_status is 'HTTP статус первого ответа, пустой пока запрос обрабатывается';
+comment on column public.t_idempotency_key.response_body is 'Тело первого ответа';
+comment on column public.t_idempotency_key.expires_at is 'Время, после которого ключ можно использовать повторно';
+create unique index t_idempotency_key_user_id_idempotency_key_uindex on public.t_idempotency_key (user_id, idempotency_key);
+create index t_idempotency_key_expires_at_index on public.t_idempotency_key (expires_at);
+
+-- +goose Down
+drop table public.t_idempotency_key;
@@ -0,0 +1,26 @@
+-- +goose Up
+create table public.t_order_status_history
+(
+    id             bigserial               not null
+        constraint t_order_status_history_pk
+            primary key,
+    order_number   varchar                 not null,
+    old_status     varchar(10)             not null,
+    new_status     varchar(10)             not null
+        constraint t_order_status_history_d_order_status_code_fk
+            references public.d_order_status (code),
+    accrual_status varchar                 not null,
+    accrual        numeric,
+    created_at     timestamp default now() not null
+);
+comment on table public.t_order_status_history is 'История изменения статусов заказов по ответам системы расчёта начислений';
+comment on column public.t_order_status_history.order_number is 'Номер заказа';
+comment on column public.t_order_status_history.old_status is 'Статус заказа до проверки';
+comment on column public.t_order_status_history.new_status is 'Статус заказа после проверки';
+comment on column public.t_order_status_history.accrual_status is 'Статус заказа в системе расчёта начислений';
+comment on column public.t_order_status_history.accrual is 'Начисление по ответу системы расчёта начислений';
+comment on column public.t_order_status_history.created_at is 'Дата проверки';
+create index t_order_status_history_order_number_index on public.t_order_status_history (order_number);
+
+-- +goose Down
+drop table public.t_order_status_history;
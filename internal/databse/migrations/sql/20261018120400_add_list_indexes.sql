@@ -0,0 +1,7 @@
+-- +goose Up
+create index t_order_user_id_created_at_number_index on public.t_order (user_id, created_at, number);
+create index t_account_user_id_created_at_id_index on public.t_account (user_id, created_at, id) where difference < 0;
+
+-- +goose Down
+drop index public.t_account_user_id_created_at_id_index;
+drop index public.t_order_user_id_created_at_number_index;
@@ -0,0 +1,16 @@
+-- +goose Up
+alter table public.t_order
+    add next_check_at timestamp,
+    add attempts      integer default 0 not null,
+    add parked_at     timestamp;
+comment on column public.t_order.next_check_at is 'Время следующей проверки в системе расчёта начислений';
+comment on column public.t_order.attempts is 'Количество неудачных проверок подряд';
+comment on column public.t_order.parked_at is 'Время, когда проверки заказа были остановлены после превышения количества попыток';
+create index t_order_status_code_next_check_at_index on public.t_order (status_code, next_check_at) where parked_at is null;
+
+-- +goose Down
+drop index public.t_order_status_code_next_check_at_index;
+alter table public.t_order
+    drop column parked_at,
+    drop column attempts,
+    drop column next_check_at;
@@ -0,0 +1,12 @@
+-- +goose Up
+INSERT INTO d_order_status (code, description) VALUES ('PARKED', 'Проверки заказа остановлены после ошибок системы расчёта вознаграждений');
+alter table public.t_order
+    add last_error varchar;
+comment on column public.t_order.last_error is 'Ошибка последней неудачной проверки в системе расчёта начислений';
+update public.t_order set status_code = 'PARKED' where parked_at is not null;
+
+-- +goose Down
+update public.t_order set status_code = 'NEW' where status_code = 'PARKED';
+alter table public.t_order
+    drop column last_error;
+DELETE FROM d_order_status WHERE code = 'PARKED';
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table public.t_order
+    add claimed_by       varchar,
+    add lease_expires_at timestamp;
+comment on column public.t_order.claimed_by is 'Экземпляр приложения, который проверяет заказ';
+comment on column public.t_order.lease_expires_at is 'Время, до которого заказ закреплён за экземпляром приложения';
+
+-- +goose Down
+alter table public.t_order
+    drop column lease_expires_at,
+    drop column claimed_by;
@@ -0,0 +1,43 @@
+-- +goose Up
+create table public.t_session
+(
+    id           varchar                 not null
+        constraint t_session_pk
+            primary key,
+    user_id      bigint                  not null
+        constraint t_session_t_user_id_fk
+            references public.t_user,
+    created_at   timestamp default now() not null,
+    refreshed_at timestamp default now() not null,
+    expires_at   timestamp               not null,
+    revoked_at   timestamp
+);
+comment on table public.t_session is 'Сессии пользователей, идентификатор сессии передаётся в jti токена доступа';
+comment on column public.t_session.user_id is 'Пользователь';
+comment on column public.t_session.refreshed_at is 'Время последнего обновления токенов сессии';
+comment on column public.t_session.expires_at is 'Время, после которого сессию нельзя продлить токеном обновления';
+comment on column public.t_session.revoked_at is 'Время выхода из сессии, после него токены сессии не принимаются';
+create index t_session_user_id_index on public.t_session (user_id);
+create index t_session_expires_at_index on public.t_session (expires_at);
+
+create table public.t_refresh_token
+(
+    token_hash varchar                 not null
+        constraint t_refresh_token_pk
+            primary key,
+    session_id varchar                 not null
+        constraint t_refresh_token_t_session_id_fk
+            references public.t_session
+            on delete cascade,
+    created_at timestamp default now() not null,
+    expires_at timestamp               not null,
+    used_at    timestamp
+);
+comment on table public.t_refresh_token is 'Токены обновления сессий, каждый токен используется один раз';
+comment on column public.t_refresh_token.token_hash is 'SHA-256 хэш токена обновления, сам токен не хранится';
+comment on column public.t_refresh_token.used_at is 'Время обмена токена на новый, повторное использование отзывает сессию';
+create index t_refresh_token_session_id_index on public.t_refresh_token (session_id);
+
+-- +goose Down
+drop table public.t_refresh_token;
+drop table public.t_session;
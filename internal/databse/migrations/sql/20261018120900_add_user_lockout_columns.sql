@@ -0,0 +1,11 @@
+-- +goose Up
+alter table public.t_user
+    add failed_logins integer default 0 not null,
+    add locked_until  timestamp;
+comment on column public.t_user.failed_logins is 'Количество неудачных входов подряд с последнего успешного входа или блокировки';
+comment on column public.t_user.locked_until is 'Время, до которого вход пользователя заблокирован после подбора пароля';
+
+-- +goose Down
+alter table public.t_user
+    drop column locked_until,
+    drop column failed_logins;
@@ -0,0 +1,24 @@
+-- +goose Up
+create table public.t_password_reset
+(
+    token_hash varchar                 not null
+        constraint t_password_reset_pk
+            primary key,
+    user_id    bigint                  not null
+        constraint t_password_reset_t_user_id_fk
+            references public.t_user
+            on delete cascade,
+    created_at timestamp default now() not null,
+    expires_at timestamp               not null,
+    used_at    timestamp
+);
+comment on table public.t_password_reset is 'Одноразовые токены сброса пароля';
+comment on column public.t_password_reset.token_hash is 'SHA-256 хэш токена сброса, сам токен не хранится';
+comment on column public.t_password_reset.user_id is 'Пользователь, пароль которого сбрасывается';
+comment on column public.t_password_reset.expires_at is 'Время, после которого токен не принимается';
+comment on column public.t_password_reset.used_at is 'Время использования или отзыва токена';
+create index t_password_reset_user_id_index on public.t_password_reset (user_id);
+create index t_password_reset_expires_at_index on public.t_password_reset (expires_at);
+
+-- +goose Down
+drop table public.t_password_reset;
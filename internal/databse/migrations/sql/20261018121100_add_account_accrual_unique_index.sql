@@ -0,0 +1,6 @@
+-- +goose Up
+-- Начисление по заказу может быть только одно, повторное начисление другим экземпляром приложения отклоняется базой данных
+create unique index t_account_order_number_accrual_uindex on public.t_account (order_number) where difference > 0;
+
+-- +goose Down
+drop index public.t_account_order_number_accrual_uindex;
@@ -4,6 +4,8 @@ import (
 	"errors"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"gofemart/internal/databse/migrations"
 	"gofemart/internal/logger"
 )
@@ -16,10 +18,12 @@ type DBPool struct {
 }
 
 func newPgDBx(dsn string, maxConnections int, maxIdleConnections int) (*sqlx.DB, error) {
-	db, err := sqlx.Open("pgx", dsn)
+	// Драйвер оборачиваем, чтобы запросы репозиториев попадали в трассировку спанами
+	sqlDB, err := otelsql.Open("pgx", dsn, otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(sqlDB, "pgx")
 	db.SetMaxOpenConns(maxConnections)
 	db.SetMaxIdleConns(maxIdleConnections)
 	// Если дсн не передан, то просто возвращаем созданный пул, он не работоспособен
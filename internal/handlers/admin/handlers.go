@@ -0,0 +1,241 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/asaskevich/govalidator"
+	"gofemart/internal/gofemarterrors"
+	"gofemart/internal/helpers"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/ordercheck"
+	"gofemart/internal/pagination"
+	"gofemart/internal/payloads"
+	"gofemart/internal/repositories"
+	"gofemart/internal/services"
+	"io"
+	"net/http"
+)
+
+// Handlers Хэндлеры административного API
+type Handlers struct {
+	dbPool repositories.SQLExecutor
+	guard  *services.LoginGuard
+}
+
+// NewHandlers создает новый экземпляр Handlers с предоставленным SQLExecutor и защитой входа, задержки которой сбрасываются при разблокировке.
+func NewHandlers(dbPool repositories.SQLExecutor, guard *services.LoginGuard) *Handlers {
+	return &Handlers{
+		dbPool: dbPool,
+		guard:  guard,
+	}
+}
+
+// GetParkedOrdersHandler обрабатывает запросы на получение заказов, проверки которых остановлены.
+// @Summary Получить остановленные заказы
+// @Description Возвращает заказы в статусе PARKED с количеством неудачных проверок и последней ошибкой системы начислений.
+// @Tags Администрирование
+// @Produce  json
+// @Security AdminToken
+// @Param limit query int false "Количество заказов на странице"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param from query string false "Остановленные не раньше, RFC3339"
+// @Param to query string false "Остановленные не позже, RFC3339"
+// @Param sort query string false "Направление сортировки по времени остановки: asc или desc"
+// @Success 200 {array} models.ParkedOrder "Список остановленных заказов"
+// @Success 200 {object} pagination.Page[models.ParkedOrder] "Страница остановленных заказов"
+// @Failure 400 {string} payloads.ErrorResponseBody
+// @Failure 401 {string} payloads.ErrorResponseBody
+// @Failure 403 {string} payloads.ErrorResponseBody
+// @Failure 500 {string} payloads.ErrorResponseBody
+// @Router /api/admin/orders/parked [get]
+func (h *Handlers) GetParkedOrdersHandler(response http.ResponseWriter, request *http.Request) {
+	query, err := pagination.ParseQuery(request.URL.Query())
+	if err == nil && len(query.Statuses) > 0 {
+		err = errors.New("status filter is not supported for parked orders")
+	}
+	if err != nil {
+		helpers.ProcessResponseWithStatus(err.Error(), http.StatusBadRequest, response)
+		return
+	}
+
+	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
+	orders, err := rep.GetParkedOrders(query)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+
+	var res []byte
+	if query.Paginated {
+		res, err = json.Marshal(pagination.NewPage(orders, query.Limit, func(order models.ParkedOrder) pagination.Cursor {
+			return pagination.Cursor{At: order.ParkedAt.Time, Key: order.Number}
+		}))
+	} else {
+		if orders == nil {
+			orders = []models.ParkedOrder{}
+		}
+		res, err = json.Marshal(orders)
+	}
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, res); err != nil {
+		helpers.SetInternalError(err, response)
+	}
+}
+
+// RequeueOrdersHandler обрабатывает запрос на возврат остановленных заказов в очередь проверки.
+// @Summary Вернуть остановленные заказы в очередь
+// @Description Переводит выбранные заказы из статуса PARKED в NEW, сбрасывает счётчик неудачных проверок и отправляет их в пул обработки.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param orders body payloads.RequeueOrders true "Номера заказов"
+// @Success 200 {object} payloads.RequeueResult
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 403 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/admin/orders/requeue [post]
+func (h *Handlers) RequeueOrdersHandler(response http.ResponseWriter, request *http.Request) {
+	body, err := h.getRequeueBody(request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
+
+	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
+	orders, err := rep.RequeueParkedOrders(body.Numbers)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+
+	result := payloads.RequeueResult{Requeued: []string{}, Skipped: []string{}}
+	requeued := make(map[string]struct{}, len(orders))
+	for _, order := range orders {
+		requeued[order.Number] = struct{}{}
+		result.Requeued = append(result.Requeued, order.Number)
+		// Заказ уже сохранён в статусе NEW, поэтому если очередь заполнена, его заберёт проверка базы данных
+		if _, err := ordercheck.CheckPool.Push(request.Context(), &order); err != nil {
+			logger.Log.Warnw("Requeued order not pushed to queue", "order", order.Number, "error", err)
+		}
+	}
+	for _, number := range body.Numbers {
+		if _, ok := requeued[number]; !ok {
+			result.Skipped = append(result.Skipped, number)
+		}
+	}
+	logger.Log.Infow("Orders requeued", "requeued", result.Requeued, "skipped", result.Skipped)
+
+	res, err := json.Marshal(result)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, res); err != nil {
+		helpers.SetInternalError(err, response)
+	}
+}
+
+// getRequeueBody получаем тело запроса на возврат заказов в очередь
+func (h *Handlers) getRequeueBody(request *http.Request) (*payloads.RequeueOrders, error) {
+	rawBody, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body payloads.RequeueOrders
+	if err := json.Unmarshal(rawBody, &body); err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	result, err := govalidator.ValidateStruct(body)
+	if err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	if !result || len(body.Numbers) == 0 {
+		return nil, &gofemarterrors.RequestError{InternalError: errors.New("numbers are required"), HTTPStatus: http.StatusBadRequest}
+	}
+	return &body, nil
+}
+
+// GetAccrualStatusHandler обрабатывает запрос на получение состояния автоматических выключателей запросов к поставщикам начислений.
+// @Summary Состояние запросов к поставщикам начислений
+// @Description Возвращает для каждого поставщика префиксы номеров и состояние автоматического выключателя: closed, open или half-open, количество ошибок подряд и время следующей попытки.
+// @Tags Администрирование
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} ordercheck.ProviderStatus
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 403 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/admin/accrual/status [get]
+func (h *Handlers) GetAccrualStatusHandler(response http.ResponseWriter, request *http.Request) {
+	res, err := json.Marshal(ordercheck.CheckPool.AccrualStatus())
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, res); err != nil {
+		helpers.SetInternalError(err, response)
+	}
+}
+
+// UnlockUserHandler обрабатывает запрос на снятие блокировки входа пользователя.
+// @Summary Разблокировать вход пользователя
+// @Description Сбрасывает счётчик неудачных входов и блокировку пользователя, а также задержки попыток входа на этом экземпляре приложения.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param user body payloads.UnlockUser true "Логин пользователя"
+// @Success 200 {object} payloads.ErrorResponseBody
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 403 {object} payloads.ErrorResponseBody
+// @Failure 404 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/admin/users/unlock [post]
+func (h *Handlers) UnlockUserHandler(response http.ResponseWriter, request *http.Request) {
+	body, err := h.getUnlockBody(request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
+
+	rep := repositories.NewUserRepository(request.Context(), h.dbPool)
+	exists, err := rep.UnlockUser(body.Login)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if !exists {
+		helpers.ProcessResponseWithStatus("user not found", http.StatusNotFound, response)
+		return
+	}
+	h.guard.Unlock(body.Login)
+	logger.Log.Infow("User login unlocked", "login", body.Login)
+	helpers.ProcessResponseWithStatus("user unlocked", http.StatusOK, response)
+}
+
+// getUnlockBody получаем тело запроса на снятие блокировки пользователя
+func (h *Handlers) getUnlockBody(request *http.Request) (*payloads.UnlockUser, error) {
+	rawBody, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body payloads.UnlockUser
+	if err := json.Unmarshal(rawBody, &body); err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	result, err := govalidator.ValidateStruct(body)
+	if err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	if !result {
+		return nil, &gofemarterrors.RequestError{InternalError: errors.New("login is required"), HTTPStatus: http.StatusBadRequest}
+	}
+	return &body, nil
+}
@@ -38,6 +38,7 @@ func NewHandlers(dbPool repositories.SQLExecutor) *Handlers {
 // @Accept json
 // @Produce json
 // @Param withdraw body payloads.Withdraw true "Withdraw payload"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повторный запрос с тем же ключом вернёт сохранённый ответ"
 // @Success 200 {object} payloads.ErrorResponseBody
 // @Failure 400 {object} payloads.ErrorResponseBody
 // @Failure 401 {object} payloads.ErrorResponseBody
@@ -132,6 +133,11 @@ func (b *Handlers) getBody(request *http.Request) (*payloads.Withdraw, error) {
 		return nil, &gofemarterrors.RequestError{InternalError: errors.New("bad request"), HTTPStatus: http.StatusBadRequest}
 	}
 
+	// Списать можно только положительную сумму
+	if !body.Sum.IsPositive() {
+		return nil, &gofemarterrors.RequestError{InternalError: errors.New("sum must be positive"), HTTPStatus: http.StatusBadRequest}
+	}
+
 	return &body, nil
 }
 
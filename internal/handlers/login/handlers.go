@@ -4,35 +4,58 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/asaskevich/govalidator"
-	config "gofemart/internal/configuration"
 	"gofemart/internal/gofemarterrors"
 	"gofemart/internal/helpers"
 	"gofemart/internal/logger"
 	"gofemart/internal/models"
+	"gofemart/internal/notifier"
 	"gofemart/internal/payloads"
 	"gofemart/internal/repositories"
+	"gofemart/internal/services"
 	"gofemart/internal/token"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// dummyPasswordHash хэш для проверки пароля несуществующего пользователя
+var dummyPasswordHash = sync.OnceValue(func() string {
+	passwordHash, err := models.HashPassword("dummy password", models.DefaultPasswordParams)
+	if err != nil {
+		logger.Log.Error(err)
+	}
+	return passwordHash
+})
+
 // Handlers для обработки запросов, связанных с регистрацией и аутентификацией пользователей.
 type Handlers struct {
-	dbPool          repositories.SQLExecutor
-	jwtKeys         *config.JWTKeys
-	tokenExpiration time.Duration
-	hashKey         string
+	dbPool            repositories.SQLExecutor
+	jwtKeys           *token.KeyRing
+	tokenExpiration   time.Duration
+	refreshExpiration time.Duration
+	hashKey           string
+	guard             *services.LoginGuard
+	resetLimiter      *services.ResetLimiter
+	resetTasks        *services.BackgroundTasks
+	notifier          notifier.Notifier
+	resetExpiration   time.Duration
 }
 
 // NewHandlers инициализирует и возвращает новый экземпляр Handlers,
-// настроенный с указанным подключением к базе данных, ключами JWT, сроком действия токена и хэш-ключом.
-func NewHandlers(dbPool repositories.SQLExecutor, jwtKeys *config.JWTKeys, tokenExpiration time.Duration, hashKey string) *Handlers {
+// настроенный с указанным подключением к базе данных, набором ключей JWT, сроками действия токенов доступа и обновления и ключом устаревших хэшей паролей, защитой входа от подбора пароля, ограничением запросов сброса пароля, фоновыми задачами отправки токенов сброса пароля, способом их доставки и сроком действия.
+func NewHandlers(dbPool repositories.SQLExecutor, jwtKeys *token.KeyRing, tokenExpiration time.Duration, refreshExpiration time.Duration, hashKey string, guard *services.LoginGuard, resetLimiter *services.ResetLimiter, resetTasks *services.BackgroundTasks, resetNotifier notifier.Notifier, resetExpiration time.Duration) *Handlers {
 	return &Handlers{
-		dbPool:          dbPool,
-		jwtKeys:         jwtKeys,
-		tokenExpiration: tokenExpiration,
-		hashKey:         hashKey,
+		dbPool:            dbPool,
+		jwtKeys:           jwtKeys,
+		tokenExpiration:   tokenExpiration,
+		refreshExpiration: refreshExpiration,
+		hashKey:           hashKey,
+		guard:             guard,
+		resetLimiter:      resetLimiter,
+		resetTasks:        resetTasks,
+		notifier:          resetNotifier,
+		resetExpiration:   resetExpiration,
 	}
 }
 
@@ -75,26 +98,13 @@ func (l *Handlers) RegistrationHandler(response http.ResponseWriter, request *ht
 		return
 	}
 
-	// Создаём токен для пользователя
-	tkn, err := l.createJWTToken(user)
-	if err != nil {
-		helpers.SetInternalError(err, response)
-		return
-	}
-
-	// Устанавливаем токен в ответ
-	payload := payloads.Authorization{Token: tkn}
-	responseBody, err := json.Marshal(payload)
+	// Создаём сессию и токены для пользователя
+	authorization, err := l.sessionService(request).Start(user)
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
-
-	response.Header().Set("Authorization", "Bearer "+tkn)
-
-	if rErr := helpers.SetHTTPResponse(response, http.StatusOK, responseBody); rErr != nil {
-		logger.Log.Error(rErr)
-	}
+	l.setAuthorization(response, authorization)
 }
 
 // getBody получаем тело для регистрации
@@ -129,7 +139,7 @@ func (l *Handlers) createUser(body *payloads.Register) (*models.User, error) {
 		Login:    body.Login,
 		Password: body.Password,
 	}
-	err := user.GeneratePasswordHash(l.hashKey)
+	err := user.GeneratePasswordHash()
 	if err != nil {
 		return nil, err
 	}
@@ -148,10 +158,25 @@ func (l *Handlers) createAndSaveUser(body *payloads.Register, repository *reposi
 	return user, nil
 }
 
-// createJWTToken создаём JWT токен
-func (l *Handlers) createJWTToken(user *models.User) (string, error) {
-	generator := token.NewJWTGenerator(l.jwtKeys.Private, l.jwtKeys.Public, l.tokenExpiration)
-	return generator.Generate(user)
+// sessionService сервис сессий в контексте запроса
+func (l *Handlers) sessionService(request *http.Request) *services.SessionService {
+	generator := token.NewJWTGenerator(l.jwtKeys, l.tokenExpiration)
+	return services.NewSessionService(request.Context(), l.dbPool, generator, l.refreshExpiration)
+}
+
+// setAuthorization устанавливаем токены в ответ
+func (l *Handlers) setAuthorization(response http.ResponseWriter, authorization *payloads.Authorization) {
+	responseBody, err := json.Marshal(authorization)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+
+	response.Header().Set("Authorization", "Bearer "+authorization.Token)
+
+	if rErr := helpers.SetHTTPResponse(response, http.StatusOK, responseBody); rErr != nil {
+		logger.Log.Error(rErr)
+	}
 }
 
 // LoginHandler обрабатывает вход пользователя в систему, проверяя учетные данные и генерируя токен авторизации.
@@ -164,6 +189,7 @@ func (l *Handlers) createJWTToken(user *models.User) (string, error) {
 // @Success 200 {object} payloads.Authorization
 // @Failure 400 {object} payloads.ErrorResponseBody
 // @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 429 {object} payloads.ErrorResponseBody "Слишком много попыток, время ожидания в заголовке Retry-After"
 // @Failure 500 {object} payloads.ErrorResponseBody
 // @Router /api/user/login [post]
 func (l *Handlers) LoginHandler(response http.ResponseWriter, request *http.Request) {
@@ -173,52 +199,173 @@ func (l *Handlers) LoginHandler(response http.ResponseWriter, request *http.Requ
 		helpers.ProcessRequestErrorWithBody(err, response)
 		return
 	}
-	requestedUser, err := l.createUser(body)
-	if err != nil {
-		helpers.SetInternalError(err, response)
+	// Слишком частые попытки с одного адреса отклоняем до обращения к базе данных
+	ip := helpers.ClientIP(request)
+	if wait := l.guard.Wait(body.Login, ip); wait > 0 {
+		helpers.SetRetryAfter("too many login attempts", wait, response)
 		return
 	}
 
 	userRepository := repositories.NewUserRepository(request.Context(), l.dbPool)
-	dbUser, exists, err := userRepository.GetUserByLogin(requestedUser.Login)
+	dbUser, exists, err := userRepository.GetUserByLogin(body.Login)
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
-	if !exists {
-		helpers.ProcessResponseWithStatus("password and login are incorrect", http.StatusUnauthorized, response)
+	// Заблокированный пользователь получает тот же ответ, что и несуществующий, чтобы по ответу нельзя было подобрать логины.
+	// Пароль заблокированного пользователя не проверяется, но хэш считается, чтобы не отличаться по времени ответа.
+	if !exists || dbUser.LockedFor(time.Now()) > 0 {
+		_, _, _ = models.VerifyPassword(body.Password, dummyPasswordHash(), l.hashKey)
+		l.loginFailed(response, userRepository, body.Login, ip, nil)
 		return
 	}
 
-	ok, err := dbUser.CheckPasswordHash(requestedUser.PasswordHash)
+	ok, rehash, err := dbUser.CheckPassword(body.Password, l.hashKey)
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
 	if !ok {
-		helpers.ProcessResponseWithStatus("password and login are incorrect", http.StatusUnauthorized, response)
+		l.loginFailed(response, userRepository, body.Login, ip, dbUser)
+		return
+	}
+	if err = l.guard.Succeeded(userRepository, body.Login, ip, dbUser); err != nil {
+		helpers.SetInternalError(err, response)
 		return
 	}
+	if rehash {
+		l.rehashPassword(userRepository, dbUser, body.Password)
+	}
 
-	// Создаём токен для пользователя
-	tkn, err := l.createJWTToken(dbUser)
+	// Создаём сессию и токены для пользователя
+	authorization, err := l.sessionService(request).Start(dbUser)
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
+	l.setAuthorization(response, authorization)
+}
+
+// loginFailed учитываем неудачную попытку входа и отвечаем 401.
+// Наступившую блокировку не показываем, иначе по ответу можно отличить существующий логин.
+func (l *Handlers) loginFailed(response http.ResponseWriter, repository *repositories.UserRepository, login string, ip string, user *models.User) {
+	if _, err := l.guard.Failed(repository, login, ip, user); err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	helpers.ProcessResponseWithStatus("password and login are incorrect", http.StatusUnauthorized, response)
+}
+
+// rehashPassword пересчитываем устаревший хэш пароля после успешного входа.
+// Ошибка не мешает входу, хэш пересчитается при следующем входе.
+func (l *Handlers) rehashPassword(repository *repositories.UserRepository, user *models.User, password string) {
+	user.Password = password
+	if err := user.GeneratePasswordHash(); err != nil {
+		logger.Log.Errorw("Password rehash failed", "user", user.ID, "error", err)
+		return
+	}
+	if err := repository.UpdatePasswordHash(user.ID, user.PasswordHash); err != nil {
+		logger.Log.Errorw("Password rehash failed", "user", user.ID, "error", err)
+		return
+	}
+	logger.Log.Infow("Password hash upgraded", "user", user.ID)
+}
+
+// RefreshHandler обменивает токен обновления на новую пару токенов.
+// @Summary Обновление токенов
+// @Description Выдаёт новый токен доступа и новый токен обновления, старый токен обновления больше не принимается.
+// @Description Повторное использование токена обновления отзывает сессию.
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param refresh body payloads.RefreshToken true "Refresh token"
+// @Success 200 {object} payloads.Authorization
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/token/refresh [post]
+func (l *Handlers) RefreshHandler(response http.ResponseWriter, request *http.Request) {
+	body, err := l.getRefreshBody(request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
 
-	// Устанавливаем токен в ответ
-	payload := payloads.Authorization{Token: tkn}
-	responseBody, err := json.Marshal(payload)
+	authorization, err := l.sessionService(request).Refresh(body.RefreshToken)
+	if errors.Is(err, services.ErrorInvalidRefreshToken) || errors.Is(err, services.ErrorRefreshTokenReused) {
+		helpers.ProcessResponseWithStatus(err.Error(), http.StatusUnauthorized, response)
+		return
+	}
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
+	l.setAuthorization(response, authorization)
+}
 
-	response.Header().Set("Authorization", "Bearer "+tkn)
+// getRefreshBody получаем тело запроса обновления токенов
+func (l *Handlers) getRefreshBody(request *http.Request) (*payloads.RefreshToken, error) {
+	rawBody, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body payloads.RefreshToken
+	if err = json.Unmarshal(rawBody, &body); err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	if result, err := govalidator.ValidateStruct(body); err != nil || !result {
+		return nil, &gofemarterrors.RequestError{InternalError: errors.New("refresh token is required"), HTTPStatus: http.StatusBadRequest}
+	}
+	return &body, nil
+}
 
-	if rErr := helpers.SetHTTPResponse(response, http.StatusOK, responseBody); rErr != nil {
-		logger.Log.Error(rErr)
+// LogoutHandler завершает текущую сессию пользователя.
+// @Summary Выход из текущей сессии
+// @Description Отзывает сессию токена доступа, её токены доступа и обновления больше не принимаются.
+// @Tags Пользователь
+// @Produce json
+// @Success 200 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/logout [post]
+func (l *Handlers) LogoutHandler(response http.ResponseWriter, request *http.Request) {
+	user, ok := request.Context().Value(token.UserKey).(*models.User)
+	if !ok {
+		helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, response)
+		return
+	}
+	sessionID, ok := request.Context().Value(token.SessionKey).(string)
+	if !ok {
+		helpers.ProcessResponseWithStatus("Session not found", http.StatusUnauthorized, response)
+		return
+	}
+	if err := l.sessionService(request).Logout(user.ID, sessionID); err != nil {
 		helpers.SetInternalError(err, response)
+		return
+	}
+	helpers.ProcessResponseWithStatus("logged out", http.StatusOK, response)
+}
+
+// LogoutAllHandler завершает все сессии пользователя.
+// @Summary Выход из всех сессий
+// @Description Отзывает все сессии пользователя, включая текущую.
+// @Tags Пользователь
+// @Produce json
+// @Success 200 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/logout/all [post]
+func (l *Handlers) LogoutAllHandler(response http.ResponseWriter, request *http.Request) {
+	user, ok := request.Context().Value(token.UserKey).(*models.User)
+	if !ok {
+		helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, response)
+		return
+	}
+	revoked, err := l.sessionService(request).LogoutAll(user.ID)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
 	}
+	logger.Log.Infow("User logged out everywhere", "user", user.ID, "sessions", revoked)
+	helpers.ProcessResponseWithStatus("logged out everywhere", http.StatusOK, response)
 }
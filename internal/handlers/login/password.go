@@ -0,0 +1,155 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/asaskevich/govalidator"
+	"gofemart/internal/gofemarterrors"
+	"gofemart/internal/helpers"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"gofemart/internal/services"
+	"gofemart/internal/token"
+	"io"
+	"net/http"
+)
+
+// ChangePasswordHandler меняет пароль авторизованного пользователя.
+// @Summary Смена пароля
+// @Description Меняет пароль после проверки текущего, все остальные сессии пользователя отзываются, текущая сохраняется.
+// @Description Неверный текущий пароль учитывается как неудачная попытка входа.
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param password body payloads.ChangePassword true "Текущий и новый пароль"
+// @Success 200 {object} payloads.ErrorResponseBody
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 403 {object} payloads.ErrorResponseBody
+// @Failure 429 {object} payloads.ErrorResponseBody "Слишком много попыток, время ожидания в заголовке Retry-After"
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/password [post]
+func (l *Handlers) ChangePasswordHandler(response http.ResponseWriter, request *http.Request) {
+	user, ok := request.Context().Value(token.UserKey).(*models.User)
+	if !ok {
+		helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, response)
+		return
+	}
+	sessionID, ok := request.Context().Value(token.SessionKey).(string)
+	if !ok {
+		helpers.ProcessResponseWithStatus("Session not found", http.StatusUnauthorized, response)
+		return
+	}
+	body, err := getPasswordBody[payloads.ChangePassword](request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
+	// Подбор текущего пароля по украденному токену ограничиваем так же, как вход
+	ip := helpers.ClientIP(request)
+	if wait := l.guard.Wait(user.Login, ip); wait > 0 {
+		helpers.SetRetryAfter("too many password attempts", wait, response)
+		return
+	}
+
+	err = l.passwordService(request.Context()).Change(user, sessionID, body.CurrentPassword, body.NewPassword)
+	if errors.Is(err, services.ErrorWrongPassword) {
+		if _, gErr := l.guard.Failed(nil, user.Login, ip, nil); gErr != nil {
+			logger.Log.Error(gErr)
+		}
+		helpers.ProcessResponseWithStatus(err.Error(), http.StatusForbidden, response)
+		return
+	}
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	helpers.ProcessResponseWithStatus("password changed", http.StatusOK, response)
+}
+
+// PasswordResetRequestHandler отправляет пользователю токен сброса пароля.
+// @Summary Запрос сброса пароля
+// @Description Создаёт одноразовый токен сброса пароля и отправляет его пользователю в фоне, ответ не зависит от того, существует ли пользователь с таким логином.
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param reset body payloads.PasswordResetRequest true "Логин пользователя"
+// @Success 202 {object} payloads.ErrorResponseBody
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 429 {object} payloads.ErrorResponseBody "Слишком много запросов, время ожидания в заголовке Retry-After"
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/password/reset/request [post]
+func (l *Handlers) PasswordResetRequestHandler(response http.ResponseWriter, request *http.Request) {
+	body, err := getPasswordBody[payloads.PasswordResetRequest](request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
+	// Рассылку токенов ограничиваем по логину и по адресу отдельно от попыток входа
+	if wait := l.resetLimiter.Allow(body.Login, helpers.ClientIP(request)); wait > 0 {
+		helpers.SetRetryAfter("too many password reset requests", wait, response)
+		return
+	}
+
+	// Токен создаётся и отправляется в фоне, чтобы ни ответ, ни время ответа не зависели от того, существует ли пользователь.
+	// Остановка приложения дожидается фоновой отправки, поэтому созданный токен не остаётся без уведомления.
+	service := l.passwordService(context.WithoutCancel(request.Context()))
+	l.resetTasks.Go(func() {
+		if err := service.RequestReset(body.Login); err != nil {
+			logger.Log.Error(err)
+		}
+	})
+	helpers.ProcessResponseWithStatus("password reset requested", http.StatusAccepted, response)
+}
+
+// PasswordResetHandler задаёт новый пароль по токену сброса.
+// @Summary Сброс пароля
+// @Description Задаёт новый пароль по одноразовому токену сброса, все сессии пользователя отзываются, блокировка входа снимается.
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param reset body payloads.PasswordReset true "Токен сброса и новый пароль"
+// @Success 200 {object} payloads.ErrorResponseBody
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/password/reset [post]
+func (l *Handlers) PasswordResetHandler(response http.ResponseWriter, request *http.Request) {
+	body, err := getPasswordBody[payloads.PasswordReset](request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
+	err = l.passwordService(request.Context()).Reset(body.Token, body.NewPassword)
+	if errors.Is(err, services.ErrorInvalidResetToken) {
+		helpers.ProcessResponseWithStatus(err.Error(), http.StatusBadRequest, response)
+		return
+	}
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	helpers.ProcessResponseWithStatus("password reset", http.StatusOK, response)
+}
+
+// passwordService сервис паролей с контекстом ctx
+func (l *Handlers) passwordService(ctx context.Context) *services.PasswordService {
+	return services.NewPasswordService(ctx, l.dbPool, l.notifier, l.resetExpiration, l.hashKey)
+}
+
+// getPasswordBody получаем и проверяем тело запросов смены и сброса пароля
+func getPasswordBody[T any](request *http.Request) (*T, error) {
+	rawBody, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body T
+	if err = json.Unmarshal(rawBody, &body); err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	if _, err = govalidator.ValidateStruct(body); err != nil {
+		return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+	}
+	return &body, nil
+}
@@ -1,18 +1,31 @@
 package orders
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gofemart/internal/gofemarterrors"
 	"gofemart/internal/helpers"
 	"gofemart/internal/luna"
 	"gofemart/internal/models"
 	"gofemart/internal/ordercheck"
+	"gofemart/internal/pagination"
 	"gofemart/internal/repositories"
+	"gofemart/internal/services"
 	"gofemart/internal/token"
+	"gofemart/internal/tracing"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// maxBatchSize максимальное количество номеров заказов в одном пакете
+const maxBatchSize = 10000
+
 // Handlers Хэндлеры работы с заказами
 type Handlers struct {
 	dbPool repositories.SQLExecutor
@@ -33,6 +46,7 @@ func NewHandlers(dbPool repositories.SQLExecutor) *Handlers {
 // @Accept json
 // @Produce json
 // @Param order body string true "Order number"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повторный запрос с тем же ключом вернёт сохранённый ответ"
 // @Success 200 {object} payloads.ErrorResponseBody
 // @Success 202 {object} payloads.ErrorResponseBody
 // @Failure 400 {object} payloads.ErrorResponseBody
@@ -48,6 +62,8 @@ func (h *Handlers) RegisterOrderHandler(response http.ResponseWriter, request *h
 		return
 	}
 	strBody := strings.Trim(string(body), " \n\r")
+	ctx, span := tracing.Start(request.Context(), "orders.register", strBody)
+	defer span.End()
 
 	// Проверим полученный номер алгоритмом луна
 	ok, err := luna.Check(strBody)
@@ -67,7 +83,7 @@ func (h *Handlers) RegisterOrderHandler(response http.ResponseWriter, request *h
 		return
 	}
 
-	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
+	rep := repositories.NewOrderRepository(ctx, h.dbPool)
 
 	order, ok, err := h.getOrderFromBd(rep, strBody)
 	if err != nil {
@@ -91,13 +107,95 @@ func (h *Handlers) RegisterOrderHandler(response http.ResponseWriter, request *h
 		helpers.SetInternalError(err, response)
 		return
 	}
-	if _, err := h.sendToQueue(order); err != nil {
+	if _, err := h.sendToQueue(ctx, order); err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
 	helpers.ProcessResponseWithStatus("new order number accepted for processing", http.StatusAccepted, response)
 }
 
+// RegisterOrdersBatchHandler обрабатывает запрос на пакетную регистрацию заказов.
+// Принимает JSON массив номеров или номера, разделённые переводом строки.
+// @Summary Регистрирует пакет заказов
+// @Description Каждый номер проверяется алгоритмом Луна, новые номера сохраняются одним запросом и отправляются на проверку начислений. Возвращает результат по каждому номеру.
+// @Tags Заказы
+// @Accept json
+// @Accept plain
+// @Produce json
+// @Param orders body []string true "Order numbers"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повторный запрос с тем же ключом вернёт сохранённый ответ"
+// @Success 200 {array} payloads.BatchOrderResult
+// @Failure 400 {object} payloads.ErrorResponseBody
+// @Failure 401 {object} payloads.ErrorResponseBody
+// @Failure 500 {object} payloads.ErrorResponseBody
+// @Router /api/user/orders/batch [post]
+func (h *Handlers) RegisterOrdersBatchHandler(response http.ResponseWriter, request *http.Request) {
+	// Берём авторизованного пользователя
+	user, ok := request.Context().Value(token.UserKey).(*models.User)
+	if !ok {
+		helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, response)
+		return
+	}
+
+	numbers, err := getBatchNumbers(request)
+	if err != nil {
+		helpers.ProcessRequestErrorWithBody(err, response)
+		return
+	}
+
+	service := services.NewOrderBatchService(request.Context(), h.dbPool, ordercheck.CheckPool)
+	results, err := service.Upload(user.ID, numbers)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+
+	res, err := json.Marshal(results)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, res); err != nil {
+		helpers.SetInternalError(err, response)
+	}
+}
+
+// getBatchNumbers считываем номера заказов из тела запроса.
+// Тело в формате JSON массива строк или список номеров, разделённых переводом строки. Пустые строки пропускаются.
+func getBatchNumbers(request *http.Request) ([]string, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var numbers []string
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var raw []string
+		if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+			return nil, &gofemarterrors.RequestError{InternalError: err, HTTPStatus: http.StatusBadRequest}
+		}
+		for _, number := range raw {
+			if number = strings.TrimSpace(number); number != "" {
+				numbers = append(numbers, number)
+			}
+		}
+	} else {
+		for _, line := range strings.Split(trimmed, "\n") {
+			if number := strings.Trim(line, " \r\t"); number != "" {
+				numbers = append(numbers, number)
+			}
+		}
+	}
+
+	if len(numbers) == 0 {
+		return nil, &gofemarterrors.RequestError{InternalError: errors.New("no order numbers"), HTTPStatus: http.StatusBadRequest}
+	}
+	if len(numbers) > maxBatchSize {
+		return nil, &gofemarterrors.RequestError{InternalError: fmt.Errorf("too many order numbers, maximum is %d", maxBatchSize), HTTPStatus: http.StatusBadRequest}
+	}
+	return numbers, nil
+}
+
 // getOrderFromBd извлекает заказ из базы данных на основе предоставленного номера заказа.
 // Он возвращает заказ, логическое значение, указывающее, был ли заказ найден, и любые ошибки, возникшие в ходе процесса.
 func (h *Handlers) getOrderFromBd(rep *repositories.OrderRepository, number string) (*models.Order, bool, error) {
@@ -111,17 +209,25 @@ func (h *Handlers) saveOrder(rep *repositories.OrderRepository, order *models.Or
 
 // sendToQueue отправляет заказ в очередь для дальнейшей обработки.
 // Возвращает логическое значение, указывающее на успешность операции, и ошибку, если она возникла.
-func (h *Handlers) sendToQueue(order *models.Order) (bool, error) {
-	return ordercheck.CheckPool.Push(order)
+func (h *Handlers) sendToQueue(ctx context.Context, order *models.Order) (bool, error) {
+	return ordercheck.CheckPool.Push(ctx, order)
 }
 
 // GetOrdersHandler обрабатывает запросы на получение списка заказов для аутентифицированного пользователя.
 // @Summary Получить список заказов
-// @Description Возвращает список заказов для аутентифицированного пользователя.
+// @Description Возвращает список заказов для аутентифицированного пользователя. Без параметров выборки возвращает весь список массивом, с параметрами — страницу с курсором на следующую.
 // @Tags Заказы
 // @Produce  json
+// @Param limit query int false "Количество заказов на странице"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param status query string false "Фильтр по статусу, можно перечислить через запятую"
+// @Param from query string false "Загруженные не раньше, RFC3339"
+// @Param to query string false "Загруженные не позже, RFC3339"
+// @Param sort query string false "Направление сортировки по времени загрузки: asc или desc"
 // @Success 200 {array} models.OrderWithAccrual "Список заказов"
+// @Success 200 {object} pagination.Page[models.OrderWithAccrual] "Страница заказов"
 // @Failure 204 {string} payloads.ErrorResponseBody
+// @Failure 400 {string} payloads.ErrorResponseBody
 // @Failure 401 {string} payloads.ErrorResponseBody
 // @Failure 500 {string} payloads.ErrorResponseBody
 // @Router /api/user/orders [get]
@@ -133,19 +239,37 @@ func (h *Handlers) GetOrdersHandler(response http.ResponseWriter, request *http.
 		return
 	}
 
+	query, ok := getListQuery(request, response)
+	if !ok {
+		return
+	}
+	for _, status := range query.Statuses {
+		if !models.IsOrderStatus(status) {
+			helpers.ProcessResponseWithStatus("unknown order status "+status, http.StatusBadRequest, response)
+			return
+		}
+	}
+
 	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
-	orders, err := rep.GetOrdersByUserWithAccrual(user.ID)
+	orders, err := rep.GetOrdersByUserWithAccrual(user.ID, query)
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
 
-	if len(orders) == 0 {
+	if len(orders) == 0 && !query.Paginated {
 		helpers.ProcessResponseWithStatus("no orders found", http.StatusNoContent, response)
 		return
 	}
 
-	res, err := json.Marshal(orders)
+	var res []byte
+	if query.Paginated {
+		res, err = json.Marshal(pagination.NewPage(orders, query.Limit, func(order models.OrderWithAccrual) pagination.Cursor {
+			return pagination.Cursor{At: order.CreatedAt, Key: order.Number}
+		}))
+	} else {
+		res, err = json.Marshal(orders)
+	}
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
@@ -157,11 +281,18 @@ func (h *Handlers) GetOrdersHandler(response http.ResponseWriter, request *http.
 
 // GetOrdersWwithdrawalsHandler обрабатывает запросы на получение списка заказов со снятием средств для аутентифицированного пользователя.
 // @Summary Получить заказы со снятием средств
-// @Description Возвращает список заказов со снятием средств для аутентифицированного пользователя.
+// @Description Возвращает список заказов со снятием средств для аутентифицированного пользователя. Без параметров выборки возвращает весь список массивом, с параметрами — страницу с курсором на следующую.
 // @Tags Заказы
 // @Produce  json
+// @Param limit query int false "Количество списаний на странице"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param from query string false "Списания не раньше, RFC3339"
+// @Param to query string false "Списания не позже, RFC3339"
+// @Param sort query string false "Направление сортировки по времени списания: asc или desc"
 // @Success 200 {array} models.OrderWithdraw "Список заказов"
+// @Success 200 {object} pagination.Page[models.OrderWithdraw] "Страница списаний"
 // @Failure 204 {string} payloads.ErrorResponseBody
+// @Failure 400 {string} payloads.ErrorResponseBody
 // @Failure 401 {string} payloads.ErrorResponseBody
 // @Failure 500 {string} payloads.ErrorResponseBody
 // @Router /api/user/withdrawals [get]
@@ -173,19 +304,39 @@ func (h *Handlers) GetOrdersWwithdrawalsHandler(response http.ResponseWriter, re
 		return
 	}
 
+	query, ok := getListQuery(request, response)
+	if !ok {
+		return
+	}
+	if len(query.Statuses) > 0 {
+		helpers.ProcessResponseWithStatus("status filter is not supported for withdrawals", http.StatusBadRequest, response)
+		return
+	}
+
 	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
-	orders, err := rep.GetOrdersByUserWithdraw(user.ID)
+	orders, err := rep.GetOrdersByUserWithdraw(user.ID, query)
+	if errors.Is(err, pagination.ErrorInvalidCursor) {
+		helpers.ProcessResponseWithStatus(err.Error(), http.StatusBadRequest, response)
+		return
+	}
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
 	}
 
-	if len(orders) == 0 {
+	if len(orders) == 0 && !query.Paginated {
 		helpers.ProcessResponseWithStatus("no orders withdrawals", http.StatusNoContent, response)
 		return
 	}
 
-	res, err := json.Marshal(orders)
+	var res []byte
+	if query.Paginated {
+		res, err = json.Marshal(pagination.NewPage(orders, query.Limit, func(order models.OrderWithdraw) pagination.Cursor {
+			return pagination.Cursor{At: order.ProcessedAt.Time, Key: strconv.FormatInt(order.ID, 10)}
+		}))
+	} else {
+		res, err = json.Marshal(orders)
+	}
 	if err != nil {
 		helpers.SetInternalError(err, response)
 		return
@@ -194,3 +345,136 @@ func (h *Handlers) GetOrdersWwithdrawalsHandler(response http.ResponseWriter, re
 		helpers.SetInternalError(err, response)
 	}
 }
+
+// GetOrderHandler обрабатывает запросы на получение подробной информации об одном заказе аутентифицированного пользователя.
+// @Summary Получить заказ пользователя
+// @Description Возвращает статус заказа, начисление, время загрузки и последней проверки, нахождение в очереди на обработку и связанное списание.
+// @Tags Заказы
+// @Produce  json
+// @Param number path string true "Order number"
+// @Success 200 {object} models.OrderDetails "Информация о заказе"
+// @Failure 401 {string} payloads.ErrorResponseBody
+// @Failure 403 {string} payloads.ErrorResponseBody
+// @Failure 404 {string} payloads.ErrorResponseBody
+// @Failure 500 {string} payloads.ErrorResponseBody
+// @Router /api/user/orders/{number} [get]
+func (h *Handlers) GetOrderHandler(response http.ResponseWriter, request *http.Request) {
+	// Берём авторизованного пользователя
+	user, ok := request.Context().Value(token.UserKey).(*models.User)
+	if !ok {
+		helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, response)
+		return
+	}
+
+	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
+	order, ok := h.getUserOrder(rep, user, chi.URLParam(request, "number"), response)
+	if !ok {
+		return
+	}
+
+	details := models.NewOrderDetails(order)
+	// Заказ могут проверять другие экземпляры приложения, поэтому кроме своей очереди смотрим на аренду в базе данных
+	details.Queued = order.Leased(time.Now()) || ordercheck.CheckPool.InQueue(order.Number)
+	accrual, err := rep.GetOrderAccrual(order.Number)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	details.Accrual = accrual
+	withdraw, ok, err := rep.GetOrderWithdraw(order.Number, user.ID)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if ok {
+		details.Withdrawal = withdraw
+	}
+
+	res, err := json.Marshal(details)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, res); err != nil {
+		helpers.SetInternalError(err, response)
+	}
+}
+
+// GetOrderHistoryHandler обрабатывает запросы на получение истории статусов заказа аутентифицированного пользователя.
+// @Summary Получить историю статусов заказа
+// @Description Возвращает изменения статуса заказа по ответам системы расчёта начислений в хронологическом порядке.
+// @Tags Заказы
+// @Produce  json
+// @Param number path string true "Order number"
+// @Success 200 {array} models.OrderStatusHistory "История статусов заказа"
+// @Failure 204 {string} payloads.ErrorResponseBody
+// @Failure 401 {string} payloads.ErrorResponseBody
+// @Failure 403 {string} payloads.ErrorResponseBody
+// @Failure 404 {string} payloads.ErrorResponseBody
+// @Failure 500 {string} payloads.ErrorResponseBody
+// @Router /api/user/orders/{number}/history [get]
+func (h *Handlers) GetOrderHistoryHandler(response http.ResponseWriter, request *http.Request) {
+	// Берём авторизованного пользователя
+	user, ok := request.Context().Value(token.UserKey).(*models.User)
+	if !ok {
+		helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, response)
+		return
+	}
+
+	rep := repositories.NewOrderRepository(request.Context(), h.dbPool)
+	order, ok := h.getUserOrder(rep, user, chi.URLParam(request, "number"), response)
+	if !ok {
+		return
+	}
+
+	history, err := rep.GetStatusHistory(order.Number)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+
+	if len(history) == 0 {
+		helpers.ProcessResponseWithStatus("no order history", http.StatusNoContent, response)
+		return
+	}
+
+	res, err := json.Marshal(history)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, res); err != nil {
+		helpers.SetInternalError(err, response)
+	}
+}
+
+// getListQuery разбираем параметры выборки списка из строки запроса.
+// Если параметры некорректны, записывает ответ с ошибкой и возвращает false.
+func getListQuery(request *http.Request, response http.ResponseWriter) (*pagination.Query, bool) {
+	query, err := pagination.ParseQuery(request.URL.Query())
+	if err != nil {
+		helpers.ProcessResponseWithStatus(err.Error(), http.StatusBadRequest, response)
+		return nil, false
+	}
+	return query, true
+}
+
+// getUserOrder извлекает заказ пользователя по номеру.
+// Если заказ не найден или принадлежит другому пользователю, записывает ответ с ошибкой и возвращает false.
+// Для чужих заказов не раскрывается никакой информации, кроме факта запрета доступа.
+func (h *Handlers) getUserOrder(rep *repositories.OrderRepository, user *models.User, number string, response http.ResponseWriter) (*models.Order, bool) {
+	order, exists, err := h.getOrderFromBd(rep, number)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return nil, false
+	}
+	if !exists {
+		helpers.ProcessResponseWithStatus("order not found", http.StatusNotFound, response)
+		return nil, false
+	}
+	if order.UserID != user.ID {
+		helpers.ProcessResponseWithStatus("access to the order is forbidden", http.StatusForbidden, response)
+		return nil, false
+	}
+	return order, true
+}
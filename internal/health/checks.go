@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gofemart/internal/databse/migrations"
+	"time"
+)
+
+// ErrorPoolNotRunning Ошибка, что пул проверки заказов закрыт или не запущен
+var ErrorPoolNotRunning = errors.New("order check pool is not running")
+
+// ErrorMigrationsPending Ошибка, что схема базы данных отстаёт от встроенных миграций
+var ErrorMigrationsPending = errors.New("migrations are not at the latest version")
+
+// ErrorAccrualCircuitOpen Ошибка, что выключатели запросов открыты у всех поставщиков начислений
+var ErrorAccrualCircuitOpen = errors.New("accrual circuit is open for all providers")
+
+// PoolState состояние пула проверки заказов
+type PoolState interface {
+	Running() bool
+}
+
+// AccrualState состояние выключателей запросов к поставщикам начислений
+type AccrualState interface {
+	AccrualOpenFor() time.Duration
+}
+
+// DatabaseCheck проверка доступности базы данных
+func DatabaseCheck(db *sql.DB) Check {
+	return Check{
+		Name: "database",
+		Run:  db.PingContext,
+	}
+}
+
+// MigrationsCheck проверка, что к базе данных применены все миграции, встроенные в приложение, latest версия последней из них.
+// Схема новее встроенных миграций не считается ошибкой, чтобы старые экземпляры оставались готовыми во время обновления.
+func MigrationsCheck(db *sql.DB, latest int64) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			current, err := migrations.Current(ctx, db)
+			if err != nil {
+				return err
+			}
+			if current < latest {
+				return fmt.Errorf("%w: current %d, latest %d", ErrorMigrationsPending, current, latest)
+			}
+			return nil
+		},
+	}
+}
+
+// PoolCheck проверка, что пул проверки заказов запущен и не закрыт
+func PoolCheck(pool PoolState) Check {
+	return Check{
+		Name: "checkpool",
+		Run: func(context.Context) error {
+			if pool == nil || !pool.Running() {
+				return ErrorPoolNotRunning
+			}
+			return nil
+		},
+	}
+}
+
+// AccrualCheck проверка, что хотя бы один поставщик начислений принимает запросы.
+// Если optional, открытые выключатели не делают приложение неготовым.
+func AccrualCheck(accrual AccrualState, optional bool) Check {
+	return Check{
+		Name:     "accrual",
+		Optional: optional,
+		Run: func(context.Context) error {
+			if openFor := accrual.AccrualOpenFor(); openFor > 0 {
+				return fmt.Errorf("%w: retry in %s", ErrorAccrualCircuitOpen, openFor.Round(time.Second))
+			}
+			return nil
+		},
+	}
+}
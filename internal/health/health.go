@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gofemart/internal/helpers"
+	"gofemart/internal/logger"
+	"gofemart/internal/payloads"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCheckTimeout время на выполнение одной проверки, если в конфигурации оно не задано
+const defaultCheckTimeout = 2 * time.Second
+
+// shutdownCheckName имя проверки завершения работы в ответе готовности
+const shutdownCheckName = "shutdown"
+
+// ErrorShuttingDown Ошибка, что приложение завершает работу и не принимает новые запросы
+var ErrorShuttingDown = errors.New("shutting down")
+
+// Check проверка готовности приложения.
+// Проверка с Optional попадает в ответ, но не делает приложение неготовым.
+type Check struct {
+	Name     string
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+// Probe проверки живости и готовности приложения для оркестратора
+type Probe struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewProbe создаём проверки готовности, каждая проверка ограничена временем timeout
+func NewProbe(timeout time.Duration, checks ...Check) *Probe {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	return &Probe{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Shutdown отмечаем, что приложение завершает работу, после этого оно всегда не готово
+func (p *Probe) Shutdown() {
+	logger.Log.Info("Readiness probe switched to shutting down")
+	p.shuttingDown.Store(true)
+}
+
+// Ready выполняем проверки параллельно и собираем результат
+func (p *Probe) Ready(ctx context.Context) payloads.Health {
+	result := payloads.Health{Status: payloads.HealthStatusOK, Checks: make(map[string]payloads.HealthCheck, len(p.checks)+1)}
+	if p.shuttingDown.Load() {
+		result.Status = payloads.HealthStatusFail
+		result.Checks[shutdownCheckName] = payloads.HealthCheck{Status: payloads.HealthStatusFail, Error: ErrorShuttingDown.Error(), Duration: "0s"}
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range p.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			checkResult := p.run(ctx, check)
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Checks[check.Name] = checkResult
+			if checkResult.Status == payloads.HealthStatusFail {
+				result.Status = payloads.HealthStatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+	return result
+}
+
+// run выполняем одну проверку с ограничением по времени
+func (p *Probe) run(ctx context.Context, check Check) payloads.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Run(ctx)
+	checkResult := payloads.HealthCheck{Status: payloads.HealthStatusOK, Duration: time.Since(start).String()}
+	if err == nil {
+		return checkResult
+	}
+	checkResult.Error = err.Error()
+	checkResult.Status = payloads.HealthStatusFail
+	if check.Optional {
+		checkResult.Status = payloads.HealthStatusWarn
+	}
+	logger.Log.Warnw("Readiness check failed", "check", check.Name, "optional", check.Optional, "error", err)
+	return checkResult
+}
+
+// LivenessHandler отвечает, что процесс жив и обрабатывает запросы.
+// @Summary Проверка живости
+// @Description Отвечает 200, пока процесс обрабатывает запросы, зависимости не проверяются.
+// @Tags Служебные
+// @Produce json
+// @Success 200 {object} payloads.Health
+// @Router /healthz [get]
+func (p *Probe) LivenessHandler(response http.ResponseWriter, _ *http.Request) {
+	writeHealth(response, http.StatusOK, payloads.Health{Status: payloads.HealthStatusOK})
+}
+
+// ReadinessHandler отвечает, готово ли приложение принимать запросы, с разбивкой по проверкам.
+// @Summary Проверка готовности
+// @Description Проверяет базу данных, версию миграций, пул проверки заказов и выключатели поставщиков начислений.
+// @Description Отвечает 503, если обязательная проверка не прошла или приложение завершает работу.
+// @Tags Служебные
+// @Produce json
+// @Success 200 {object} payloads.Health
+// @Failure 503 {object} payloads.Health
+// @Router /readyz [get]
+func (p *Probe) ReadinessHandler(response http.ResponseWriter, request *http.Request) {
+	result := p.Ready(request.Context())
+	status := http.StatusOK
+	if result.Status != payloads.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(response, status, result)
+}
+
+// writeHealth записываем результат проверки в ответ
+func writeHealth(response http.ResponseWriter, status int, result payloads.Health) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	if err := helpers.SetHTTPResponse(response, status, body); err != nil {
+		logger.Log.Error(err)
+	}
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gofemart/internal/payloads"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakePool пул с заданным состоянием
+type fakePool struct {
+	running bool
+	openFor time.Duration
+}
+
+func (p fakePool) Running() bool {
+	return p.running
+}
+
+func (p fakePool) AccrualOpenFor() time.Duration {
+	return p.openFor
+}
+
+func okCheck(name string) Check {
+	return Check{Name: name, Run: func(context.Context) error { return nil }}
+}
+
+func failCheck(name string, optional bool) Check {
+	return Check{Name: name, Optional: optional, Run: func(context.Context) error { return errors.New(name + " failed") }}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		checks     []Check
+		shutdown   bool
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "ready",
+			checks:     []Check{okCheck("database"), PoolCheck(fakePool{running: true}), AccrualCheck(fakePool{}, false)},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"database": payloads.HealthStatusOK, "checkpool": payloads.HealthStatusOK, "accrual": payloads.HealthStatusOK},
+		},
+		{
+			name:       "required_check_failed",
+			checks:     []Check{failCheck("database", false), okCheck("migrations")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": payloads.HealthStatusFail, "migrations": payloads.HealthStatusOK},
+		},
+		{
+			name:       "pool_closed",
+			checks:     []Check{PoolCheck(fakePool{running: false})},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"checkpool": payloads.HealthStatusFail},
+		},
+		{
+			name:       "optional_accrual_circuit_open",
+			checks:     []Check{okCheck("database"), AccrualCheck(fakePool{openFor: time.Minute}, true)},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"database": payloads.HealthStatusOK, "accrual": payloads.HealthStatusWarn},
+		},
+		{
+			name:       "required_accrual_circuit_open",
+			checks:     []Check{AccrualCheck(fakePool{openFor: time.Minute}, false)},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"accrual": payloads.HealthStatusFail},
+		},
+		{
+			name:       "shutting_down",
+			checks:     []Check{okCheck("database")},
+			shutdown:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": payloads.HealthStatusOK, shutdownCheckName: payloads.HealthStatusFail},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			probe := NewProbe(time.Second, tc.checks...)
+			if tc.shutdown {
+				probe.Shutdown()
+			}
+			recorder := httptest.NewRecorder()
+			probe.ReadinessHandler(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("expected status %d, got %d", tc.wantStatus, recorder.Code)
+			}
+			var body payloads.Health
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Checks) != len(tc.wantChecks) {
+				t.Errorf("expected checks %v, got %v", tc.wantChecks, body.Checks)
+			}
+			for name, status := range tc.wantChecks {
+				if body.Checks[name].Status != status {
+					t.Errorf("expected check %s status %s, got %+v", name, status, body.Checks[name])
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	probe := NewProbe(10*time.Millisecond, Check{Name: "slow", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	result := probe.Ready(context.Background())
+	if result.Status != payloads.HealthStatusFail || result.Checks["slow"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("expected slow check to fail by timeout, got %+v", result)
+	}
+}
+
+func TestLivenessHandlerWhileShuttingDown(t *testing.T) {
+	probe := NewProbe(time.Second, failCheck("database", false))
+	probe.Shutdown()
+	recorder := httptest.NewRecorder()
+	probe.LivenessHandler(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"gofemart/internal/gofemarterrors"
 	"gofemart/internal/logger"
 	"gofemart/internal/payloads"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // SetHTTPResponse Отправка ошибки и сообщения ошибки.
@@ -75,3 +78,19 @@ func ProcessResponseWithStatus(message string, status int, response http.Respons
 		logger.Log.Error(rErr)
 	}
 }
+
+// ClientIP адрес клиента из соединения без порта
+func ClientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// SetRetryAfter отвечаем 429 с заголовком Retry-After в целых секундах, округлённых вверх
+func SetRetryAfter(message string, wait time.Duration, response http.ResponseWriter) {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	response.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	ProcessResponseWithStatus(message, http.StatusTooManyRequests, response)
+}
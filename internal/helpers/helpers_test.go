@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestSetHTTPResponse(t *testing.T) {
@@ -72,3 +73,14 @@ func TestSetHTTPResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestSetRetryAfter(t *testing.T) {
+	response := httptest.NewRecorder()
+	SetRetryAfter("too many login attempts", 1500*time.Millisecond, response)
+	if response.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", response.Code)
+	}
+	if retryAfter := response.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("expected Retry-After rounded up to 2, got %s", retryAfter)
+	}
+}
@@ -0,0 +1,32 @@
+package idempotency
+
+import (
+	"context"
+	"gofemart/internal/logger"
+	"gofemart/internal/repositories"
+	"time"
+)
+
+// RunCleanup периодически удаляет ключи идемпотентности с истёкшим сроком действия, пока не будет закрыт контекст.
+func RunCleanup(ctx context.Context, dbPool repositories.SQLQueryer, dur time.Duration) {
+	logger.Log.Infow("Run idempotency keys cleanup", "duration", dur)
+	if dur <= 0 {
+		return
+	}
+	repository := repositories.NewIdempotencyRepository(ctx, dbPool)
+	ticker := time.NewTicker(dur)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := repository.DeleteExpired(time.Now())
+			if err != nil {
+				logger.Log.Error(err)
+				continue
+			}
+			logger.Log.Infow("Expired idempotency keys deleted", "count", deleted)
+		}
+	}
+}
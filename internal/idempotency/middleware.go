@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"gofemart/internal/helpers"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/repositories"
+	"gofemart/internal/token"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// HeaderIdempotencyKey заголовок с ключом идемпотентности запроса
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed заголовок, которым помечается повторно отданный сохранённый ответ
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	// maxKeyLength максимальная длина ключа идемпотентности
+	maxKeyLength = 255
+)
+
+// keyRepository определяет методы хранилища ключей идемпотентности.
+type keyRepository interface {
+	Reserve(key *models.IdempotencyKey, abandonedBefore time.Time) (bool, error)
+	GetByKey(userID int64, key string) (*models.IdempotencyKey, bool, error)
+	SaveResponse(key *models.IdempotencyKey) error
+	Delete(key *models.IdempotencyKey) error
+}
+
+// Keeper обеспечивает идемпотентность изменяющих запросов по заголовку Idempotency-Key.
+// Первый ответ на запрос с ключом сохраняется в базе данных на время ttl
+// и отдаётся повторно на все последующие запросы пользователя с тем же ключом.
+// Если ответ не сохранён за processingTimeout, например процесс упал во время обработки, ключ можно занять заново.
+type Keeper struct {
+	ttl               time.Duration
+	processingTimeout time.Duration
+	// newRepository создаёт хранилище ключей для контекста запроса
+	newRepository func(ctx context.Context) keyRepository
+}
+
+// NewKeeper создает и возвращает новый экземпляр Keeper с указанным пулом соединений, временем хранения ответов
+// и временем, после которого незавершённый запрос считается брошенным.
+func NewKeeper(dbPool repositories.SQLQueryer, ttl time.Duration, processingTimeout time.Duration) *Keeper {
+	return &Keeper{
+		ttl:               ttl,
+		processingTimeout: processingTimeout,
+		newRepository: func(ctx context.Context) keyRepository {
+			return repositories.NewIdempotencyRepository(ctx, dbPool)
+		},
+	}
+}
+
+// Middleware выполняет запрос с ключом идемпотентности один раз и повторяет сохранённый ответ на повторные запросы.
+// Запросы без заголовка Idempotency-Key обрабатываются как обычно.
+// Должно располагаться после аутентификации, так как ключи хранятся для каждого пользователя отдельно.
+func (k *Keeper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			helpers.ProcessResponseWithStatus("Idempotency-Key is too long", http.StatusBadRequest, w)
+			return
+		}
+		// Берём авторизованного пользователя
+		user, ok := r.Context().Value(token.UserKey).(*models.User)
+		if !ok {
+			helpers.ProcessResponseWithStatus("User not found", http.StatusUnauthorized, w)
+			return
+		}
+		// Читаем тело запроса для подсчёта хэша и возвращаем его обработчику
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			helpers.SetInternalError(err, w)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Ответ сохраняется и после обрыва соединения клиентом, чтобы его можно было повторить
+		repository := k.newRepository(context.WithoutCancel(r.Context()))
+		idempotencyKey := models.NewIdempotencyKey(user.ID, key, requestHash(r, body), k.ttl)
+		reserved, err := repository.Reserve(idempotencyKey, idempotencyKey.CreatedAt.Add(-k.processingTimeout))
+		if err != nil {
+			helpers.SetInternalError(err, w)
+			return
+		}
+		if !reserved {
+			k.replay(repository, idempotencyKey, w)
+			return
+		}
+
+		// Если обработчик запаниковал, освобождаем ключ, чтобы клиент мог повторить запрос
+		defer func() {
+			if p := recover(); p != nil {
+				k.release(repository, idempotencyKey)
+				panic(p)
+			}
+		}()
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+		k.complete(repository, idempotencyKey, recorder)
+	})
+}
+
+// replay отдаёт сохранённый ответ на запрос с уже использованным ключом
+func (k *Keeper) replay(repository keyRepository, requested *models.IdempotencyKey, w http.ResponseWriter) {
+	stored, exists, err := repository.GetByKey(requested.UserID, requested.Key)
+	if err != nil {
+		helpers.SetInternalError(err, w)
+		return
+	}
+	// Ключ мог быть освобождён между попыткой занять его и чтением
+	if !exists {
+		helpers.ProcessResponseWithStatus("request with this Idempotency-Key was not completed, retry it", http.StatusConflict, w)
+		return
+	}
+	if stored.RequestHash != requested.RequestHash {
+		helpers.ProcessResponseWithStatus("Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity, w)
+		return
+	}
+	if !stored.Completed() {
+		helpers.ProcessResponseWithStatus("request with this Idempotency-Key is still being processed", http.StatusConflict, w)
+		return
+	}
+
+	logger.Log.Infow("Replay idempotent response", "user", stored.UserID, "key", stored.Key, "status", stored.ResponseStatus.Int32)
+	w.Header().Set(HeaderIdempotentReplayed, "true")
+	if err := helpers.SetHTTPResponse(w, int(stored.ResponseStatus.Int32), stored.ResponseBody); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// complete сохраняем ответ обработчика.
+// Ответы с ошибкой сервера не сохраняются, ключ освобождается, чтобы клиент мог повторить запрос.
+func (k *Keeper) complete(repository keyRepository, key *models.IdempotencyKey, recorder *responseRecorder) {
+	if recorder.status >= http.StatusInternalServerError {
+		k.release(repository, key)
+		return
+	}
+	key.ResponseStatus = sql.NullInt32{Int32: int32(recorder.status), Valid: true}
+	key.ResponseBody = recorder.body.Bytes()
+	if err := repository.SaveResponse(key); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// release освобождаем ключ, чтобы запрос можно было выполнить повторно
+func (k *Keeper) release(repository keyRepository, key *models.IdempotencyKey) {
+	if err := repository.Delete(key); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// requestHash считаем хэш метода, пути и тела запроса, по которому определяется повторное использование ключа с другим запросом
+func requestHash(r *http.Request, body []byte) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hasher.Write(body)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// responseRecorder http.ResponseWriter, который передаёт ответ дальше и сохраняет его статус и тело
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// newResponseRecorder создаём responseRecorder со статусом по умолчанию
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+// Write реализует метод http.ResponseWriter.Write, сохраняя записанное тело
+func (r *responseRecorder) Write(body []byte) (int, error) {
+	r.body.Write(body)
+	return r.ResponseWriter.Write(body)
+}
+
+// WriteHeader реализует метод http.ResponseWriter.WriteHeader, сохраняя статус ответа
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.status = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,256 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/idempotency/mock"
+	"gofemart/internal/models"
+	"gofemart/internal/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	user := &models.User{ID: 1}
+	body := `{"order":"2377225624","sum":751}`
+	request := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+	hash := requestHash(request, []byte(body))
+
+	tests := []struct {
+		name          string
+		key           string
+		user          *models.User
+		handlerStatus int
+		setup         func() keyRepository
+		wantCalled    bool
+		wantStatus    int
+		wantBody      string
+		wantReplayed  bool
+	}{
+		{
+			name:          "without_key",
+			user:          user,
+			handlerStatus: http.StatusOK,
+			setup: func() keyRepository {
+				return mock.NewMockkeyRepository(ctrl)
+			},
+			wantCalled: true,
+			wantStatus: http.StatusOK,
+			wantBody:   "handled",
+		},
+		{
+			name:          "without_user",
+			key:           "key",
+			handlerStatus: http.StatusOK,
+			setup: func() keyRepository {
+				return mock.NewMockkeyRepository(ctrl)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "too_long_key",
+			key:           strings.Repeat("k", maxKeyLength+1),
+			user:          user,
+			handlerStatus: http.StatusOK,
+			setup: func() keyRepository {
+				return mock.NewMockkeyRepository(ctrl)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "first_request",
+			key:           "key",
+			user:          user,
+			handlerStatus: http.StatusAccepted,
+			setup: func() keyRepository {
+				repo := mock.NewMockkeyRepository(ctrl)
+				repo.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().
+					SaveResponse(gomock.Any()).
+					DoAndReturn(func(key *models.IdempotencyKey) error {
+						if key.ResponseStatus.Int32 != http.StatusAccepted || string(key.ResponseBody) != "handled" {
+							t.Errorf("saved response = %d %s, want %d handled", key.ResponseStatus.Int32, key.ResponseBody, http.StatusAccepted)
+						}
+						return nil
+					})
+				return repo
+			},
+			wantCalled: true,
+			wantStatus: http.StatusAccepted,
+			wantBody:   "handled",
+		},
+		{
+			name:          "first_request_server_error",
+			key:           "key",
+			user:          user,
+			handlerStatus: http.StatusInternalServerError,
+			setup: func() keyRepository {
+				repo := mock.NewMockkeyRepository(ctrl)
+				repo.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().Delete(gomock.Any()).Return(nil)
+				repo.EXPECT().SaveResponse(gomock.Any()).Times(0)
+				return repo
+			},
+			wantCalled: true,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "handled",
+		},
+		{
+			name:          "replay",
+			key:           "key",
+			user:          user,
+			handlerStatus: http.StatusOK,
+			setup: func() keyRepository {
+				repo := mock.NewMockkeyRepository(ctrl)
+				repo.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(false, nil)
+				repo.EXPECT().GetByKey(user.ID, "key").Return(&models.IdempotencyKey{
+					UserID:         user.ID,
+					Key:            "key",
+					RequestHash:    hash,
+					ResponseStatus: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+					ResponseBody:   []byte("stored"),
+				}, true, nil)
+				return repo
+			},
+			wantStatus:   http.StatusOK,
+			wantBody:     "stored",
+			wantReplayed: true,
+		},
+		{
+			name:          "different_request",
+			key:           "key",
+			user:          user,
+			handlerStatus: http.StatusOK,
+			setup: func() keyRepository {
+				repo := mock.NewMockkeyRepository(ctrl)
+				repo.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(false, nil)
+				repo.EXPECT().GetByKey(user.ID, "key").Return(&models.IdempotencyKey{
+					UserID:         user.ID,
+					Key:            "key",
+					RequestHash:    "other",
+					ResponseStatus: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+				}, true, nil)
+				return repo
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:          "in_progress",
+			key:           "key",
+			user:          user,
+			handlerStatus: http.StatusOK,
+			setup: func() keyRepository {
+				repo := mock.NewMockkeyRepository(ctrl)
+				repo.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(false, nil)
+				repo.EXPECT().GetByKey(user.ID, "key").Return(&models.IdempotencyKey{
+					UserID:      user.ID,
+					Key:         "key",
+					RequestHash: hash,
+				}, true, nil)
+				return repo
+			},
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.setup()
+			keeper := &Keeper{
+				ttl: time.Hour,
+				newRepository: func(ctx context.Context) keyRepository {
+					return repo
+				},
+			}
+			called := false
+			handler := keeper.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				got, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != body {
+					t.Errorf("handler body = %s, want %s", got, body)
+				}
+				w.WriteHeader(tt.handlerStatus)
+				if _, err := w.Write([]byte("handled")); err != nil {
+					t.Fatal(err)
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+			if tt.key != "" {
+				req.Header.Set(HeaderIdempotencyKey, tt.key)
+			}
+			if tt.user != nil {
+				req = req.WithContext(context.WithValue(req.Context(), token.UserKey, tt.user))
+			}
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if response.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && response.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", response.Body.String(), tt.wantBody)
+			}
+			if replayed := response.Header().Get(HeaderIdempotentReplayed) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+		})
+	}
+}
+
+func TestMiddlewareReleasesKeyOnPanic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockkeyRepository(ctrl)
+	var reserved *models.IdempotencyKey
+	repo.EXPECT().
+		Reserve(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(key *models.IdempotencyKey, abandonedBefore time.Time) (bool, error) {
+			// Незавершённый ключ считается брошенным после processingTimeout от начала запроса
+			if !abandonedBefore.Equal(key.CreatedAt.Add(-time.Minute)) {
+				t.Errorf("abandonedBefore = %s, want %s", abandonedBefore, key.CreatedAt.Add(-time.Minute))
+			}
+			reserved = key
+			return true, nil
+		})
+	repo.EXPECT().
+		Delete(gomock.Any()).
+		DoAndReturn(func(key *models.IdempotencyKey) error {
+			if key != reserved {
+				t.Error("expected reserved key to be released")
+			}
+			return nil
+		})
+	repo.EXPECT().SaveResponse(gomock.Any()).Times(0)
+	keeper := &Keeper{
+		ttl:               time.Hour,
+		processingTimeout: time.Minute,
+		newRepository: func(ctx context.Context) keyRepository {
+			return repo
+		},
+	}
+	handler := keeper.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader("{}"))
+	req.Header.Set(HeaderIdempotencyKey, "key")
+	req = req.WithContext(context.WithValue(req.Context(), token.UserKey, &models.User{ID: 1}))
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic to be passed on")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/idempotency/middleware.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	models "gofemart/internal/models"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockkeyRepository is a mock of keyRepository interface.
+type MockkeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockkeyRepositoryMockRecorder
+}
+
+// MockkeyRepositoryMockRecorder is the mock recorder for MockkeyRepository.
+type MockkeyRepositoryMockRecorder struct {
+	mock *MockkeyRepository
+}
+
+// NewMockkeyRepository creates a new mock instance.
+func NewMockkeyRepository(ctrl *gomock.Controller) *MockkeyRepository {
+	mock := &MockkeyRepository{ctrl: ctrl}
+	mock.recorder = &MockkeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockkeyRepository) EXPECT() *MockkeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockkeyRepository) Delete(key *models.IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockkeyRepositoryMockRecorder) Delete(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockkeyRepository)(nil).Delete), key)
+}
+
+// GetByKey mocks base method.
+func (m *MockkeyRepository) GetByKey(userID int64, key string) (*models.IdempotencyKey, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByKey", userID, key)
+	ret0, _ := ret[0].(*models.IdempotencyKey)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByKey indicates an expected call of GetByKey.
+func (mr *MockkeyRepositoryMockRecorder) GetByKey(userID, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByKey", reflect.TypeOf((*MockkeyRepository)(nil).GetByKey), userID, key)
+}
+
+// Reserve mocks base method.
+func (m *MockkeyRepository) Reserve(key *models.IdempotencyKey, abandonedBefore time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", key, abandonedBefore)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockkeyRepositoryMockRecorder) Reserve(key, abandonedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockkeyRepository)(nil).Reserve), key, abandonedBefore)
+}
+
+// SaveResponse mocks base method.
+func (m *MockkeyRepository) SaveResponse(key *models.IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveResponse", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveResponse indicates an expected call of SaveResponse.
+func (mr *MockkeyRepositoryMockRecorder) SaveResponse(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveResponse", reflect.TypeOf((*MockkeyRepository)(nil).SaveResponse), key)
+}
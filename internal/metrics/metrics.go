@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+// namespace префикс имён метрик приложения
+const namespace = "gofemart"
+
+// Registry реестр метрик приложения
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests количество обработанных HTTP запросов по маршруту и статусу ответа
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by chi route, method and status.",
+	}, []string{"method", "route", "status"})
+	// HTTPDuration время обработки HTTP запросов по маршруту и статусу ответа
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by chi route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	// OrdersChecked количество проверок заказов по итоговому статусу
+	OrdersChecked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "checked_total",
+		Help:      "Number of order checks by resulting order status, FAILED for failed checks that will be retried.",
+	}, []string{"status"})
+	// AccrualResponses количество ответов поставщиков начислений по коду ответа
+	AccrualResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "responses_total",
+		Help:      "Number of accrual service responses by provider, request kind and HTTP status code, code is \"error\" when no response was received.",
+	}, []string{"provider", "kind", "code"})
+	// AccrualDuration время запросов к поставщикам начислений
+	AccrualDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "request_duration_seconds",
+		Help:      "Accrual service request latency by provider and request kind.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "kind"})
+	// AccrualPauses длительность пауз запросов к поставщикам начислений после ответа 429
+	AccrualPauses = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "pause_seconds",
+		Help:      "Pause durations requested by accrual providers with 429 responses.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"provider"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		OrdersChecked,
+		AccrualResponses,
+		AccrualDuration,
+		AccrualPauses,
+	)
+}
+
+// Handler обработчик эндпоинта /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB регистрируем метрики пула соединений базы данных из sql.DB.Stats
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unknownRoute метка маршрута для запросов, которые не совпали ни с одним маршрутом chi
+const unknownRoute = "unknown"
+
+// HTTPMetrics мидлваре, которое считает запросы и время их обработки по шаблону маршрута chi и статусу ответа.
+// Шаблон маршрута вместо пути не даёт номерам заказов раздувать количество меток.
+func HTTPMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		writer := &statusWriter{ResponseWriter: response, status: http.StatusOK}
+		next.ServeHTTP(writer, request)
+		route := unknownRoute
+		if routeContext := chi.RouteContext(request.Context()); routeContext != nil {
+			if pattern := routeContext.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		status := strconv.Itoa(writer.status)
+		HTTPRequests.WithLabelValues(request.Method, route, status).Inc()
+		HTTPDuration.WithLabelValues(request.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter http.ResponseWriter с сохранением статуса ответа
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader сохраняет статус ответа
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(HTTPMetrics)
+	router.Get("/api/user/orders/{number}", func(writer http.ResponseWriter, request *http.Request) {
+		if chi.URLParam(request, "number") == "2" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = writer.Write([]byte("{}"))
+	})
+
+	for _, path := range []string{"/api/user/orders/1", "/api/user/orders/3", "/api/user/orders/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	cases := []struct {
+		route  string
+		status string
+		want   float64
+	}{
+		{route: "/api/user/orders/{number}", status: "200", want: 2},
+		{route: "/api/user/orders/{number}", status: "404", want: 1},
+		{route: unknownRoute, status: "404", want: 1},
+	}
+	for _, tc := range cases {
+		if got := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, tc.route, tc.status)); got != tc.want {
+			t.Errorf("expected %v requests for %s %s, got %v", tc.want, tc.route, tc.status, got)
+		}
+	}
+}
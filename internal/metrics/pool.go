@@ -0,0 +1,47 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// PoolSource источник состояния пула проверки заказов
+type PoolSource interface {
+	QueueLen() int
+	QueueCap() int
+	InFlight() int
+}
+
+// poolCollector собирает состояние пула проверки заказов в момент запроса метрик
+type poolCollector struct {
+	source   PoolSource
+	queueLen *prometheus.Desc
+	queueCap *prometheus.Desc
+	inFlight *prometheus.Desc
+}
+
+// NewPoolCollector создаём сборщик метрик пула проверки заказов
+func NewPoolCollector(source PoolSource) prometheus.Collector {
+	return &poolCollector{
+		source:   source,
+		queueLen: prometheus.NewDesc(namespace+"_pool_queue_length", "Number of orders waiting in the check pool queue.", nil, nil),
+		queueCap: prometheus.NewDesc(namespace+"_pool_queue_capacity", "Capacity of the check pool queue.", nil, nil),
+		inFlight: prometheus.NewDesc(namespace+"_pool_in_flight_orders", "Number of orders being checked right now.", nil, nil),
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueLen
+	ch <- c.queueCap
+	ch <- c.inFlight
+}
+
+// Collect реализует prometheus.Collector
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.queueLen, prometheus.GaugeValue, float64(c.source.QueueLen()))
+	ch <- prometheus.MustNewConstMetric(c.queueCap, prometheus.GaugeValue, float64(c.source.QueueCap()))
+	ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(c.source.InFlight()))
+}
+
+// RegisterPool регистрируем метрики пула проверки заказов
+func RegisterPool(source PoolSource) error {
+	return Registry.Register(NewPoolCollector(source))
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+)
+
+// poolSource состояние пула для тестов
+type poolSource struct {
+	queueLen int
+	queueCap int
+	inFlight int
+}
+
+func (s poolSource) QueueLen() int { return s.queueLen }
+func (s poolSource) QueueCap() int { return s.queueCap }
+func (s poolSource) InFlight() int { return s.inFlight }
+
+func TestPoolCollector(t *testing.T) {
+	collector := NewPoolCollector(poolSource{queueLen: 3, queueCap: 1000, inFlight: 2})
+	want := `
+# HELP gofemart_pool_in_flight_orders Number of orders being checked right now.
+# TYPE gofemart_pool_in_flight_orders gauge
+gofemart_pool_in_flight_orders 2
+# HELP gofemart_pool_queue_capacity Capacity of the check pool queue.
+# TYPE gofemart_pool_queue_capacity gauge
+gofemart_pool_queue_capacity 1000
+# HELP gofemart_pool_queue_length Number of orders waiting in the check pool queue.
+# TYPE gofemart_pool_queue_length gauge
+gofemart_pool_queue_length 3
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"gofemart/internal/helpers"
+	"net/http"
+	"strings"
+)
+
+// AdminAuth проверяем доступ к административному API по статическому токену в заголовке Authorization: Bearer <token>.
+// Если токен не задан, административный API отключён и все запросы отклоняются.
+func AdminAuth(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				helpers.ProcessResponseWithStatus("admin API is disabled", http.StatusForbidden, w)
+				return
+			}
+			requestToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) != 1 {
+				helpers.ProcessResponseWithStatus("invalid admin token", http.StatusUnauthorized, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
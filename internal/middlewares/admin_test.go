@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		wantStatus int
+	}{
+		{
+			name:       "valid_token",
+			token:      "secret",
+			header:     "Bearer secret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong_token",
+			token:      "secret",
+			header:     "Bearer other",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "without_bearer",
+			token:      "secret",
+			header:     "secret",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "admin_disabled",
+			token:      "",
+			header:     "Bearer ",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AdminAuth(tt.token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			request := httptest.NewRequest(http.MethodGet, "/api/admin/orders/parked", nil)
+			request.Header.Set("Authorization", tt.header)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+			if response.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.Code, tt.wantStatus)
+			}
+		})
+	}
+}
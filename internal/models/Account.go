@@ -11,14 +11,14 @@ import (
 type Account struct {
 	ID          int64          `db:"id"`
 	UserID      int64          `db:"user_id"`
-	Difference  float64        `db:"difference"`
+	Difference  Money          `db:"difference"`
 	OrderNumber sql.NullString `db:"order_number"`
 	CreatedAt   time.Time      `db:"created_at"`
 	UpdatedAt   time.Time      `db:"updated_at"`
 }
 
 // NewAccount создает новый экземпляр Account с указанным номером заказа, идентификатором пользователя и суммой начисления.
-func NewAccount(orderNumber sql.NullString, userID int64, difference float64) *Account {
+func NewAccount(orderNumber sql.NullString, userID int64, difference Money) *Account {
 	return &Account{
 		Difference:  difference,
 		UserID:      userID,
@@ -32,6 +32,16 @@ func NewAccount(orderNumber sql.NullString, userID int64, difference float64) *A
 // Current хранит текущий баланс пользователя.
 // Withdrawn хранит сумму всех снятых средств пользователя.
 type Balance struct {
-	Current   float64 `db:"current" json:"current"`
-	Withdrawn float64 `db:"withdrawn" json:"withdrawn"`
+	Current   Money `db:"current" json:"current" swaggertype:"number"`
+	Withdrawn Money `db:"withdrawn" json:"withdrawn" swaggertype:"number"`
+}
+
+// BalanceMismatch описывает расхождение сохранённого баланса пользователя с балансом, рассчитанным по транзакциям.
+// Current и Withdrawn — значения из таблицы балансов, LedgerCurrent и LedgerWithdrawn — рассчитанные по t_account.
+type BalanceMismatch struct {
+	UserID          int64 `db:"user_id"`
+	Current         Money `db:"current"`
+	Withdrawn       Money `db:"withdrawn"`
+	LedgerCurrent   Money `db:"ledger_current"`
+	LedgerWithdrawn Money `db:"ledger_withdrawn"`
 }
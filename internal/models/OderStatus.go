@@ -11,4 +11,14 @@ const (
 	StatusProcessing = "PROCESSING" // Заказ обрабатывается
 	StatusInvalid    = "INVALID"    // Заказу отказано в начислении
 	StatusProcessed  = "PROCESSED"  // Заказ обработан, и ему начислены баллы
+	StatusParked     = "PARKED"     // Проверки заказа остановлены после ошибок системы начислений
 )
+
+// IsOrderStatus проверяет, что code является известным статусом заказа
+func IsOrderStatus(code string) bool {
+	switch code {
+	case StatusNew, StatusProcessing, StatusInvalid, StatusProcessed, StatusParked:
+		return true
+	}
+	return false
+}
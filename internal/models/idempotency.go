@@ -0,0 +1,36 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IdempotencyKey представляет собой ключ идемпотентности запроса пользователя и сохранённый ответ на первый запрос.
+// ResponseStatus не заполнен, пока первый запрос ещё обрабатывается.
+type IdempotencyKey struct {
+	ID             int64         `db:"id"`
+	UserID         int64         `db:"user_id"`
+	Key            string        `db:"idempotency_key"`
+	RequestHash    string        `db:"request_hash"`
+	ResponseStatus sql.NullInt32 `db:"response_status"`
+	ResponseBody   []byte        `db:"response_body"`
+	CreatedAt      time.Time     `db:"created_at"`
+	ExpiresAt      time.Time     `db:"expires_at"`
+}
+
+// NewIdempotencyKey создает новый ключ идемпотентности, действующий в течение ttl.
+func NewIdempotencyKey(userID int64, key string, requestHash string, ttl time.Duration) *IdempotencyKey {
+	now := time.Now()
+	return &IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+// Completed показывает, сохранён ли уже ответ на первый запрос.
+func (k *IdempotencyKey) Completed() bool {
+	return k.ResponseStatus.Valid
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Money представляет собой денежную сумму с точной десятичной арифметикой.
+// Оборачивает decimal.Decimal, в базе данных хранится как NUMERIC,
+// а в JSON сериализуется числом, как того требует спецификация API.
+type Money struct {
+	decimal.Decimal
+}
+
+// NewMoney создает Money из десятичного значения.
+func NewMoney(value decimal.Decimal) Money {
+	return Money{Decimal: value}
+}
+
+// NewMoneyFromString создает Money из строкового представления числа.
+func NewMoneyFromString(value string) (Money, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Decimal: d}, nil
+}
+
+// Add возвращает сумму двух значений Money.
+func (m Money) Add(other Money) Money {
+	return Money{Decimal: m.Decimal.Add(other.Decimal)}
+}
+
+// Sub возвращает разность двух значений Money.
+func (m Money) Sub(other Money) Money {
+	return Money{Decimal: m.Decimal.Sub(other.Decimal)}
+}
+
+// Neg возвращает значение Money с противоположным знаком.
+func (m Money) Neg() Money {
+	return Money{Decimal: m.Decimal.Neg()}
+}
+
+// MarshalJSON сериализует Money в JSON числом без кавычек и без потери точности.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal.String()), nil
+}
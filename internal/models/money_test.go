@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneySumIsExact(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  string
+		count int
+		want  string
+	}{
+		{
+			name:  "ten_cents",
+			item:  "0.1",
+			count: 10000,
+			want:  "1000",
+		},
+		{
+			name:  "one_cent",
+			item:  "0.01",
+			count: 100000,
+			want:  "1000",
+		},
+		{
+			name:  "accrual_with_cents",
+			item:  "729.98",
+			count: 3000,
+			want:  "2189940",
+		},
+		{
+			name:  "withdraw",
+			item:  "-0.07",
+			count: 1000,
+			want:  "-70",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := NewMoneyFromString(tt.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := NewMoneyFromString(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := Money{}
+			for i := 0; i < tt.count; i++ {
+				sum = sum.Add(item)
+			}
+			if !sum.Equal(want.Decimal) {
+				t.Errorf("sum = %s, want %s", sum.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "integer",
+			input: `751`,
+			want:  `751`,
+		},
+		{
+			name:  "fraction",
+			input: `500.5`,
+			want:  `500.5`,
+		},
+		{
+			name:  "long_fraction",
+			input: `0.30000000000000001`,
+			want:  `0.30000000000000001`,
+		},
+		{
+			name:  "quoted",
+			input: `"42.42"`,
+			want:  `42.42`,
+		},
+		{
+			name:    "not_a_number",
+			input:   `"abc"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var money Money
+			err := json.Unmarshal([]byte(tt.input), &money)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := json.Marshal(money)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "numeric_as_string",
+			src:  "1234.56",
+			want: "1234.56",
+		},
+		{
+			name: "numeric_as_bytes",
+			src:  []byte("-0.01"),
+			want: "-0.01",
+		},
+		{
+			name: "integer",
+			src:  int64(10),
+			want: "10",
+		},
+		{
+			name:    "unsupported",
+			src:     true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var money Money
+			err := money.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if money.String() != tt.want {
+				t.Errorf("Scan() = %s, want %s", money.String(), tt.want)
+			}
+			value, err := money.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != tt.want {
+				t.Errorf("Value() = %v, want %s", value, tt.want)
+			}
+		})
+	}
+}
@@ -7,14 +7,23 @@ import (
 	"time"
 )
 
-// Order представляет собой заказ клиента
+// Order представляет собой заказ клиента.
+// NextCheckAt время следующей проверки в системе начислений, Attempts количество неудачных проверок подряд,
+// ParkedAt время остановки проверок после превышения количества попыток, LastError ошибка последней неудачной проверки.
+// ClaimedBy экземпляр приложения, за которым заказ закреплён на проверку до LeaseExpiresAt.
 type Order struct {
-	Number        string       `db:"number" json:"number"`
-	UserID        int64        `db:"user_id" json:"-"`
-	StatusCode    string       `db:"status_code" json:"status"`
-	CreatedAt     time.Time    `db:"created_at" json:"-"`
-	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
-	LastCheckedAt sql.NullTime `db:"last_checked_at" json:"-"`
+	Number         string         `db:"number" json:"number"`
+	UserID         int64          `db:"user_id" json:"-"`
+	StatusCode     string         `db:"status_code" json:"status"`
+	CreatedAt      time.Time      `db:"created_at" json:"-"`
+	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+	LastCheckedAt  sql.NullTime   `db:"last_checked_at" json:"-"`
+	NextCheckAt    sql.NullTime   `db:"next_check_at" json:"-"`
+	Attempts       int            `db:"attempts" json:"-"`
+	ParkedAt       sql.NullTime   `db:"parked_at" json:"-"`
+	LastError      sql.NullString `db:"last_error" json:"-"`
+	ClaimedBy      sql.NullString `db:"claimed_by" json:"-"`
+	LeaseExpiresAt sql.NullTime   `db:"lease_expires_at" json:"-"`
 }
 
 // NewOrder создает и возвращает новый экземпляр Order с начальным статусом StatusNew.
@@ -29,6 +38,15 @@ func NewOrder(number string, userID int64) *Order {
 	}
 }
 
+// Leased показывает, закреплён ли заказ на проверку за каким-либо экземпляром приложения с действующей на момент now арендой.
+// Проверенные и остановленные заказы закреплёнными не считаются.
+func (o *Order) Leased(now time.Time) bool {
+	if (o.StatusCode != StatusNew && o.StatusCode != StatusProcessing) || o.ParkedAt.Valid {
+		return false
+	}
+	return o.ClaimedBy.Valid && o.LeaseExpiresAt.Valid && o.LeaseExpiresAt.Time.After(now)
+}
+
 // JSONTime представляет собой оболочку для типа time стандартной библиотеки,
 // которая обеспечивает пользовательскую сериализацию и сканирование JSON.
 type JSONTime struct {
@@ -53,18 +71,63 @@ func (jt JSONTime) MarshalJSON() ([]byte, error) {
 
 // OrderWithAccrual объединяет Order с дополнительной информацией о начислении.
 // Включает временную метку последнего обновления и сумму начисления.
+// Accrual равен nil, если начисления по заказу нет, тогда поле отсутствует в ответе.
 type OrderWithAccrual struct {
 	Order
 	UpdatedAt JSONTime `db:"updated_at" json:"updated_at"`
-	Accrual   float64  `db:"accrual" json:"accrual,omitempty"`
+	Accrual   *Money   `db:"accrual" json:"accrual,omitempty" swaggertype:"number"`
 }
 
 // OrderWithdraw представляет собой запись о заказе с тратами.
 // Number содержит номер заказа.
 // Accrual содержит сумму начисления по заказу.
 // ProcessedAt содержит дату обработки и время вывода.
+// ID идентификатор записи списания, используется для постраничной выборки.
 type OrderWithdraw struct {
+	ID          int64    `db:"id" json:"-"`
 	Number      string   `db:"number" json:"order"`
-	Accrual     float64  `db:"accrual" json:"sum,omitempty"`
+	Accrual     Money    `db:"accrual" json:"sum" swaggertype:"number"`
 	ProcessedAt JSONTime `db:"processed_at" json:"processed_at"`
 }
+
+// OrderDetails представляет собой подробную информацию об одном заказе пользователя.
+// LastCheckedAt отсутствует, если заказ ещё не проверялся в системе начислений.
+// Queued показывает, ждёт ли заказ проверки: закреплён за каким-либо экземпляром приложения или находится в очереди экземпляра, ответившего на запрос.
+// Attempts и LastError показывают неудачные проверки заказа в системе начислений подряд.
+// Withdrawal содержит списание в счёт заказа, если оно было.
+type OrderDetails struct {
+	Number        string         `json:"number"`
+	StatusCode    string         `json:"status"`
+	Accrual       *Money         `json:"accrual,omitempty" swaggertype:"number"`
+	UploadedAt    JSONTime       `json:"uploaded_at"`
+	LastCheckedAt *JSONTime      `json:"last_checked_at,omitempty"`
+	Queued        bool           `json:"queued"`
+	Attempts      int            `json:"attempts,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	Withdrawal    *OrderWithdraw `json:"withdrawal,omitempty"`
+}
+
+// NewOrderDetails создает OrderDetails на основе заказа.
+func NewOrderDetails(order *Order) *OrderDetails {
+	details := &OrderDetails{
+		Number:     order.Number,
+		StatusCode: order.StatusCode,
+		UploadedAt: JSONTime{Time: order.CreatedAt},
+		Attempts:   order.Attempts,
+		LastError:  order.LastError.String,
+	}
+	if order.LastCheckedAt.Valid {
+		details.LastCheckedAt = &JSONTime{Time: order.LastCheckedAt.Time}
+	}
+	return details
+}
+
+// ParkedOrder представляет собой заказ, проверки которого остановлены после ошибок системы начислений.
+type ParkedOrder struct {
+	Number        string    `db:"number" json:"number"`
+	UserID        int64     `db:"user_id" json:"user_id"`
+	Attempts      int       `db:"attempts" json:"attempts"`
+	LastError     string    `db:"last_error" json:"last_error"`
+	ParkedAt      JSONTime  `db:"parked_at" json:"parked_at"`
+	LastCheckedAt *JSONTime `db:"last_checked_at" json:"last_checked_at,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestOrderLeased(t *testing.T) {
+	now := time.Now()
+	claimed := sql.NullString{String: "instance", Valid: true}
+	tests := []struct {
+		name  string
+		order Order
+		want  bool
+	}{
+		{
+			name:  "not_claimed",
+			order: Order{StatusCode: StatusNew},
+		},
+		{
+			name:  "claimed",
+			order: Order{StatusCode: StatusProcessing, ClaimedBy: claimed, LeaseExpiresAt: sql.NullTime{Time: now.Add(time.Minute), Valid: true}},
+			want:  true,
+		},
+		{
+			name:  "lease_expired",
+			order: Order{StatusCode: StatusNew, ClaimedBy: claimed, LeaseExpiresAt: sql.NullTime{Time: now.Add(-time.Minute), Valid: true}},
+		},
+		{
+			name:  "processed",
+			order: Order{StatusCode: StatusProcessed, ClaimedBy: claimed, LeaseExpiresAt: sql.NullTime{Time: now.Add(time.Minute), Valid: true}},
+		},
+		{
+			name:  "parked",
+			order: Order{StatusCode: StatusParked, ClaimedBy: claimed, LeaseExpiresAt: sql.NullTime{Time: now.Add(time.Minute), Valid: true}, ParkedAt: sql.NullTime{Time: now, Valid: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.order.Leased(now); got != tt.want {
+				t.Errorf("Leased() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
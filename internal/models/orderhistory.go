@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderStatusHistory представляет собой запись об изменении статуса заказа по ответу системы начислений.
+// AccrualStatus содержит статус, который вернула система начислений, Accrual — начисление, если оно было рассчитано.
+type OrderStatusHistory struct {
+	ID            int64    `db:"id" json:"-"`
+	OrderNumber   string   `db:"order_number" json:"-"`
+	OldStatus     string   `db:"old_status" json:"old_status"`
+	NewStatus     string   `db:"new_status" json:"new_status"`
+	AccrualStatus string   `db:"accrual_status" json:"accrual_status"`
+	Accrual       *Money   `db:"accrual" json:"accrual,omitempty" swaggertype:"number"`
+	CreatedAt     JSONTime `db:"created_at" json:"created_at"`
+}
+
+// NewOrderStatusHistory создает запись об изменении статуса заказа с текущим временем.
+func NewOrderStatusHistory(orderNumber string, oldStatus string, newStatus string, accrualStatus string, accrual *Money) *OrderStatusHistory {
+	return &OrderStatusHistory{
+		OrderNumber:   orderNumber,
+		OldStatus:     oldStatus,
+		NewStatus:     newStatus,
+		AccrualStatus: accrualStatus,
+		Accrual:       accrual,
+		CreatedAt:     JSONTime{Time: time.Now()},
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2idPrefix префикс хэша пароля argon2id в формате PHC
+const argon2idPrefix = "$argon2id$"
+
+// ErrorInvalidPasswordHash Ошибка, что хэш пароля в базе данных не удалось разобрать
+var ErrorInvalidPasswordHash = errors.New("invalid password hash")
+
+// PasswordParams параметры argon2id для хэширования паролей.
+// Параметры записываются в хэш, поэтому их изменение не ломает проверку старых хэшей.
+type PasswordParams struct {
+	Time    uint32 // количество проходов
+	Memory  uint32 // память в КиБ
+	Threads uint8  // количество потоков
+	KeyLen  uint32 // длина хэша в байтах
+	SaltLen uint32 // длина соли в байтах
+}
+
+// DefaultPasswordParams параметры хэширования новых паролей.
+// Хэши с другими параметрами пересчитываются при следующем входе пользователя.
+var DefaultPasswordParams = PasswordParams{
+	Time:    1,
+	Memory:  64 * 1024,
+	Threads: 2,
+	KeyLen:  32,
+	SaltLen: 16,
+}
+
+// HashPassword хэшируем пароль argon2id со случайной солью.
+// Результат в формате $argon2id$v=19$m=65536,t=1,p=2$<соль>$<хэш>, соль и хэш в base64 без дополнения.
+func HashPassword(password string, params PasswordParams) (string, error) {
+	salt := make([]byte, params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// VerifyPassword проверяем пароль по хэшу argon2id или по устаревшему хэшу HMAC-SHA256 с ключом legacyHashKey.
+// needsRehash сообщает, что пароль верный, но хэш нужно пересчитать с текущими параметрами.
+func VerifyPassword(password string, encoded string, legacyHashKey string) (ok bool, needsRehash bool, err error) {
+	if !strings.HasPrefix(encoded, argon2idPrefix) {
+		ok, err = verifyLegacyPassword(password, encoded, legacyHashKey)
+		return ok, ok, err
+	}
+	params, salt, hash, err := decodePasswordHash(encoded)
+	if err != nil {
+		return false, false, err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+	if subtle.ConstantTimeCompare(key, hash) != 1 {
+		return false, false, nil
+	}
+	return true, params != DefaultPasswordParams, nil
+}
+
+// decodePasswordHash разбираем хэш argon2id в формате PHC
+func decodePasswordHash(encoded string) (PasswordParams, []byte, []byte, error) {
+	var params PasswordParams
+	parts := strings.Split(encoded, "$")
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", соль, хэш
+	if len(parts) != 6 {
+		return params, nil, nil, ErrorInvalidPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported version %s", ErrorInvalidPasswordHash, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %w", ErrorInvalidPasswordHash, err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %w", ErrorInvalidPasswordHash, err)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %w", ErrorInvalidPasswordHash, err)
+	}
+	params.SaltLen = uint32(len(salt))
+	params.KeyLen = uint32(len(hash))
+	return params, salt, hash, nil
+}
+
+// legacyPasswordHash устаревший хэш пароля HMAC-SHA256 с общим ключом приложения
+func legacyPasswordHash(password string, hashKey string) string {
+	harsher := hmac.New(sha256.New, []byte(hashKey))
+	harsher.Write([]byte(password))
+	return hex.EncodeToString(harsher.Sum(nil))
+}
+
+// verifyLegacyPassword проверяем пароль по устаревшему хэшу HMAC-SHA256
+func verifyLegacyPassword(password string, encoded string, hashKey string) (bool, error) {
+	if hashKey == "" {
+		return false, errors.New("hash key is empty")
+	}
+	decodedHash, err := hex.DecodeString(encoded)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrorInvalidPasswordHash, err)
+	}
+	decodedPassword, err := hex.DecodeString(legacyPasswordHash(password, hashKey))
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(decodedHash, decodedPassword), nil
+}
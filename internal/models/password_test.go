@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashPasswordUsesUniqueSalt(t *testing.T) {
+	first, err := HashPassword("secret", DefaultPasswordParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := HashPassword("secret", DefaultPasswordParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(first, "$argon2id$v=19$m=65536,t=1,p=2$") {
+		t.Errorf("expected argon2id hash with parameters, got %s", first)
+	}
+	if first == second {
+		t.Error("expected different hashes for the same password")
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	current, err := HashPassword("secret", DefaultPasswordParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak := DefaultPasswordParams
+	weak.Memory = 8 * 1024
+	outdated, err := HashPassword("secret", weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := legacyPasswordHash("secret", "key")
+
+	tests := []struct {
+		name       string
+		password   string
+		encoded    string
+		wantOk     bool
+		wantRehash bool
+	}{
+		{
+			name:     "argon2id",
+			password: "secret",
+			encoded:  current,
+			wantOk:   true,
+		},
+		{
+			name:     "argon2id_wrong_password",
+			password: "wrong",
+			encoded:  current,
+		},
+		{
+			name:       "argon2id_outdated_params",
+			password:   "secret",
+			encoded:    outdated,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "legacy_hmac",
+			password:   "secret",
+			encoded:    legacy,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "legacy_hmac_wrong_password",
+			password: "wrong",
+			encoded:  legacy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := VerifyPassword(tt.password, tt.encoded, "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOk || rehash != tt.wantRehash {
+				t.Errorf("expected ok=%v rehash=%v, got ok=%v rehash=%v", tt.wantOk, tt.wantRehash, ok, rehash)
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	_, _, err := VerifyPassword("secret", "$argon2id$v=19$m=65536,t=1,p=2$broken", "key")
+	if !errors.Is(err, ErrorInvalidPasswordHash) {
+		t.Errorf("expected ErrorInvalidPasswordHash, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"time"
+)
+
+// passwordResetTokenBytes длина случайного токена сброса пароля в байтах
+const passwordResetTokenBytes = 32
+
+// PasswordResetToken представляет собой одноразовый токен сброса пароля, в базе хранится только его хэш.
+type PasswordResetToken struct {
+	TokenHash string       `db:"token_hash"`
+	UserID    int64        `db:"user_id"`
+	CreatedAt time.Time    `db:"created_at"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+}
+
+// NewPasswordResetToken создаёт новый токен сброса пароля пользователя, действующий ttl.
+// Возвращает запись для базы данных и сам токен, который отправляется пользователю.
+func NewPasswordResetToken(userID int64, ttl time.Duration) (*PasswordResetToken, string, error) {
+	raw := make([]byte, passwordResetTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now()
+	return &PasswordResetToken{
+		TokenHash: HashPasswordResetToken(value),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, value, nil
+}
+
+// Usable токен ещё не использован и не истёк
+func (t *PasswordResetToken) Usable(now time.Time) bool {
+	return !t.UsedAt.Valid && now.Before(t.ExpiresAt)
+}
+
+// HashPasswordResetToken хэш токена сброса пароля для хранения и поиска в базе данных
+func HashPasswordResetToken(value string) string {
+	return HashRefreshToken(value)
+}
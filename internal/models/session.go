@@ -0,0 +1,79 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// sessionIDBytes длина случайного идентификатора сессии в байтах
+const sessionIDBytes = 16
+
+// refreshTokenBytes длина случайного токена обновления в байтах
+const refreshTokenBytes = 32
+
+// Session представляет собой сессию пользователя.
+// ID передаётся в jti токенов доступа, после RevokedAt токены сессии не принимаются.
+type Session struct {
+	ID          string       `db:"id"`
+	UserID      int64        `db:"user_id"`
+	CreatedAt   time.Time    `db:"created_at"`
+	RefreshedAt time.Time    `db:"refreshed_at"`
+	ExpiresAt   time.Time    `db:"expires_at"`
+	RevokedAt   sql.NullTime `db:"revoked_at"`
+}
+
+// NewSession создаёт новую сессию пользователя, которую можно продлевать в течение ttl
+func NewSession(userID int64, ttl time.Duration) (*Session, error) {
+	id := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Session{
+		ID:          hex.EncodeToString(id),
+		UserID:      userID,
+		CreatedAt:   now,
+		RefreshedAt: now,
+		ExpiresAt:   now.Add(ttl),
+	}, nil
+}
+
+// Active сессия не отозвана и не истекла
+func (s *Session) Active(now time.Time) bool {
+	return !s.RevokedAt.Valid && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken представляет собой одноразовый токен обновления сессии, в базе хранится только его хэш.
+type RefreshToken struct {
+	TokenHash string       `db:"token_hash"`
+	SessionID string       `db:"session_id"`
+	CreatedAt time.Time    `db:"created_at"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+}
+
+// NewRefreshToken создаёт новый токен обновления сессии, действующий до expiresAt.
+// Возвращает запись для базы данных и сам токен, который отдаётся пользователю.
+func NewRefreshToken(sessionID string, expiresAt time.Time) (*RefreshToken, string, error) {
+	raw := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(raw)
+	return &RefreshToken{
+		TokenHash: HashRefreshToken(value),
+		SessionID: sessionID,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}, value, nil
+}
+
+// HashRefreshToken хэш токена обновления для хранения и поиска в базе данных
+func HashRefreshToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,9 @@
 package models
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"database/sql"
 	"errors"
+	"time"
 )
 
 // User представляет пользователя в системе.
@@ -12,38 +11,40 @@ import (
 // Login — имя пользователя для входа.
 // Password — текстовый пароль пользователя. Это поле игнорируется базой данных.
 // PasswordHash — хешированная версия пароля пользователя.
+// FailedLogins и LockedUntil — неудачные входы подряд и время окончания блокировки входа.
 type User struct {
-	ID           int64  `db:"id"`
-	Login        string `db:"login"`
-	Password     string `db:"-"`
-	PasswordHash string `db:"password_hash"`
+	ID           int64        `db:"id"`
+	Login        string       `db:"login"`
+	Password     string       `db:"-"`
+	PasswordHash string       `db:"password_hash"`
+	FailedLogins int          `db:"failed_logins"`
+	LockedUntil  sql.NullTime `db:"locked_until"`
 }
 
-// GeneratePasswordHash создаём хэш пароля пользователя
-func (u *User) GeneratePasswordHash(hashKey string) error {
+// LockedFor сколько ещё заблокирован вход пользователя, 0 если вход не заблокирован
+func (u *User) LockedFor(now time.Time) time.Duration {
+	if !u.LockedUntil.Valid || !now.Before(u.LockedUntil.Time) {
+		return 0
+	}
+	return u.LockedUntil.Time.Sub(now)
+}
+
+// GeneratePasswordHash создаём хэш пароля пользователя argon2id с собственной солью
+func (u *User) GeneratePasswordHash() error {
 	if u.Password == "" {
 		return errors.New("password key is empty")
 	}
-	if hashKey == "" {
-		return errors.New("hash key is empty")
+	passwordHash, err := HashPassword(u.Password, DefaultPasswordParams)
+	if err != nil {
+		return err
 	}
-	harsher := hmac.New(sha256.New, []byte(hashKey))
-	harsher.Write([]byte(u.Password))
-	u.PasswordHash = hex.EncodeToString(harsher.Sum(nil))
+	u.PasswordHash = passwordHash
 
 	return nil
 }
 
-// CheckPasswordHash проверяем совпадение паролей
-func (u *User) CheckPasswordHash(passwordHash string) (bool, error) {
-	decodedHash, err := hex.DecodeString(passwordHash)
-	if err != nil {
-		return false, err
-	}
-	decodedUserHash, err := hex.DecodeString(u.PasswordHash)
-	if err != nil {
-		return false, err
-	}
-
-	return hmac.Equal(decodedHash, decodedUserHash), nil
+// CheckPassword проверяем пароль по хэшу пользователя.
+// Устаревшие хэши HMAC-SHA256 проверяются ключом legacyHashKey, rehash сообщает, что хэш пора пересчитать.
+func (u *User) CheckPassword(password string, legacyHashKey string) (ok bool, rehash bool, err error) {
+	return VerifyPassword(password, u.PasswordHash, legacyHashKey)
 }
@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gofemart/internal/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// Способы доставки уведомлений
+const (
+	KindLog  = "log"  // запись в журнал приложения для локальной разработки
+	KindFile = "file" // дописывание строк JSON в файл для локальной разработки и тестов
+)
+
+// ErrorUnknownNotifier Ошибка, что в конфигурации указан неизвестный способ доставки уведомлений
+var ErrorUnknownNotifier = errors.New("unknown notifier")
+
+// PasswordReset уведомление со ссылкой на сброс пароля
+type PasswordReset struct {
+	UserID    int64     `json:"user_id"`
+	Login     string    `json:"login"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Notifier доставка уведомлений пользователям
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, reset PasswordReset) error
+}
+
+// New создаём уведомления выбранного способа доставки, path используется для доставки в файл
+func New(kind string, path string) (Notifier, error) {
+	switch kind {
+	case KindLog, "":
+		return &LogNotifier{}, nil
+	case KindFile:
+		return NewFileNotifier(path)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownNotifier, kind)
+	}
+}
+
+// LogNotifier пишет уведомления в журнал приложения.
+// Токены попадают в журнал, поэтому способ подходит только для локальной разработки.
+type LogNotifier struct{}
+
+// SendPasswordReset пишем токен сброса пароля в журнал
+func (n *LogNotifier) SendPasswordReset(_ context.Context, reset PasswordReset) error {
+	logger.Log.Infow("Password reset requested", "user", reset.UserID, "login", reset.Login, "token", reset.Token, "expiresAt", reset.ExpiresAt)
+	return nil
+}
+
+// FileNotifier дописывает уведомления в файл, по одному объекту JSON в строке
+type FileNotifier struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFileNotifier создаём уведомления в файл path, файл создаётся при первом уведомлении
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	if path == "" {
+		return nil, errors.New("notifier file path is empty")
+	}
+	return &FileNotifier{path: path}, nil
+}
+
+// SendPasswordReset дописываем уведомление о сбросе пароля в файл
+func (n *FileNotifier) SendPasswordReset(_ context.Context, reset PasswordReset) error {
+	line, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if n, err := New(KindLog, ""); err != nil || n == nil {
+		t.Errorf("expected log notifier, got %v, %v", n, err)
+	}
+	if _, err := New(KindFile, ""); err == nil {
+		t.Error("expected error for file notifier without path")
+	}
+	if _, err := New("smtp", ""); !errors.Is(err, ErrorUnknownNotifier) {
+		t.Errorf("expected ErrorUnknownNotifier, got %v", err)
+	}
+}
+
+func TestFileNotifierAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	n, err := NewFileNotifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	for _, login := range []string{"first", "second"} {
+		if err = n.SendPasswordReset(context.Background(), PasswordReset{UserID: 1, Login: login, Token: "token-" + login, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var got []PasswordReset
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var reset PasswordReset
+		if err = json.Unmarshal(scanner.Bytes(), &reset); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, reset)
+	}
+	if len(got) != 2 || got[0].Token != "token-first" || got[1].Login != "second" || !got[1].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected two notifications in order, got %+v", got)
+	}
+}
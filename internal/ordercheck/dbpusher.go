@@ -27,7 +27,7 @@ func (p *Pool) pushFromDB(dur time.Duration) {
 	}
 }
 
-// pushDBProcessingOrdersToQueue получаем из базы данных необработанные заказы и пушим их в очередь
+// pushDBProcessingOrdersToQueue закрепляем в базе данных необработанные заказы за экземпляром приложения и пушим их в очередь
 func (p *Pool) pushDBProcessingOrdersToQueue() error {
 	limit := cap(p.inChanel) - len(p.inChanel)
 	logger.Log.Infow("Push db processing orders to queue", "limit", limit)
@@ -36,16 +36,31 @@ func (p *Pool) pushDBProcessingOrdersToQueue() error {
 	}
 	keys := p.getCurrentOrdersKeys()
 	rep := p.orderRepo
-	olderThen := time.Now().Add(-p.olderThenDuration)
-	orders, err := rep.GetOrdersExcludeOrdersWhereStatusIn(limit, keys, olderThen, models.StatusProcessing, models.StatusNew)
+	now := time.Now()
+	olderThen := now.Add(-p.olderThenDuration)
+	orders, err := rep.ClaimOrdersWhereStatusIn(limit, keys, p.instanceID, now, olderThen, now.Add(p.leaseDuration), models.StatusProcessing, models.StatusNew)
 	if err != nil {
 		return err
 	}
-	for _, order := range orders {
-		if _, err := p.Push(&order); err != nil {
+	for i := range orders {
+		pushed, err := p.Push(p.ctx, &orders[i])
+		if err != nil || !pushed {
+			// Очередь могла заполниться или пул закрыться, не попавшие в очередь заказы освобождаем для других экземпляров
+			p.releaseOrders(orders[i:])
 			return err
 		}
 	}
 
 	return nil
 }
+
+// releaseOrders снимаем закрепление заказов, которые не попали в очередь
+func (p *Pool) releaseOrders(orders []models.Order) {
+	numbers := make([]string, 0, len(orders))
+	for _, order := range orders {
+		numbers = append(numbers, order.Number)
+	}
+	if err := p.orderRepo.ReleaseOrders(numbers, p.instanceID); err != nil {
+		logger.Log.Error(err)
+	}
+}
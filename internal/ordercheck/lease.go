@@ -0,0 +1,66 @@
+package ordercheck
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"os"
+	"time"
+)
+
+// newInstanceID генерируем идентификатор экземпляра приложения из имени хоста и случайного суффикса,
+// чтобы перезапущенный экземпляр не продлевал аренду заказов предыдущего
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "gofemart"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		logger.Log.Error(err)
+	}
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// claimOrder закрепляем заказ за экземпляром приложения перед проверкой и возвращаем его актуальное состояние из базы данных.
+// Заказ в очереди мог устареть, пока его проверял другой экземпляр, поэтому проверяется только возвращённый заказ.
+// Возвращает false, если заказ уже проверен, остановлен или его проверяет другой экземпляр.
+func (p *Pool) claimOrder(number string) (*models.Order, bool) {
+	now := time.Now()
+	order, claimed, err := p.orderRepo.ClaimOrder(number, p.instanceID, now, now.Add(p.leaseDuration))
+	if err != nil {
+		logger.Log.Error(err)
+		return nil, false
+	}
+	if !claimed {
+		logger.Log.Infow("Order already checked or claimed by another instance", "number", number)
+	}
+	return order, claimed
+}
+
+// releaseOrder снимаем закрепление заказа после проверки
+func (p *Pool) releaseOrder(number string) {
+	if err := p.orderRepo.ReleaseOrder(number, p.instanceID); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// renewLeases периодически продлеваем аренду заказов, которые находятся в очереди или в работе
+func (p *Pool) renewLeases(dur time.Duration) {
+	logger.Log.Infow("Renew leases", "duration", dur)
+	defer p.wg.Done()
+	ticker := time.NewTicker(dur)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			keys := p.getCurrentOrdersKeys()
+			if err := p.orderRepo.RenewLeases(keys, p.instanceID, time.Now().Add(p.leaseDuration)); err != nil {
+				logger.Log.Error(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package ordercheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/models"
+	"gofemart/internal/ordercheck/mock"
+	"testing"
+	"time"
+)
+
+func TestClaimOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	testCases := []struct {
+		name    string
+		claimed bool
+		err     error
+		want    bool
+	}{
+		{
+			name:    "claimed",
+			claimed: true,
+			want:    true,
+		},
+		{
+			name:    "claimed_by_another_instance",
+			claimed: false,
+			want:    false,
+		},
+		{
+			name: "db_error",
+			err:  errors.New("db error"),
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mock.NewMockoRepo(ctrl)
+			repo.EXPECT().
+				ClaimOrder("1", "instance", gomock.Any(), gomock.Any()).
+				DoAndReturn(func(number string, instance string, now time.Time, leaseExpiresAt time.Time) (*models.Order, bool, error) {
+					if leaseExpiresAt.Sub(now) != time.Minute {
+						t.Errorf("expected lease for %v, got %v", time.Minute, leaseExpiresAt.Sub(now))
+					}
+					if !tc.claimed {
+						return nil, false, tc.err
+					}
+					return claimNew(number, instance, now, leaseExpiresAt)
+				})
+			p := &Pool{
+				orderRepo:     repo,
+				instanceID:    "instance",
+				leaseDuration: time.Minute,
+			}
+			order, got := p.claimOrder("1")
+			if got != tc.want {
+				t.Errorf("claimOrder() = %v, want %v", got, tc.want)
+			}
+			if got && order.ClaimedBy.String != "instance" {
+				t.Errorf("expected claimed order from repository, got %+v", order)
+			}
+		})
+	}
+}
+
+// claimNew ответ ClaimOrder, который закрепляет заказ со статусом StatusNew за экземпляром instance
+func claimNew(number string, instance string, _ time.Time, leaseExpiresAt time.Time) (*models.Order, bool, error) {
+	return &models.Order{
+		Number:         number,
+		StatusCode:     models.StatusNew,
+		ClaimedBy:      sql.NullString{String: instance, Valid: true},
+		LeaseExpiresAt: sql.NullTime{Time: leaseExpiresAt, Valid: true},
+	}, true, nil
+}
+
+func TestPushDBProcessingOrdersToQueueClaimsOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().
+		ClaimOrdersWhereStatusIn(2, []string{"1"}, "instance", gomock.Any(), gomock.Any(), gomock.Any(), models.StatusProcessing, models.StatusNew).
+		Return([]models.Order{{Number: "2"}, {Number: "3"}}, nil)
+	p := &Pool{
+		orderMap:      map[string]*WorkedOrder{"1": {model: &models.Order{Number: "1"}}},
+		inChanel:      make(chan string, 3),
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+	}
+	p.inChanel <- "1"
+
+	if err := p.pushDBProcessingOrdersToQueue(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, number := range []string{"2", "3"} {
+		if !p.InQueue(number) {
+			t.Errorf("expected order %s in queue", number)
+		}
+	}
+}
+
+func TestPushDBProcessingOrdersToQueueReleasesUnpushedOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().
+		ClaimOrdersWhereStatusIn(2, gomock.Any(), "instance", gomock.Any(), gomock.Any(), gomock.Any(), models.StatusProcessing, models.StatusNew).
+		Return([]models.Order{{Number: "1"}, {Number: "2"}}, nil)
+	// Очередь заполнилась после подсчёта свободного места, второй заказ должен освободиться
+	repo.EXPECT().ReleaseOrders([]string{"2"}, "instance").Return(nil)
+	p := &Pool{
+		orderMap:      map[string]*WorkedOrder{},
+		inChanel:      make(chan string, 2),
+		ctx:           context.Background(),
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+	}
+	p.orderRepo = &fillingRepo{oRepo: repo, fill: func() { p.inChanel <- "other" }}
+
+	if err := p.pushDBProcessingOrdersToQueue(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !p.InQueue("1") || p.InQueue("2") {
+		t.Error("expected only the first order in queue")
+	}
+}
+
+// fillingRepo репозиторий заказов, который заполняет очередь сразу после выбора заказов
+type fillingRepo struct {
+	oRepo
+	fill func()
+}
+
+// ClaimOrdersWhereStatusIn выбираем заказы и занимаем место в очереди
+func (r *fillingRepo) ClaimOrdersWhereStatusIn(limit int, excludedNumbers []string, instance string, now time.Time, olderThen time.Time, leaseExpiresAt time.Time, statuses ...string) ([]models.Order, error) {
+	orders, err := r.oRepo.ClaimOrdersWhereStatusIn(limit, excludedNumbers, instance, now, olderThen, leaseExpiresAt, statuses...)
+	r.fill()
+	return orders, err
+}
+
+func TestRenewLeases(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockoRepo(ctrl)
+	renewed := make(chan []string, 1)
+	repo.EXPECT().
+		RenewLeases(gomock.Any(), "instance", gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(numbers []string, instance string, leaseExpiresAt time.Time) error {
+			select {
+			case renewed <- numbers:
+			default:
+			}
+			return nil
+		})
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		orderMap:      map[string]*WorkedOrder{"1": {model: &models.Order{Number: "1"}}},
+		ctx:           ctx,
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+	}
+	p.wg.Add(1)
+	go p.renewLeases(time.Millisecond)
+
+	numbers := <-renewed
+	cancel()
+	p.wg.Wait()
+	if len(numbers) != 1 || numbers[0] != "1" {
+		t.Errorf("expected lease renewal for order 1, got %v", numbers)
+	}
+}
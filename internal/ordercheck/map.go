@@ -22,3 +22,34 @@ func (p *Pool) getCurrentOrdersKeys() []string {
 
 	return keys
 }
+
+// InQueue проверяем находится ли заказ в очереди пула на обработку
+func (p *Pool) InQueue(number string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	_, ok := p.orderMap[number]
+	return ok
+}
+
+// QueueLen количество заказов в очереди пула
+func (p *Pool) QueueLen() int {
+	return len(p.inChanel)
+}
+
+// QueueCap ёмкость очереди пула
+func (p *Pool) QueueCap() int {
+	return cap(p.inChanel)
+}
+
+// InFlight количество заказов, которые проверяются прямо сейчас
+func (p *Pool) InFlight() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	count := 0
+	for _, order := range p.orderMap {
+		if order.inWork {
+			count++
+		}
+	}
+	return count
+}
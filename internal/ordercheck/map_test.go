@@ -0,0 +1,73 @@
+package ordercheck
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestInQueue(t *testing.T) {
+	testCases := []struct {
+		name      string
+		order     string
+		setup     func(*Pool)
+		wantQueue bool
+	}{
+		{
+			name:  "order_waiting",
+			order: "1",
+			setup: func(p *Pool) {
+				p.orderMap["1"] = &WorkedOrder{inWork: false}
+			},
+			wantQueue: true,
+		},
+		{
+			name:  "order_in_work",
+			order: "2",
+			setup: func(p *Pool) {
+				p.orderMap["2"] = &WorkedOrder{inWork: true}
+			},
+			wantQueue: true,
+		},
+		{
+			name:      "order_not_exist",
+			order:     "3",
+			setup:     func(p *Pool) {},
+			wantQueue: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Pool{
+				orderMap: make(map[string]*WorkedOrder),
+				mutex:    sync.RWMutex{},
+			}
+			tc.setup(p)
+			got := p.InQueue(tc.order)
+			if got != tc.wantQueue {
+				t.Errorf("InQueue() = %v, want %v", got, tc.wantQueue)
+			}
+		})
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	p := &Pool{
+		orderMap: map[string]*WorkedOrder{
+			"1": {inWork: true},
+			"2": {},
+			"3": {inWork: true},
+		},
+		inChanel: make(chan string, 10),
+	}
+	p.inChanel <- "2"
+	if got := p.QueueLen(); got != 1 {
+		t.Errorf("QueueLen() = %v, want 1", got)
+	}
+	if got := p.QueueCap(); got != 10 {
+		t.Errorf("QueueCap() = %v, want 10", got)
+	}
+	if got := p.InFlight(); got != 2 {
+		t.Errorf("InFlight() = %v, want 2", got)
+	}
+}
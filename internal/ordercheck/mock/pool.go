@@ -5,6 +5,8 @@
 package mock
 
 import (
+	context "context"
+	accrual "gofemart/internal/accrual"
 	models "gofemart/internal/models"
 	payloads "gofemart/internal/payloads"
 	reflect "reflect"
@@ -36,24 +38,96 @@ func (m *MockoRepo) EXPECT() *MockoRepoMockRecorder {
 	return m.recorder
 }
 
-// GetOrdersExcludeOrdersWhereStatusIn mocks base method.
-func (m *MockoRepo) GetOrdersExcludeOrdersWhereStatusIn(limit int, excludedNumbers []string, olderThen time.Time, statuses ...string) ([]models.Order, error) {
+// ClaimOrder mocks base method.
+func (m *MockoRepo) ClaimOrder(number, instance string, now, leaseExpiresAt time.Time) (*models.Order, bool, error) {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{limit, excludedNumbers, olderThen}
+	ret := m.ctrl.Call(m, "ClaimOrder", number, instance, now, leaseExpiresAt)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimOrder indicates an expected call of ClaimOrder.
+func (mr *MockoRepoMockRecorder) ClaimOrder(number, instance, now, leaseExpiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOrder", reflect.TypeOf((*MockoRepo)(nil).ClaimOrder), number, instance, now, leaseExpiresAt)
+}
+
+// ClaimOrdersWhereStatusIn mocks base method.
+func (m *MockoRepo) ClaimOrdersWhereStatusIn(limit int, excludedNumbers []string, instance string, now, olderThen, leaseExpiresAt time.Time, statuses ...string) ([]models.Order, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{limit, excludedNumbers, instance, now, olderThen, leaseExpiresAt}
 	for _, a := range statuses {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "GetOrdersExcludeOrdersWhereStatusIn", varargs...)
+	ret := m.ctrl.Call(m, "ClaimOrdersWhereStatusIn", varargs...)
 	ret0, _ := ret[0].([]models.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOrdersExcludeOrdersWhereStatusIn indicates an expected call of GetOrdersExcludeOrdersWhereStatusIn.
-func (mr *MockoRepoMockRecorder) GetOrdersExcludeOrdersWhereStatusIn(limit, excludedNumbers, olderThen interface{}, statuses ...interface{}) *gomock.Call {
+// ClaimOrdersWhereStatusIn indicates an expected call of ClaimOrdersWhereStatusIn.
+func (mr *MockoRepoMockRecorder) ClaimOrdersWhereStatusIn(limit, excludedNumbers, instance, now, olderThen, leaseExpiresAt interface{}, statuses ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{limit, excludedNumbers, instance, now, olderThen, leaseExpiresAt}, statuses...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOrdersWhereStatusIn", reflect.TypeOf((*MockoRepo)(nil).ClaimOrdersWhereStatusIn), varargs...)
+}
+
+// CreateStatusHistory mocks base method.
+func (m *MockoRepo) CreateStatusHistory(history *models.OrderStatusHistory) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStatusHistory", history)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateStatusHistory indicates an expected call of CreateStatusHistory.
+func (mr *MockoRepoMockRecorder) CreateStatusHistory(history interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatusHistory", reflect.TypeOf((*MockoRepo)(nil).CreateStatusHistory), history)
+}
+
+// ReleaseOrder mocks base method.
+func (m *MockoRepo) ReleaseOrder(number, instance string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseOrder", number, instance)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseOrder indicates an expected call of ReleaseOrder.
+func (mr *MockoRepoMockRecorder) ReleaseOrder(number, instance interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseOrder", reflect.TypeOf((*MockoRepo)(nil).ReleaseOrder), number, instance)
+}
+
+// ReleaseOrders mocks base method.
+func (m *MockoRepo) ReleaseOrders(numbers []string, instance string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseOrders", numbers, instance)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseOrders indicates an expected call of ReleaseOrders.
+func (mr *MockoRepoMockRecorder) ReleaseOrders(numbers, instance interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseOrders", reflect.TypeOf((*MockoRepo)(nil).ReleaseOrders), numbers, instance)
+}
+
+// RenewLeases mocks base method.
+func (m *MockoRepo) RenewLeases(numbers []string, instance string, leaseExpiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewLeases", numbers, instance, leaseExpiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewLeases indicates an expected call of RenewLeases.
+func (mr *MockoRepoMockRecorder) RenewLeases(numbers, instance, leaseExpiresAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{limit, excludedNumbers, olderThen}, statuses...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersExcludeOrdersWhereStatusIn", reflect.TypeOf((*MockoRepo)(nil).GetOrdersExcludeOrdersWhereStatusIn), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewLeases", reflect.TypeOf((*MockoRepo)(nil).RenewLeases), numbers, instance, leaseExpiresAt)
 }
 
 // UpdateOrder mocks base method.
@@ -131,18 +205,46 @@ func (m *MockAccrual) EXPECT() *MockAccrualMockRecorder {
 }
 
 // Accrual mocks base method.
-func (m *MockAccrual) Accrual(order *models.Order) (*payloads.Accrual, error) {
+func (m *MockAccrual) Accrual(ctx context.Context, order *models.Order) (*payloads.Accrual, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Accrual", order)
+	ret := m.ctrl.Call(m, "Accrual", ctx, order)
 	ret0, _ := ret[0].(*payloads.Accrual)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Accrual indicates an expected call of Accrual.
-func (mr *MockAccrualMockRecorder) Accrual(order interface{}) *gomock.Call {
+func (mr *MockAccrualMockRecorder) Accrual(ctx, order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Accrual", reflect.TypeOf((*MockAccrual)(nil).Accrual), ctx, order)
+}
+
+// BreakerOpenFor mocks base method.
+func (m *MockAccrual) BreakerOpenFor() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BreakerOpenFor")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// BreakerOpenFor indicates an expected call of BreakerOpenFor.
+func (mr *MockAccrualMockRecorder) BreakerOpenFor() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Accrual", reflect.TypeOf((*MockAccrual)(nil).Accrual), order)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BreakerOpenFor", reflect.TypeOf((*MockAccrual)(nil).BreakerOpenFor))
+}
+
+// BreakerStatus mocks base method.
+func (m *MockAccrual) BreakerStatus() accrual.BreakerStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BreakerStatus")
+	ret0, _ := ret[0].(accrual.BreakerStatus)
+	return ret0
+}
+
+// BreakerStatus indicates an expected call of BreakerStatus.
+func (mr *MockAccrualMockRecorder) BreakerStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BreakerStatus", reflect.TypeOf((*MockAccrual)(nil).BreakerStatus))
 }
 
 // Pause mocks base method.
@@ -156,3 +258,41 @@ func (mr *MockAccrualMockRecorder) Pause(duration interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockAccrual)(nil).Pause), duration)
 }
+
+// MockBatchAccrual is a mock of BatchAccrual interface.
+type MockBatchAccrual struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchAccrualMockRecorder
+}
+
+// MockBatchAccrualMockRecorder is the mock recorder for MockBatchAccrual.
+type MockBatchAccrualMockRecorder struct {
+	mock *MockBatchAccrual
+}
+
+// NewMockBatchAccrual creates a new mock instance.
+func NewMockBatchAccrual(ctrl *gomock.Controller) *MockBatchAccrual {
+	mock := &MockBatchAccrual{ctrl: ctrl}
+	mock.recorder = &MockBatchAccrualMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchAccrual) EXPECT() *MockBatchAccrualMockRecorder {
+	return m.recorder
+}
+
+// AccrualBatch mocks base method.
+func (m *MockBatchAccrual) AccrualBatch(ctx context.Context, orders []*models.Order) (map[string]*payloads.Accrual, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccrualBatch", ctx, orders)
+	ret0, _ := ret[0].(map[string]*payloads.Accrual)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AccrualBatch indicates an expected call of AccrualBatch.
+func (mr *MockBatchAccrualMockRecorder) AccrualBatch(ctx, orders interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccrualBatch", reflect.TypeOf((*MockBatchAccrual)(nil).AccrualBatch), ctx, orders)
+}
@@ -3,6 +3,7 @@ package ordercheck
 import (
 	"context"
 	"errors"
+	"go.opentelemetry.io/otel/trace"
 	"gofemart/internal/accrual"
 	"gofemart/internal/logger"
 	"gofemart/internal/models"
@@ -16,10 +17,18 @@ import (
 // ErrorPoolClosed Ошибка, что пул обработки уже закрыт
 var ErrorPoolClosed = errors.New("pool closed")
 
+// defaultLeaseDuration время аренды заказа, если в конфигурации оно не задано
+const defaultLeaseDuration = 30 * time.Second
+
 // oRepo определяет методы взаимодействия с заказами в репозитории.
 type oRepo interface {
-	GetOrdersExcludeOrdersWhereStatusIn(limit int, excludedNumbers []string, olderThen time.Time, statuses ...string) ([]models.Order, error)
+	ClaimOrdersWhereStatusIn(limit int, excludedNumbers []string, instance string, now time.Time, olderThen time.Time, leaseExpiresAt time.Time, statuses ...string) ([]models.Order, error)
+	ClaimOrder(number string, instance string, now time.Time, leaseExpiresAt time.Time) (*models.Order, bool, error)
+	RenewLeases(numbers []string, instance string, leaseExpiresAt time.Time) error
+	ReleaseOrder(number string, instance string) error
+	ReleaseOrders(numbers []string, instance string) error
 	UpdateOrder(order *models.Order) error
+	CreateStatusHistory(history *models.OrderStatusHistory) error
 }
 
 // aRepo определяет интерфейс для взаимодействия с начислениями в репозитории.
@@ -29,14 +38,22 @@ type aRepo interface {
 
 // Accrual предоставляет методы для проверки начислений и управления паузами.
 type Accrual interface {
-	Accrual(order *models.Order) (*payloads.Accrual, error)
+	Accrual(ctx context.Context, order *models.Order) (*payloads.Accrual, error)
 	Pause(duration time.Duration)
+	BreakerStatus() accrual.BreakerStatus
+	BreakerOpenFor() time.Duration
+}
+
+// BatchAccrual поставщик начислений, который умеет проверять несколько заказов одним запросом.
+type BatchAccrual interface {
+	AccrualBatch(ctx context.Context, orders []*models.Order) (map[string]*payloads.Accrual, error)
 }
 
 // WorkedOrder представляет собой обрабатываемый заказ.
 type WorkedOrder struct {
-	model  *models.Order
-	inWork bool
+	model       *models.Order
+	inWork      bool
+	spanContext trace.SpanContext // спан постановки заказа в очередь, от него продолжается трассировка проверки
 }
 
 // Pool управляет обработкой заказов, включая организацию очередей,
@@ -50,9 +67,14 @@ type Pool struct {
 	wg                sync.WaitGroup
 	cancel            context.CancelFunc
 	olderThenDuration time.Duration
+	maxAttempts       int
+	batchSize         int
+	instanceID        string
+	leaseDuration     time.Duration
+	dbExecutor        repositories.SQLExecutor
 	orderRepo         oRepo
 	accountRepo       aRepo
-	accrualProxy      Accrual
+	providers         *Registry
 }
 
 // CheckPool глобальный инстенс пула обработки заказов.
@@ -66,15 +88,35 @@ type PoolConfig struct {
 	Pause           time.Duration // пауза в запросах к сервису начислений, если он ответил ответом, что слишком много запросов
 	AccrualURL      string        // адрес системы расчёта начислений
 	DBExecutor      repositories.SQLExecutor
-	DBCheckDuration time.Duration // период в который проверяется база данных на необработанные заказы
+	DBCheckDuration time.Duration         // период в который проверяется база данных на необработанные заказы
+	MaxAttempts     int                   // количество неудачных проверок подряд, после которого проверки заказа останавливаются, если не положительное, то без ограничения
+	InstanceID      string                // идентификатор экземпляра приложения, за которым закрепляются заказы в базе данных, если пустой, то генерируется
+	LeaseDuration   time.Duration         // время, на которое заказ закрепляется за экземпляром приложения, аренда продлевается, пока заказ в очереди
+	Breaker         accrual.BreakerConfig // конфигурация автоматического выключателя запросов в систему начислений
+	RateLimit       float64               // количество запросов в секунду к системе начислений, если не положительное, то без ограничения
+	RateBurst       int                   // допустимое количество запросов к системе начислений сверх RateLimit за раз
+	BatchSize       int                   // количество заказов, которые обработчик берёт из очереди и проверяет одним запросом, если не больше 1, то заказы проверяются по одному
+	Providers       []ProviderConfig      // поставщики начислений, выбираемые по заказу, если среди них нет поставщика по умолчанию, то он собирается из AccrualURL, Pause, Breaker и RateLimit
 }
 
-// NewPool инициализирует и возвращает новый экземпляр Pool с указанным контекстом, размером очереди, количеством рабочих процессов, длительностью паузы и URL-адресом накопления.
-func NewPool(cnf PoolConfig) *Pool {
-	logger.Log.Infow("New pool", "queueSize", cnf.QueueSize, "workerCount", cnf.WorkerCount, "pause", cnf.Pause, "accrualURL", cnf.AccrualURL)
-	inChanel := make(chan string, cnf.QueueSize)
+// NewPool инициализирует и возвращает новый экземпляр Pool с указанным контекстом, размером очереди, количеством рабочих процессов
+// и реестром поставщиков начислений.
+func NewPool(cnf PoolConfig) (*Pool, error) {
+	if cnf.LeaseDuration <= 0 {
+		cnf.LeaseDuration = defaultLeaseDuration
+	}
+	instanceID := cnf.InstanceID
+	if instanceID == "" {
+		instanceID = newInstanceID()
+	}
+	logger.Log.Infow("New pool", "queueSize", cnf.QueueSize, "workerCount", cnf.WorkerCount, "pause", cnf.Pause, "accrualURL", cnf.AccrualURL, "maxAttempts", cnf.MaxAttempts, "instanceID", instanceID, "leaseDuration", cnf.LeaseDuration, "providers", len(cnf.Providers), "batchSize", cnf.BatchSize)
 	poolContext, cancel := context.WithCancel(cnf.CTX)
-	proxy := accrual.NewProxy(cnf.Pause, cnf.AccrualURL)
+	providers, err := NewRegistry(poolContext, withDefaultProvider(cnf))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	inChanel := make(chan string, cnf.QueueSize)
 
 	pool := &Pool{
 		mutex:             sync.RWMutex{},
@@ -84,13 +126,37 @@ func NewPool(cnf PoolConfig) *Pool {
 		orderMap:          make(map[string]*WorkedOrder),
 		wg:                sync.WaitGroup{},
 		olderThenDuration: time.Second * 5,
+		maxAttempts:       cnf.MaxAttempts,
+		batchSize:         cnf.BatchSize,
+		instanceID:        instanceID,
+		leaseDuration:     cnf.LeaseDuration,
+		dbExecutor:        cnf.DBExecutor,
 		accountRepo:       getAccountRepository(cnf.CTX, cnf.DBExecutor),
 		orderRepo:         getOrderRepository(cnf.CTX, cnf.DBExecutor),
-		accrualProxy:      proxy,
+		providers:         providers,
 	}
 	initPool(cnf.WorkerCount, pool, cnf.DBCheckDuration)
 
-	return pool
+	return pool, nil
+}
+
+// withDefaultProvider добавляем поставщика по умолчанию из общих настроек пула, если он не задан среди поставщиков
+func withDefaultProvider(cnf PoolConfig) []ProviderConfig {
+	for _, provider := range cnf.Providers {
+		if len(provider.Prefixes) == 0 && provider.Match == nil {
+			return cnf.Providers
+		}
+	}
+	return append([]ProviderConfig{{
+		Name: DefaultProviderName,
+		Proxy: accrual.ProxyConfig{
+			Pause:      cnf.Pause,
+			AccrualURL: cnf.AccrualURL,
+			Breaker:    cnf.Breaker,
+			RateLimit:  cnf.RateLimit,
+			RateBurst:  cnf.RateBurst,
+		},
+	}}, cnf.Providers...)
 }
 
 // initPool инициализирует и запускает пул рабочих процессов,
@@ -106,6 +172,24 @@ func initPool(workerCount int, pool *Pool, dbCheckDuration time.Duration) {
 	// Запускаем проверку базы данных
 	pool.wg.Add(1)
 	go pool.pushFromDB(dbCheckDuration)
+	// Запускаем продление аренды заказов в очереди
+	pool.wg.Add(1)
+	go pool.renewLeases(pool.leaseDuration / 3)
+}
+
+// AccrualStatus состояние автоматических выключателей запросов к поставщикам начислений
+func (p *Pool) AccrualStatus() []ProviderStatus {
+	return p.providers.Statuses()
+}
+
+// Running пул запущен и не закрыт
+func (p *Pool) Running() bool {
+	return !p.closeFlag.Load() && p.ctx.Err() == nil
+}
+
+// AccrualOpenFor через сколько закроется выключатель, если он открыт у всех поставщиков начислений, иначе 0
+func (p *Pool) AccrualOpenFor() time.Duration {
+	return p.providers.BreakerOpenFor()
 }
 
 // Close функция закрытия пула, закрываем локальный контекст, ждём завершения всех воркеров, закрываем канал очереди
@@ -138,3 +222,14 @@ func getOrderRepository(ctx context.Context, executor repositories.SQLExecutor)
 	logger.Log.Infow("Get order repository")
 	return repositories.NewOrderRepository(ctx, executor)
 }
+
+// inTransaction выполняем fn с репозиториями заказов и начислений, работающими в одной транзакции.
+// Без подключения к базе данных fn получает заданные репозитории.
+func (p *Pool) inTransaction(ctx context.Context, fn func(orderRep oRepo, accountRep aRepo) error) error {
+	if p.dbExecutor == nil {
+		return fn(p.orderRepo, p.accountRepo)
+	}
+	return repositories.InTransaction(ctx, p.dbExecutor, nil, func(tx repositories.SQLQueryer) error {
+		return fn(repositories.NewOrderRepository(ctx, tx), repositories.NewAccountRepository(ctx, tx))
+	})
+}
@@ -1,15 +1,27 @@
 package ordercheck
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gofemart/internal/accrual"
 	"gofemart/internal/logger"
+	"gofemart/internal/metrics"
 	"gofemart/internal/models"
 	"gofemart/internal/payloads"
+	"gofemart/internal/tracing"
 	"time"
 )
 
+// checkFailedLabel метка неудачной проверки заказа, после которой проверка повторится
+const checkFailedLabel = "FAILED"
+
+// ErrorUnknownAccrualStatus Ошибка, что система начислений вернула непредвиденный статус заказа
+var ErrorUnknownAccrualStatus = errors.New("unknown accrual status")
+
 // processOder обрабатываем заказ, запрашивая информацию у внешней системы
 func (p *Pool) processOder(number string) {
 	logger.Log.Infow("Process order", "number", number)
@@ -18,44 +30,230 @@ func (p *Pool) processOder(number string) {
 	if order == nil || !ok {
 		return
 	}
-	defer p.removeFromWork(number)
-	accrualResponse, err := p.accrualProxy.Accrual(order.model)
+	// После обработки убираем заказ из очереди, чтобы проверка базы данных могла вернуть его в назначенное время
+	defer p.deleteFromMap(number)
+	// Проверка продолжает трассировку постановки заказа в очередь
+	ctx, span := tracing.Start(trace.ContextWithSpanContext(p.ctx, order.spanContext), "checkpool.process", number)
+	defer span.End()
+	// Заказ, который проверяет или уже проверил другой экземпляр приложения, пропускаем
+	claimed, ok := p.claimOrder(number)
+	if !ok {
+		span.SetAttributes(attribute.Bool("checkpool.claimed_by_other", true))
+		return
+	}
+	defer p.releaseOrder(number)
+	provider, err := p.providers.For(claimed)
 	if err != nil {
-		logger.Log.Error(err)
-		var tmrErr accrual.TooManyRequestError
-		if ok := errors.Is(err, &tmrErr); ok {
-			p.accrualProxy.Pause(tmrErr.PauseDuration)
+		p.processAccrualError(ctx, nil, []*models.Order{claimed}, err)
+		return
+	}
+	span.SetAttributes(attribute.String("accrual.provider", provider.Name))
+	p.checkOrder(ctx, provider, claimed)
+}
+
+// processBatch обрабатываем несколько заказов, запрашивая их у поставщиков начислений пакетами.
+// Если поставщик не поддерживает пакетный запрос, заказы проверяются по одному.
+func (p *Pool) processBatch(numbers []string) {
+	logger.Log.Infow("Process batch", "numbers", len(numbers))
+	orders := make([]*models.Order, 0, len(numbers))
+	// У пакета несколько родителей, поэтому спаны постановки заказов в очередь связываем ссылками
+	links := make([]trace.Link, 0, len(numbers))
+	for _, number := range numbers {
+		order, ok := p.poolInWork(number)
+		if order == nil || !ok {
+			continue
+		}
+		defer p.deleteFromMap(number)
+		claimed, ok := p.claimOrder(number)
+		if !ok {
+			continue
+		}
+		defer p.releaseOrder(number)
+		orders = append(orders, claimed)
+		links = append(links, trace.Link{
+			SpanContext: order.spanContext,
+			Attributes:  []attribute.KeyValue{tracing.OrderNumberKey.String(number)},
+		})
+	}
+	ctx, span := tracing.Tracer().Start(p.ctx, "checkpool.process_batch",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("checkpool.orders", len(orders))),
+	)
+	defer span.End()
+	groups := make(map[*Provider][]*models.Order)
+	for _, order := range orders {
+		provider, err := p.providers.For(order)
+		if err != nil {
+			p.processAccrualError(ctx, nil, []*models.Order{order}, err)
+			continue
+		}
+		groups[provider] = append(groups[provider], order)
+	}
+	for provider, providerOrders := range groups {
+		p.checkOrders(ctx, provider, providerOrders)
+	}
+}
+
+// checkOrders проверяем заказы одного поставщика пакетным запросом, если поставщик его поддерживает
+func (p *Pool) checkOrders(ctx context.Context, provider *Provider, orders []*models.Order) {
+	batcher, ok := provider.Accrual.(BatchAccrual)
+	if !ok || len(orders) == 1 {
+		for _, order := range orders {
+			p.checkOrder(ctx, provider, order)
+		}
+		return
+	}
+	responses, err := batcher.AccrualBatch(ctx, orders)
+	if errors.Is(err, accrual.ErrorBatchUnsupported) {
+		logger.Log.Infow("Batch unsupported, check orders one by one", "provider", provider.Name)
+		for _, order := range orders {
+			p.checkOrder(ctx, provider, order)
+		}
+		return
+	}
+	if err != nil {
+		p.processAccrualError(ctx, provider, orders, err)
+		return
+	}
+	for _, order := range orders {
+		// Заказа нет в ответе, значит он не зарегистрирован в системе начислений
+		response, ok := responses[order.Number]
+		if !ok {
+			p.processAccrualError(ctx, provider, []*models.Order{order}, accrual.ErrorOrderNotRegistered)
+			continue
+		}
+		if err := p.processOrderAccrual(ctx, response, order); err != nil {
+			logger.Log.Error(err)
 		}
+	}
+}
+
+// checkOrder запрашиваем заказ у поставщика начислений и обрабатываем ответ
+func (p *Pool) checkOrder(ctx context.Context, provider *Provider, order *models.Order) {
+	accrualResponse, err := provider.Accrual.Accrual(ctx, order)
+	if err != nil {
+		p.processAccrualError(ctx, provider, []*models.Order{order}, err)
 		return
 	}
-	if err := p.processOrderAccrual(accrualResponse, order.model); err != nil {
+	if err := p.processOrderAccrual(ctx, accrualResponse, order); err != nil {
 		logger.Log.Error(err)
 	}
 }
 
-// processOrderAccrual обрабатываем ответ системы начислений, обновляем заказ и создаём запись в счёте пользователя
-func (p *Pool) processOrderAccrual(accrual *payloads.Accrual, order *models.Order) error {
+// processAccrualError обрабатываем ошибку запроса заказов к поставщику начислений
+func (p *Pool) processAccrualError(ctx context.Context, provider *Provider, orders []*models.Order, err error) {
+	if provider != nil {
+		logger.Log.Errorw(err.Error(), "provider", provider.Name, "orders", len(orders))
+	} else {
+		logger.Log.Error(err)
+	}
+	var tmrErr *accrual.TooManyRequestError
+	if errors.As(err, &tmrErr) {
+		// Заказ не виноват в ограничении запросов, попытку не учитываем, пауза действует только на этого поставщика
+		provider.Accrual.Pause(tmrErr.PauseDuration)
+		return
+	}
+	if errors.Is(err, accrual.ErrorCircuitOpen) {
+		// Запрос не отправлялся, заказ вернётся из базы данных после закрытия выключателя
+		return
+	}
+	for _, order := range orders {
+		if err := p.processOrderFailure(ctx, order, err); err != nil {
+			logger.Log.Error(err)
+		}
+	}
+}
+
+// processOrderAccrual обрабатываем ответ системы начислений, обновляем заказ, создаём запись в счёте пользователя
+// и записываем изменение статуса в историю заказа
+func (p *Pool) processOrderAccrual(ctx context.Context, accrual *payloads.Accrual, order *models.Order) error {
 	logger.Log.Infow("Process order accrual", "order", order.Number, "status", accrual.Status)
-	order.LastCheckedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	now := time.Now()
+	oldStatus := order.StatusCode
+	var status string
+	var accrualSum *models.Money
+	nextCheckAt := sql.NullTime{}
 	switch accrual.Status {
 	case payloads.StatusAccrualProcessing, payloads.StatusAccrualRegistered:
-		order.StatusCode = models.StatusProcessing
+		status = models.StatusProcessing
+		nextCheckAt = sql.NullTime{Time: now.Add(p.olderThenDuration), Valid: true}
 	case payloads.StatusAccrualInvalid:
-		order.StatusCode = models.StatusInvalid
+		status = models.StatusInvalid
 	case payloads.StatusAccrualProcessed:
-		if _, err := p.createNewAccount(order.Number, order.UserID, accrual.Accrual); err != nil {
+		status = models.StatusProcessed
+		accrualSum = &accrual.Accrual
+	default:
+		// Непредвиденный статус считаем неудачной проверкой, чтобы заказ не проверялся бесконечно
+		return p.processOrderFailure(ctx, order, fmt.Errorf("%w: %s", ErrorUnknownAccrualStatus, accrual.Status))
+	}
+	ctx, span := tracing.Start(ctx, "orders.update", order.Number, attribute.String("order.status", status))
+	// Начисление, обновление заказа и история фиксируются в одной транзакции,
+	// иначе после сбоя заказ может быть начислен повторно или потерять запись в истории
+	err := p.inTransaction(ctx, func(orderRep oRepo, accountRep aRepo) error {
+		if accrualSum != nil {
+			if _, err := p.createNewAccount(accountRep, order.Number, order.UserID, *accrualSum); err != nil {
+				return err
+			}
+		}
+		order.StatusCode = status
+		order.LastCheckedAt = sql.NullTime{Time: now, Valid: true}
+		order.NextCheckAt = nextCheckAt
+		order.Attempts = 0
+		order.LastError = sql.NullString{}
+		if err := orderRep.UpdateOrder(order); err != nil {
 			return err
 		}
-		order.StatusCode = models.StatusProcessed
+		history := models.NewOrderStatusHistory(order.Number, oldStatus, order.StatusCode, accrual.Status, accrualSum)
+		return orderRep.CreateStatusHistory(history)
+	})
+	tracing.End(span, err)
+	if err != nil {
+		return err
 	}
-	orderRep := p.orderRepo
-	return orderRep.UpdateOrder(order)
+	metrics.OrdersChecked.WithLabelValues(order.StatusCode).Inc()
+	return nil
+}
+
+// processOrderFailure откладываем следующую проверку заказа после неудачного запроса в систему начислений.
+// Задержка растёт с количеством неудачных проверок подряд и зависит от типа ошибки.
+// После maxAttempts неудачных проверок заказ переводится в статус StatusParked и больше не выбирается из базы данных,
+// вернуть его в очередь можно через административный API. Перевод в StatusParked записывается в историю статусов
+// в одной транзакции с обновлением заказа, ответа системы начислений при этом нет, поэтому AccrualStatus пустой.
+func (p *Pool) processOrderFailure(ctx context.Context, order *models.Order, checkErr error) error {
+	now := time.Now()
+	oldStatus := order.StatusCode
+	order.Attempts++
+	order.LastCheckedAt = sql.NullTime{Time: now, Valid: true}
+	order.LastError = sql.NullString{String: checkErr.Error(), Valid: true}
+	if p.maxAttempts > 0 && order.Attempts >= p.maxAttempts {
+		logger.Log.Warnw("Order parked", "order", order.Number, "attempts", order.Attempts, "error", checkErr)
+		order.StatusCode = models.StatusParked
+		order.NextCheckAt = sql.NullTime{}
+		order.ParkedAt = sql.NullTime{Time: now, Valid: true}
+		metrics.OrdersChecked.WithLabelValues(models.StatusParked).Inc()
+	} else {
+		metrics.OrdersChecked.WithLabelValues(checkFailedLabel).Inc()
+		delay := backoffFor(checkErr).Delay(order.Attempts)
+		logger.Log.Infow("Order check postponed", "order", order.Number, "attempts", order.Attempts, "delay", delay)
+		order.NextCheckAt = sql.NullTime{Time: now.Add(delay), Valid: true}
+	}
+	ctx, span := tracing.Start(ctx, "orders.update", order.Number, attribute.String("order.status", order.StatusCode))
+	err := p.inTransaction(ctx, func(orderRep oRepo, _ aRepo) error {
+		if err := orderRep.UpdateOrder(order); err != nil {
+			return err
+		}
+		if order.StatusCode != models.StatusParked || oldStatus == models.StatusParked {
+			return nil
+		}
+		return orderRep.CreateStatusHistory(models.NewOrderStatusHistory(order.Number, oldStatus, models.StatusParked, "", nil))
+	})
+	tracing.End(span, err)
+	return err
 }
 
 // createNewAccount создаём новую запись о начислении
-func (p *Pool) createNewAccount(orderNumber string, userID int64, diff float64) (*models.Account, error) {
-	logger.Log.Infow("Create new account", "orderNumber", orderNumber, "userID", userID, "diff", diff)
-	repository := p.accountRepo
+func (p *Pool) createNewAccount(repository aRepo, orderNumber string, userID int64, diff models.Money) (*models.Account, error) {
+	logger.Log.Infow("Create new account", "orderNumber", orderNumber, "userID", userID, "diff", diff.String())
 	account := models.NewAccount(sql.NullString{String: orderNumber, Valid: true}, userID, diff)
 	if err := repository.CreateAccount(account); err != nil {
 		return nil, err
@@ -1,12 +1,21 @@
 package ordercheck
 
 import (
+	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gofemart/internal/accrual"
 	"gofemart/internal/models"
 	"gofemart/internal/ordercheck/mock"
 	"gofemart/internal/payloads"
+	"gofemart/internal/tracing"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestCreateNewAccount(t *testing.T) {
@@ -15,7 +24,7 @@ func TestCreateNewAccount(t *testing.T) {
 		name             string
 		inputOrderNumber string
 		inputUserID      int64
-		inputDiff        float64
+		inputDiff        models.Money
 		wantErr          bool
 		setup            func() aRepo
 	}{
@@ -23,7 +32,7 @@ func TestCreateNewAccount(t *testing.T) {
 			name:             "success_create_account",
 			inputOrderNumber: "ORD123",
 			inputUserID:      123,
-			inputDiff:        45.6,
+			inputDiff:        models.NewMoney(decimal.RequireFromString("45.6")),
 			wantErr:          false,
 			setup: func() aRepo {
 				repo := mock.NewMockaRepo(ctrl)
@@ -38,7 +47,7 @@ func TestCreateNewAccount(t *testing.T) {
 			name:             "wrong_input_order_number",
 			inputOrderNumber: "",
 			inputUserID:      0,
-			inputDiff:        0,
+			inputDiff:        models.Money{},
 			wantErr:          true,
 			setup: func() aRepo {
 				repo := mock.NewMockaRepo(ctrl)
@@ -56,7 +65,7 @@ func TestCreateNewAccount(t *testing.T) {
 			p := Pool{
 				accountRepo: tc.setup(),
 			}
-			_, err := p.createNewAccount(tc.inputOrderNumber, tc.inputUserID, tc.inputDiff)
+			_, err := p.createNewAccount(p.accountRepo, tc.inputOrderNumber, tc.inputUserID, tc.inputDiff)
 			if tc.wantErr && err == nil {
 				t.Errorf("expected error, got %v", err)
 			}
@@ -83,7 +92,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 			accrual: &payloads.Accrual{
 				Order:   "1",
 				Status:  payloads.StatusAccrualProcessing,
-				Accrual: 11,
+				Accrual: models.NewMoney(decimal.NewFromInt(11)),
 			},
 			order: &models.Order{
 				Number:     "1",
@@ -95,6 +104,10 @@ func TestProcessOrderAccrual(t *testing.T) {
 					UpdateOrder(gomock.Any()).
 					AnyTimes().
 					Return(nil)
+				repo.EXPECT().
+					CreateStatusHistory(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				return repo
 			},
 			aSetup: func() aRepo {
@@ -109,7 +122,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 			accrual: &payloads.Accrual{
 				Order:   "1",
 				Status:  payloads.StatusAccrualRegistered,
-				Accrual: 11,
+				Accrual: models.NewMoney(decimal.NewFromInt(11)),
 			},
 			order: &models.Order{
 				Number:     "1",
@@ -121,6 +134,10 @@ func TestProcessOrderAccrual(t *testing.T) {
 					UpdateOrder(gomock.Any()).
 					AnyTimes().
 					Return(nil)
+				repo.EXPECT().
+					CreateStatusHistory(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				return repo
 			},
 			aSetup: func() aRepo {
@@ -135,7 +152,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 			accrual: &payloads.Accrual{
 				Order:   "1",
 				Status:  payloads.StatusAccrualInvalid,
-				Accrual: 11,
+				Accrual: models.NewMoney(decimal.NewFromInt(11)),
 			},
 			order: &models.Order{
 				Number:     "1",
@@ -147,6 +164,10 @@ func TestProcessOrderAccrual(t *testing.T) {
 					UpdateOrder(gomock.Any()).
 					AnyTimes().
 					Return(nil)
+				repo.EXPECT().
+					CreateStatusHistory(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				return repo
 			},
 			aSetup: func() aRepo {
@@ -161,7 +182,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 			accrual: &payloads.Accrual{
 				Order:   "1",
 				Status:  payloads.StatusAccrualProcessed,
-				Accrual: 11,
+				Accrual: models.NewMoney(decimal.NewFromInt(11)),
 			},
 			order: &models.Order{
 				Number:     "1",
@@ -173,6 +194,10 @@ func TestProcessOrderAccrual(t *testing.T) {
 					UpdateOrder(gomock.Any()).
 					AnyTimes().
 					Return(nil)
+				repo.EXPECT().
+					CreateStatusHistory(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				return repo
 			},
 			aSetup: func() aRepo {
@@ -191,7 +216,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 			accrual: &payloads.Accrual{
 				Order:   "1",
 				Status:  payloads.StatusAccrualProcessed,
-				Accrual: 11,
+				Accrual: models.NewMoney(decimal.NewFromInt(11)),
 			},
 			order: &models.Order{
 				Number:     "1",
@@ -203,6 +228,10 @@ func TestProcessOrderAccrual(t *testing.T) {
 					UpdateOrder(gomock.Any()).
 					AnyTimes().
 					Return(nil)
+				repo.EXPECT().
+					CreateStatusHistory(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				return repo
 			},
 			aSetup: func() aRepo {
@@ -221,7 +250,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 			accrual: &payloads.Accrual{
 				Order:   "1",
 				Status:  payloads.StatusAccrualInvalid,
-				Accrual: 11,
+				Accrual: models.NewMoney(decimal.NewFromInt(11)),
 			},
 			order: &models.Order{
 				Number:     "1",
@@ -242,6 +271,35 @@ func TestProcessOrderAccrual(t *testing.T) {
 			wantErr:    true,
 			wantStatus: models.StatusInvalid,
 		},
+		{
+			name: "status_processing_and_history_error",
+			accrual: &payloads.Accrual{
+				Order:  "1",
+				Status: payloads.StatusAccrualProcessing,
+			},
+			order: &models.Order{
+				Number:     "1",
+				StatusCode: models.StatusNew,
+			},
+			setup: func() oRepo {
+				repo := mock.NewMockoRepo(ctrl)
+				repo.EXPECT().
+					UpdateOrder(gomock.Any()).
+					AnyTimes().
+					Return(nil)
+				repo.EXPECT().
+					CreateStatusHistory(gomock.Any()).
+					AnyTimes().
+					Return(errors.New("history error"))
+				return repo
+			},
+			aSetup: func() aRepo {
+				repo := mock.NewMockaRepo(ctrl)
+				return repo
+			},
+			wantErr:    true,
+			wantStatus: models.StatusProcessing,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -250,7 +308,7 @@ func TestProcessOrderAccrual(t *testing.T) {
 				orderRepo:   tc.setup(),
 				accountRepo: tc.aSetup(),
 			}
-			err := p.processOrderAccrual(tc.accrual, tc.order)
+			err := p.processOrderAccrual(context.Background(), tc.accrual, tc.order)
 			if tc.wantErr && err == nil {
 				t.Errorf("expected error, got %v", err)
 			}
@@ -263,3 +321,423 @@ func TestProcessOrderAccrual(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessOrderAccrualHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	testCases := []struct {
+		name        string
+		accrual     *payloads.Accrual
+		oldStatus   string
+		wantNew     string
+		wantAccrual bool
+	}{
+		{
+			name:      "new_to_processing",
+			accrual:   &payloads.Accrual{Order: "1", Status: payloads.StatusAccrualRegistered},
+			oldStatus: models.StatusNew,
+			wantNew:   models.StatusProcessing,
+		},
+		{
+			name:      "processing_to_invalid",
+			accrual:   &payloads.Accrual{Order: "1", Status: payloads.StatusAccrualInvalid},
+			oldStatus: models.StatusProcessing,
+			wantNew:   models.StatusInvalid,
+		},
+		{
+			name:        "processing_to_processed",
+			accrual:     &payloads.Accrual{Order: "1", Status: payloads.StatusAccrualProcessed, Accrual: models.NewMoney(decimal.RequireFromString("729.98"))},
+			oldStatus:   models.StatusProcessing,
+			wantNew:     models.StatusProcessed,
+			wantAccrual: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got *models.OrderStatusHistory
+			oRepository := mock.NewMockoRepo(ctrl)
+			oRepository.EXPECT().UpdateOrder(gomock.Any()).Return(nil)
+			oRepository.EXPECT().
+				CreateStatusHistory(gomock.Any()).
+				DoAndReturn(func(history *models.OrderStatusHistory) error {
+					got = history
+					return nil
+				})
+			aRepository := mock.NewMockaRepo(ctrl)
+			aRepository.EXPECT().CreateAccount(gomock.Any()).AnyTimes().Return(nil)
+			p := Pool{
+				orderRepo:   oRepository,
+				accountRepo: aRepository,
+			}
+			order := &models.Order{Number: "1", StatusCode: tc.oldStatus}
+			if err := p.processOrderAccrual(context.Background(), tc.accrual, order); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got == nil {
+				t.Fatal("expected history to be created")
+			}
+			if got.OrderNumber != order.Number || got.OldStatus != tc.oldStatus || got.NewStatus != tc.wantNew || got.AccrualStatus != tc.accrual.Status {
+				t.Errorf("unexpected history %+v", got)
+			}
+			if tc.wantAccrual && (got.Accrual == nil || !got.Accrual.Equal(tc.accrual.Accrual.Decimal)) {
+				t.Errorf("expected accrual %s, got %v", tc.accrual.Accrual.String(), got.Accrual)
+			}
+			if !tc.wantAccrual && got.Accrual != nil {
+				t.Errorf("expected no accrual, got %s", got.Accrual.String())
+			}
+		})
+	}
+}
+
+func TestProcessOrderFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	testCases := []struct {
+		name         string
+		maxAttempts  int
+		attempts     int
+		err          error
+		wantAttempts int
+		wantParked   bool
+		wantMaxDelay time.Duration
+	}{
+		{
+			name:         "first_not_registered",
+			maxAttempts:  3,
+			err:          accrual.ErrorOrderNotRegistered,
+			wantAttempts: 1,
+			wantMaxDelay: notRegisteredBackoff.Base,
+		},
+		{
+			name:         "internal_error_grows",
+			maxAttempts:  5,
+			attempts:     2,
+			err:          accrual.ErrorInternalAccrual,
+			wantAttempts: 3,
+			wantMaxDelay: 4 * internalErrorBackoff.Base,
+		},
+		{
+			name:         "parked_after_max_attempts",
+			maxAttempts:  3,
+			attempts:     2,
+			err:          accrual.ErrorOrderNotRegistered,
+			wantAttempts: 3,
+			wantParked:   true,
+		},
+		{
+			name:         "unlimited_attempts",
+			attempts:     100,
+			err:          accrual.ErrorUnknownStatusRequests,
+			wantAttempts: 101,
+			wantMaxDelay: defaultBackoff.Max,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oRepository := mock.NewMockoRepo(ctrl)
+			oRepository.EXPECT().UpdateOrder(gomock.Any()).Return(nil)
+			if tc.wantParked {
+				// Перевод в StatusParked попадает в историю статусов заказа
+				oRepository.EXPECT().CreateStatusHistory(gomock.Any()).DoAndReturn(func(history *models.OrderStatusHistory) error {
+					if history.OldStatus != models.StatusNew || history.NewStatus != models.StatusParked {
+						t.Errorf("expected history %s -> %s, got %s -> %s", models.StatusNew, models.StatusParked, history.OldStatus, history.NewStatus)
+					}
+					return nil
+				})
+			}
+			p := Pool{
+				orderRepo:   oRepository,
+				maxAttempts: tc.maxAttempts,
+			}
+			order := &models.Order{Number: "1", StatusCode: models.StatusNew, Attempts: tc.attempts}
+			before := time.Now()
+			if err := p.processOrderFailure(context.Background(), order, tc.err); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if order.Attempts != tc.wantAttempts {
+				t.Errorf("expected attempts %d, got %d", tc.wantAttempts, order.Attempts)
+			}
+			if !order.LastCheckedAt.Valid {
+				t.Error("expected last checked time to be set")
+			}
+			if order.ParkedAt.Valid != tc.wantParked {
+				t.Errorf("expected parked %v, got %v", tc.wantParked, order.ParkedAt.Valid)
+			}
+			if order.LastError.String != tc.err.Error() {
+				t.Errorf("expected last error %q, got %q", tc.err.Error(), order.LastError.String)
+			}
+			if tc.wantParked {
+				if order.StatusCode != models.StatusParked {
+					t.Errorf("expected status %s, got %s", models.StatusParked, order.StatusCode)
+				}
+				if order.NextCheckAt.Valid {
+					t.Error("expected no next check for parked order")
+				}
+				return
+			}
+			if !order.NextCheckAt.Valid {
+				t.Fatal("expected next check time to be set")
+			}
+			delay := order.NextCheckAt.Time.Sub(before)
+			if delay < tc.wantMaxDelay/2 || delay > tc.wantMaxDelay+time.Second {
+				t.Errorf("expected delay up to %v, got %v", tc.wantMaxDelay, delay)
+			}
+		})
+	}
+}
+
+func TestProcessOrderAccrualUnknownStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	oRepository := mock.NewMockoRepo(ctrl)
+	oRepository.EXPECT().UpdateOrder(gomock.Any()).Return(nil)
+	oRepository.EXPECT().CreateStatusHistory(gomock.Any()).Return(nil)
+	p := Pool{
+		orderRepo:   oRepository,
+		accountRepo: mock.NewMockaRepo(ctrl),
+		maxAttempts: 1,
+	}
+	order := &models.Order{Number: "1", StatusCode: models.StatusProcessing}
+	if err := p.processOrderAccrual(context.Background(), &payloads.Accrual{Order: "1", Status: "LOST"}, order); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if order.StatusCode != models.StatusParked {
+		t.Errorf("expected status %s, got %s", models.StatusParked, order.StatusCode)
+	}
+	if order.Attempts != 1 || !order.LastError.Valid {
+		t.Errorf("expected failed attempt with last error, got attempts %d, last error %q", order.Attempts, order.LastError.String)
+	}
+}
+
+func TestProcessOrderTooManyRequestsFromConcurrentWorkers(t *testing.T) {
+	const workers = 4
+	const orders = 16
+	ctrl := gomock.NewController(t)
+	proxy := mock.NewMockAccrual(ctrl)
+	proxy.EXPECT().BreakerOpenFor().Return(time.Duration(0)).AnyTimes()
+	proxy.EXPECT().
+		Accrual(gomock.Any(), gomock.Any()).
+		Return(nil, &accrual.TooManyRequestError{InternalError: accrual.ErrorTooManyRequests, PauseDuration: time.Minute}).
+		Times(orders)
+	// Пауза не должна блокировать обработчик, иначе остальные заказы не будут разобраны
+	proxy.EXPECT().Pause(time.Minute).Times(orders)
+	// Ограничение запросов не считается неудачной попыткой, заказ не обновляется
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().ClaimOrder(gomock.Any(), "instance", gomock.Any(), gomock.Any()).DoAndReturn(claimNew).Times(orders)
+	repo.EXPECT().ReleaseOrder(gomock.Any(), "instance").Return(nil).Times(orders)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &Pool{
+		orderMap:      make(map[string]*WorkedOrder),
+		inChanel:      make(chan string, orders),
+		ctx:           ctx,
+		providers:     newTestRegistry(t, proxy),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+	}
+	for i := 0; i < orders; i++ {
+		number := strconv.Itoa(i)
+		p.orderMap[number] = &WorkedOrder{model: &models.Order{Number: number}}
+		p.inChanel <- number
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.pushFromQueue()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.getCurrentOrdersKeys()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected all orders to be processed, left %v", p.getCurrentOrdersKeys())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	p.wg.Wait()
+}
+
+// batchAccrual поставщик начислений с поддержкой пакетного запроса
+type batchAccrual struct {
+	*mock.MockAccrual
+	*mock.MockBatchAccrual
+}
+
+func TestProcessBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := batchAccrual{MockAccrual: mock.NewMockAccrual(ctrl), MockBatchAccrual: mock.NewMockBatchAccrual(ctrl)}
+	provider.MockBatchAccrual.EXPECT().
+		AccrualBatch(gomock.Any(), gomock.Len(2)).
+		Return(map[string]*payloads.Accrual{"1": {Order: "1", Status: payloads.StatusAccrualInvalid}}, nil)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().ClaimOrder(gomock.Any(), "instance", gomock.Any(), gomock.Any()).DoAndReturn(claimNew).Times(2)
+	repo.EXPECT().ReleaseOrder(gomock.Any(), "instance").Return(nil).Times(2)
+	// Найденный заказ обновляется по ответу, отсутствующий в ответе считается незарегистрированным
+	repo.EXPECT().UpdateOrder(gomock.Any()).DoAndReturn(func(order *models.Order) error {
+		switch order.Number {
+		case "1":
+			if order.StatusCode != models.StatusInvalid {
+				t.Errorf("expected order 1 status %s, got %s", models.StatusInvalid, order.StatusCode)
+			}
+		case "2":
+			if order.Attempts != 1 || order.LastError.String != accrual.ErrorOrderNotRegistered.Error() {
+				t.Errorf("expected order 2 failure, got %+v", order)
+			}
+		}
+		return nil
+	}).Times(2)
+	repo.EXPECT().CreateStatusHistory(gomock.Any()).Return(nil)
+	p := &Pool{
+		orderMap: map[string]*WorkedOrder{
+			"1": {model: &models.Order{Number: "1", StatusCode: models.StatusNew}},
+			"2": {model: &models.Order{Number: "2", StatusCode: models.StatusNew}},
+		},
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+		providers:     newTestRegistry(t, provider),
+	}
+	p.processBatch([]string{"1", "2"})
+	if len(p.getCurrentOrdersKeys()) != 0 {
+		t.Errorf("expected orders to leave the queue, got %v", p.getCurrentOrdersKeys())
+	}
+}
+
+func TestProcessBatchFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := batchAccrual{MockAccrual: mock.NewMockAccrual(ctrl), MockBatchAccrual: mock.NewMockBatchAccrual(ctrl)}
+	provider.MockBatchAccrual.EXPECT().AccrualBatch(gomock.Any(), gomock.Any()).Return(nil, accrual.ErrorBatchUnsupported)
+	provider.MockAccrual.EXPECT().
+		Accrual(gomock.Any(), gomock.Any()).
+		Return(nil, &accrual.TooManyRequestError{InternalError: accrual.ErrorTooManyRequests, PauseDuration: time.Second}).
+		Times(2)
+	provider.MockAccrual.EXPECT().Pause(time.Second).Times(2)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().ClaimOrder(gomock.Any(), "instance", gomock.Any(), gomock.Any()).DoAndReturn(claimNew).Times(2)
+	repo.EXPECT().ReleaseOrder(gomock.Any(), "instance").Return(nil).Times(2)
+	p := &Pool{
+		orderMap: map[string]*WorkedOrder{
+			"1": {model: &models.Order{Number: "1"}},
+			"2": {model: &models.Order{Number: "2"}},
+		},
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+		providers:     newTestRegistry(t, provider),
+	}
+	p.processBatch([]string{"1", "2"})
+}
+
+func TestProcessBatchServiceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := batchAccrual{MockAccrual: mock.NewMockAccrual(ctrl), MockBatchAccrual: mock.NewMockBatchAccrual(ctrl)}
+	provider.MockBatchAccrual.EXPECT().AccrualBatch(gomock.Any(), gomock.Any()).Return(nil, accrual.ErrorInternalAccrual)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().ClaimOrder(gomock.Any(), "instance", gomock.Any(), gomock.Any()).DoAndReturn(claimNew).Times(2)
+	repo.EXPECT().ReleaseOrder(gomock.Any(), "instance").Return(nil).Times(2)
+	// Ошибка пакетного запроса учитывается как неудачная проверка каждого заказа
+	repo.EXPECT().UpdateOrder(gomock.Any()).DoAndReturn(func(order *models.Order) error {
+		if order.Attempts != 1 {
+			t.Errorf("expected attempt for order %s, got %d", order.Number, order.Attempts)
+		}
+		return nil
+	}).Times(2)
+	p := &Pool{
+		orderMap: map[string]*WorkedOrder{
+			"1": {model: &models.Order{Number: "1"}},
+			"2": {model: &models.Order{Number: "2"}},
+		},
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+		providers:     newTestRegistry(t, provider),
+	}
+	p.processBatch([]string{"1", "2"})
+}
+
+func TestProcessOrderContinuesPushTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctrl := gomock.NewController(t)
+	proxy := mock.NewMockAccrual(ctrl)
+	proxy.EXPECT().Accrual(gomock.Any(), gomock.Any()).Return(nil, accrual.ErrorOrderNotRegistered)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().ClaimOrder("1", "instance", gomock.Any(), gomock.Any()).DoAndReturn(claimNew)
+	repo.EXPECT().ReleaseOrder("1", "instance").Return(nil)
+	repo.EXPECT().UpdateOrder(gomock.Any()).Return(nil)
+	p := &Pool{
+		orderMap:      make(map[string]*WorkedOrder),
+		inChanel:      make(chan string, 1),
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+		providers:     newTestRegistry(t, proxy),
+	}
+	if _, err := p.Push(context.Background(), &models.Order{Number: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	p.processOder(<-p.inChanel)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	push, process, update := spans["checkpool.push"], spans["checkpool.process"], spans["orders.update"]
+	if push == nil || process == nil || update == nil {
+		t.Fatalf("expected push, process and update spans, got %v", spans)
+	}
+	if process.Parent().SpanID() != push.SpanContext().SpanID() {
+		t.Errorf("expected process span to continue push span")
+	}
+	if update.Parent().SpanID() != process.SpanContext().SpanID() {
+		t.Errorf("expected update span to be a child of process span")
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{push, process, update} {
+		found := false
+		for _, attribute := range span.Attributes() {
+			if attribute.Key == tracing.OrderNumberKey && attribute.Value.AsString() == "1" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected order number attribute on span %s", span.Name())
+		}
+	}
+}
+
+func TestProcessOrderChecksClaimedOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	proxy := mock.NewMockAccrual(ctrl)
+	repo := mock.NewMockoRepo(ctrl)
+	// Другой экземпляр уже проверил заказ, пока он ждал в очереди, поэтому он не закрепляется и не проверяется
+	repo.EXPECT().ClaimOrder("1", "instance", gomock.Any(), gomock.Any()).Return(nil, false, nil)
+	// Заказ в очереди устарел, проверяется его состояние из базы данных
+	claimed := &models.Order{Number: "2", StatusCode: models.StatusProcessing, Attempts: 2}
+	repo.EXPECT().ClaimOrder("2", "instance", gomock.Any(), gomock.Any()).Return(claimed, true, nil)
+	repo.EXPECT().ReleaseOrder("2", "instance").Return(nil)
+	proxy.EXPECT().Accrual(gomock.Any(), claimed).Return(nil, accrual.ErrorOrderNotRegistered)
+	repo.EXPECT().UpdateOrder(claimed).Return(nil)
+	p := &Pool{
+		orderMap: map[string]*WorkedOrder{
+			"1": {model: &models.Order{Number: "1", StatusCode: models.StatusNew}},
+			"2": {model: &models.Order{Number: "2", StatusCode: models.StatusNew}},
+		},
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+		providers:     newTestRegistry(t, proxy),
+	}
+	p.processOder("1")
+	p.processOder("2")
+	if claimed.Attempts != 3 {
+		t.Errorf("expected failure to be counted from claimed order, got %d attempts", claimed.Attempts)
+	}
+}
@@ -0,0 +1,214 @@
+package ordercheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gofemart/internal/accrual"
+	"gofemart/internal/models"
+	"strings"
+	"time"
+)
+
+// DefaultProviderName имя поставщика начислений, собранного из общих настроек пула
+const DefaultProviderName = "default"
+
+// ErrorNoAccrualProvider Ошибка, что для заказа не найден поставщик начислений
+var ErrorNoAccrualProvider = errors.New("no accrual provider for order")
+
+// ErrorInvalidProviders Ошибка, что поставщики начислений настроены неверно
+var ErrorInvalidProviders = errors.New("invalid accrual providers")
+
+// ProviderConfig Конфигурация поставщика начислений.
+// Поставщик без префиксов и правила выбора используется для заказов, которые не подошли остальным.
+type ProviderConfig struct {
+	Name     string
+	Prefixes []string                       // префиксы номеров заказов, которые проверяет поставщик
+	Match    func(order *models.Order) bool // дополнительное правило выбора поставщика для заказа
+	Proxy    accrual.ProxyConfig            // адрес, пауза, выключатель и ограничение частоты запросов, контекст устанавливает пул
+	Accrual  Accrual                        // собственная реализация клиента, если задана, то Proxy не используется
+}
+
+// Provider поставщик начислений
+type Provider struct {
+	Name     string
+	Prefixes []string
+	Match    func(order *models.Order) bool
+	Accrual  Accrual
+}
+
+// ProviderStatus состояние запросов к поставщику начислений
+type ProviderStatus struct {
+	Name     string                `json:"name"`
+	Prefixes []string              `json:"prefixes,omitempty"`
+	Breaker  accrual.BreakerStatus `json:"breaker"`
+}
+
+// Registry реестр поставщиков начислений, выбирает поставщика для заказа
+type Registry struct {
+	providers []*Provider
+	fallback  *Provider
+}
+
+// NewRegistry создаём реестр поставщиков начислений, клиенты без собственной реализации создаются с контекстом ctx
+func NewRegistry(ctx context.Context, cnfs []ProviderConfig) (*Registry, error) {
+	registry := &Registry{}
+	names := make(map[string]struct{}, len(cnfs))
+	prefixes := make(map[string]string)
+	for _, cnf := range cnfs {
+		if cnf.Name == "" {
+			return nil, fmt.Errorf("%w: provider name is required", ErrorInvalidProviders)
+		}
+		if _, ok := names[cnf.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate provider %s", ErrorInvalidProviders, cnf.Name)
+		}
+		names[cnf.Name] = struct{}{}
+		for _, prefix := range cnf.Prefixes {
+			if prefix == "" {
+				return nil, fmt.Errorf("%w: provider %s has empty prefix", ErrorInvalidProviders, cnf.Name)
+			}
+			if owner, ok := prefixes[prefix]; ok {
+				return nil, fmt.Errorf("%w: prefix %s is used by providers %s and %s", ErrorInvalidProviders, prefix, owner, cnf.Name)
+			}
+			prefixes[prefix] = cnf.Name
+		}
+		provider := &Provider{
+			Name:     cnf.Name,
+			Prefixes: cnf.Prefixes,
+			Match:    cnf.Match,
+			Accrual:  cnf.Accrual,
+		}
+		if provider.Accrual == nil {
+			proxyConfig := cnf.Proxy
+			proxyConfig.CTX = ctx
+			proxyConfig.Name = cnf.Name
+			provider.Accrual = accrual.NewProxy(proxyConfig)
+		}
+		if len(cnf.Prefixes) == 0 && cnf.Match == nil {
+			if registry.fallback != nil {
+				return nil, fmt.Errorf("%w: providers %s and %s both have no prefixes", ErrorInvalidProviders, registry.fallback.Name, cnf.Name)
+			}
+			registry.fallback = provider
+		}
+		registry.providers = append(registry.providers, provider)
+	}
+	if len(registry.providers) == 0 {
+		return nil, fmt.Errorf("%w: no providers", ErrorInvalidProviders)
+	}
+	return registry, nil
+}
+
+// For выбираем поставщика для заказа: правило выбора, затем самый длинный подходящий префикс номера,
+// затем поставщик по умолчанию
+func (r *Registry) For(order *models.Order) (*Provider, error) {
+	var found *Provider
+	foundLen := 0
+	for _, provider := range r.providers {
+		if provider.Match != nil && provider.Match(order) {
+			return provider, nil
+		}
+		for _, prefix := range provider.Prefixes {
+			if len(prefix) > foundLen && strings.HasPrefix(order.Number, prefix) {
+				found, foundLen = provider, len(prefix)
+			}
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	if r.fallback != nil {
+		return r.fallback, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrorNoAccrualProvider, order.Number)
+}
+
+// BreakerOpenFor через сколько закроется выключатель, если он открыт у всех поставщиков.
+// Если хотя бы один поставщик принимает запросы, возвращает 0.
+func (r *Registry) BreakerOpenFor() time.Duration {
+	var openFor time.Duration
+	for i, provider := range r.providers {
+		providerOpenFor := provider.Accrual.BreakerOpenFor()
+		if providerOpenFor <= 0 {
+			return 0
+		}
+		if i == 0 || providerOpenFor < openFor {
+			openFor = providerOpenFor
+		}
+	}
+	return openFor
+}
+
+// Statuses состояние запросов ко всем поставщикам
+func (r *Registry) Statuses() []ProviderStatus {
+	statuses := make([]ProviderStatus, 0, len(r.providers))
+	for _, provider := range r.providers {
+		statuses = append(statuses, ProviderStatus{
+			Name:     provider.Name,
+			Prefixes: provider.Prefixes,
+			Breaker:  provider.Accrual.BreakerStatus(),
+		})
+	}
+	return statuses
+}
+
+// providerJSON описание поставщика начислений в конфигурации приложения
+type providerJSON struct {
+	Name             string   `json:"name"`
+	URL              string   `json:"url"`
+	Prefixes         []string `json:"prefixes"`
+	Pause            string   `json:"pause"`
+	BreakerThreshold int      `json:"breakerThreshold"`
+	BreakerCoolDown  string   `json:"breakerCoolDown"`
+	RateLimit        float64  `json:"rateLimit"`
+	RateBurst        int      `json:"rateBurst"`
+}
+
+// ParseProviderConfigs разбираем описание дополнительных поставщиков начислений из JSON массива.
+// Незаданные пауза, выключатель и ограничение частоты берутся из defaults.
+func ParseProviderConfigs(value string, defaults accrual.ProxyConfig) ([]ProviderConfig, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var items []providerJSON
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrorInvalidProviders, err)
+	}
+	cnfs := make([]ProviderConfig, 0, len(items))
+	for _, item := range items {
+		if item.URL == "" {
+			return nil, fmt.Errorf("%w: provider %s has no url", ErrorInvalidProviders, item.Name)
+		}
+		proxyConfig := defaults
+		proxyConfig.AccrualURL = item.URL
+		if item.Pause != "" {
+			pause, err := time.ParseDuration(item.Pause)
+			if err != nil {
+				return nil, fmt.Errorf("%w: provider %s: %w", ErrorInvalidProviders, item.Name, err)
+			}
+			proxyConfig.Pause = pause
+		}
+		if item.BreakerThreshold != 0 {
+			proxyConfig.Breaker.FailureThreshold = item.BreakerThreshold
+		}
+		if item.BreakerCoolDown != "" {
+			coolDown, err := time.ParseDuration(item.BreakerCoolDown)
+			if err != nil {
+				return nil, fmt.Errorf("%w: provider %s: %w", ErrorInvalidProviders, item.Name, err)
+			}
+			proxyConfig.Breaker.CoolDown = coolDown
+		}
+		if item.RateLimit != 0 {
+			proxyConfig.RateLimit = item.RateLimit
+		}
+		if item.RateBurst != 0 {
+			proxyConfig.RateBurst = item.RateBurst
+		}
+		cnfs = append(cnfs, ProviderConfig{
+			Name:     item.Name,
+			Prefixes: item.Prefixes,
+			Proxy:    proxyConfig,
+		})
+	}
+	return cnfs, nil
+}
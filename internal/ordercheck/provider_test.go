@@ -0,0 +1,206 @@
+package ordercheck
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/accrual"
+	"gofemart/internal/models"
+	"gofemart/internal/ordercheck/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRegistry реестр с единственным поставщиком по умолчанию
+func newTestRegistry(t *testing.T, accrualProxy Accrual) *Registry {
+	registry, err := NewRegistry(context.Background(), []ProviderConfig{{Name: DefaultProviderName, Accrual: accrualProxy}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestRegistryFor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry, err := NewRegistry(context.Background(), []ProviderConfig{
+		{Name: DefaultProviderName, Accrual: mock.NewMockAccrual(ctrl)},
+		{Name: "partner", Prefixes: []string{"77"}, Accrual: mock.NewMockAccrual(ctrl)},
+		{Name: "partner_vip", Prefixes: []string{"777"}, Accrual: mock.NewMockAccrual(ctrl)},
+		{Name: "merchant", Match: func(order *models.Order) bool { return strings.HasSuffix(order.Number, "00") }, Accrual: mock.NewMockAccrual(ctrl)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		number string
+		want   string
+	}{
+		{number: "12345678903", want: DefaultProviderName},
+		{number: "7712345", want: "partner"},
+		{number: "7771234", want: "partner_vip"},
+		{number: "7771200", want: "merchant"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.number, func(t *testing.T) {
+			provider, err := registry.For(&models.Order{Number: tc.number})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if provider.Name != tc.want {
+				t.Errorf("expected provider %s, got %s", tc.want, provider.Name)
+			}
+		})
+	}
+}
+
+func TestRegistryWithoutDefault(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry, err := NewRegistry(context.Background(), []ProviderConfig{
+		{Name: "partner", Prefixes: []string{"77"}, Accrual: mock.NewMockAccrual(ctrl)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.For(&models.Order{Number: "1"}); !errors.Is(err, ErrorNoAccrualProvider) {
+		t.Errorf("expected error %v, got %v", ErrorNoAccrualProvider, err)
+	}
+}
+
+func TestNewRegistryInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	testCases := []struct {
+		name string
+		cnfs []ProviderConfig
+	}{
+		{name: "empty"},
+		{name: "no_name", cnfs: []ProviderConfig{{Accrual: mock.NewMockAccrual(ctrl)}}},
+		{name: "duplicate_name", cnfs: []ProviderConfig{
+			{Name: "a", Prefixes: []string{"1"}, Accrual: mock.NewMockAccrual(ctrl)},
+			{Name: "a", Prefixes: []string{"2"}, Accrual: mock.NewMockAccrual(ctrl)},
+		}},
+		{name: "duplicate_prefix", cnfs: []ProviderConfig{
+			{Name: "a", Prefixes: []string{"1"}, Accrual: mock.NewMockAccrual(ctrl)},
+			{Name: "b", Prefixes: []string{"1"}, Accrual: mock.NewMockAccrual(ctrl)},
+		}},
+		{name: "two_defaults", cnfs: []ProviderConfig{
+			{Name: "a", Accrual: mock.NewMockAccrual(ctrl)},
+			{Name: "b", Accrual: mock.NewMockAccrual(ctrl)},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewRegistry(context.Background(), tc.cnfs); !errors.Is(err, ErrorInvalidProviders) {
+				t.Errorf("expected error %v, got %v", ErrorInvalidProviders, err)
+			}
+		})
+	}
+}
+
+func TestRegistryBreakerOpenFor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	first := mock.NewMockAccrual(ctrl)
+	second := mock.NewMockAccrual(ctrl)
+	registry, err := NewRegistry(context.Background(), []ProviderConfig{
+		{Name: "first", Accrual: first},
+		{Name: "second", Prefixes: []string{"7"}, Accrual: second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.EXPECT().BreakerOpenFor().Return(time.Minute)
+	second.EXPECT().BreakerOpenFor().Return(time.Duration(0))
+	if got := registry.BreakerOpenFor(); got != 0 {
+		t.Errorf("expected queue to work while one provider is available, got %v", got)
+	}
+
+	first.EXPECT().BreakerOpenFor().Return(time.Minute)
+	second.EXPECT().BreakerOpenFor().Return(time.Second)
+	if got := registry.BreakerOpenFor(); got != time.Second {
+		t.Errorf("expected %v, got %v", time.Second, got)
+	}
+}
+
+func TestParseProviderConfigs(t *testing.T) {
+	defaults := accrual.ProxyConfig{
+		Pause:     time.Minute,
+		Breaker:   accrual.BreakerConfig{FailureThreshold: 5, CoolDown: 30 * time.Second},
+		RateLimit: 10,
+		RateBurst: 1,
+	}
+	cnfs, err := ParseProviderConfigs(`[
+		{"name": "partner", "url": "http://partner:8080", "prefixes": ["77"], "pause": "5s", "rateLimit": 2},
+		{"name": "other", "url": "http://other:8080", "prefixes": ["88"], "breakerThreshold": 3, "breakerCoolDown": "1m", "rateBurst": 4}
+	]`, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cnfs) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(cnfs))
+	}
+	partner := cnfs[0].Proxy
+	if cnfs[0].Name != "partner" || partner.AccrualURL != "http://partner:8080" || partner.Pause != 5*time.Second || partner.RateLimit != 2 || partner.Breaker != defaults.Breaker {
+		t.Errorf("unexpected partner config %+v", cnfs[0])
+	}
+	other := cnfs[1].Proxy
+	if other.Pause != time.Minute || other.RateBurst != 4 || other.Breaker.FailureThreshold != 3 || other.Breaker.CoolDown != time.Minute {
+		t.Errorf("unexpected other config %+v", cnfs[1])
+	}
+
+	if cnfs, err := ParseProviderConfigs("", defaults); err != nil || cnfs != nil {
+		t.Errorf("expected no providers, got %v, %v", cnfs, err)
+	}
+	for _, value := range []string{`{`, `[{"name": "a"}]`, `[{"name": "a", "url": "http://a", "pause": "soon"}]`} {
+		if _, err := ParseProviderConfigs(value, defaults); !errors.Is(err, ErrorInvalidProviders) {
+			t.Errorf("expected error %v for %s, got %v", ErrorInvalidProviders, value, err)
+		}
+	}
+}
+
+func TestWithDefaultProvider(t *testing.T) {
+	cnf := PoolConfig{
+		AccrualURL: "http://accrual:8080",
+		Pause:      time.Minute,
+		Providers:  []ProviderConfig{{Name: "partner", Prefixes: []string{"77"}}},
+	}
+	providers := withDefaultProvider(cnf)
+	if len(providers) != 2 || providers[0].Name != DefaultProviderName || providers[0].Proxy.AccrualURL != cnf.AccrualURL {
+		t.Errorf("expected default provider from pool config, got %+v", providers)
+	}
+
+	cnf.Providers = append(cnf.Providers, ProviderConfig{Name: "main"})
+	if providers := withDefaultProvider(cnf); len(providers) != 2 || providers[1].Name != "main" {
+		t.Errorf("expected configured default provider, got %+v", providers)
+	}
+}
+
+func TestProcessOrderUsesSelectedProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	primary := mock.NewMockAccrual(ctrl)
+	partner := mock.NewMockAccrual(ctrl)
+	registry, err := NewRegistry(context.Background(), []ProviderConfig{
+		{Name: DefaultProviderName, Accrual: primary},
+		{Name: "partner", Prefixes: []string{"77"}, Accrual: partner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Пауза от партнёра не затрагивает основного поставщика
+	partner.EXPECT().
+		Accrual(gomock.Any(), gomock.Any()).
+		Return(nil, &accrual.TooManyRequestError{InternalError: accrual.ErrorTooManyRequests, PauseDuration: time.Minute})
+	partner.EXPECT().Pause(time.Minute)
+	repo := mock.NewMockoRepo(ctrl)
+	repo.EXPECT().ClaimOrder("771", "instance", gomock.Any(), gomock.Any()).DoAndReturn(claimNew)
+	repo.EXPECT().ReleaseOrder("771", "instance").Return(nil)
+	p := &Pool{
+		orderMap:      map[string]*WorkedOrder{"771": {model: &models.Order{Number: "771"}}},
+		ctx:           context.Background(),
+		orderRepo:     repo,
+		instanceID:    "instance",
+		leaseDuration: time.Minute,
+		providers:     registry,
+	}
+	p.processOder("771")
+}
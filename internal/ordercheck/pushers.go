@@ -1,28 +1,36 @@
 package ordercheck
 
 import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
 	"gofemart/internal/logger"
 	"gofemart/internal/models"
+	"gofemart/internal/tracing"
+	"time"
 )
 
 // Push добавляет заказ в очередь пула,
 // если очередь не заполнена и пул не закрыт, возвращая статус успешного выполнения и ошибку.
-func (p *Pool) Push(order *models.Order) (bool, error) {
+// Проверка заказа продолжает трассировку из ctx.
+func (p *Pool) Push(ctx context.Context, order *models.Order) (bool, error) {
 	logger.Log.Infow("Push order to pool", "order", order.Number)
 	if p.closeFlag.Load() {
 		return false, ErrorPoolClosed
 	}
+	_, span := tracing.Start(ctx, "checkpool.push", order.Number)
+	defer span.End()
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 	lenChanel := len(p.inChanel)
 	capChanel := cap(p.inChanel)
 	if lenChanel < capChanel {
 		logger.Log.Infow("Push order to queue", "order", order.Number, "len", lenChanel, "cap", capChanel)
-		p.orderMap[order.Number] = &WorkedOrder{model: order}
+		p.orderMap[order.Number] = &WorkedOrder{model: order, spanContext: span.SpanContext()}
 		p.inChanel <- order.Number
 		return true, nil
 	}
 	logger.Log.Infow("Order not pushed to queue", "order", order.Number, "len", lenChanel, "cap", capChanel)
+	span.SetAttributes(attribute.Bool("checkpool.queue_full", true))
 	return false, nil
 }
 
@@ -30,6 +38,17 @@ func (p *Pool) Push(order *models.Order) (bool, error) {
 func (p *Pool) pushFromQueue() {
 	defer p.wg.Done()
 	for {
+		// Пока выключатели запросов открыты у всех поставщиков начислений, заказы из очереди не берём
+		if openFor := p.providers.BreakerOpenFor(); openFor > 0 {
+			logger.Log.Infow("Accrual circuit breaker is open. Push from queue paused", "duration", openFor)
+			select {
+			case <-p.ctx.Done():
+				logger.Log.Info("Pool context closed. Push from queue stopped")
+				return
+			case <-time.After(openFor):
+			}
+			continue
+		}
 		select {
 		case <-p.ctx.Done():
 			logger.Log.Info("Pool context closed. Push from queue stopped")
@@ -39,9 +58,38 @@ func (p *Pool) pushFromQueue() {
 			if !ok {
 				return
 			}
+			if p.batchSize > 1 {
+				p.processBatch(p.collectBatch(number))
+				continue
+			}
 			if !p.checkInWork(number) {
 				p.processOder(number)
 			}
 		}
 	}
 }
+
+// collectBatch добираем из очереди без ожидания заказы к уже полученному, пока не наберётся batchSize
+func (p *Pool) collectBatch(number string) []string {
+	numbers := make([]string, 0, p.batchSize)
+	seen := make(map[string]struct{}, p.batchSize)
+	if !p.checkInWork(number) {
+		numbers = append(numbers, number)
+		seen[number] = struct{}{}
+	}
+	for len(numbers) < p.batchSize {
+		select {
+		case next, ok := <-p.inChanel:
+			if !ok {
+				return numbers
+			}
+			if _, ok := seen[next]; !ok && !p.checkInWork(next) {
+				numbers = append(numbers, next)
+				seen[next] = struct{}{}
+			}
+		default:
+			return numbers
+		}
+	}
+	return numbers
+}
@@ -3,8 +3,11 @@ package ordercheck
 import (
 	"context"
 	"errors"
+	"github.com/golang/mock/gomock"
 	"gofemart/internal/models"
+	"gofemart/internal/ordercheck/mock"
 	"testing"
+	"time"
 )
 
 func TestPush(t *testing.T) {
@@ -53,7 +56,7 @@ func TestPush(t *testing.T) {
 				ctx:      context.Background(),
 			}
 			tt.setup(p)
-			got, err := p.Push(tt.order)
+			got, err := p.Push(context.Background(), tt.order)
 
 			if (err != nil) != (tt.wantErr != nil) {
 				t.Errorf("Error is not expected. error = %v, wantErr %v", err, tt.wantErr)
@@ -71,3 +74,70 @@ func TestPush(t *testing.T) {
 		})
 	}
 }
+
+func TestPushFromQueueWaitsWhileBreakerOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	proxy := mock.NewMockAccrual(ctrl)
+	proxy.EXPECT().BreakerOpenFor().Return(time.Hour).MinTimes(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		orderMap:  make(map[string]*WorkedOrder),
+		inChanel:  make(chan string, 1),
+		ctx:       ctx,
+		providers: newTestRegistry(t, proxy),
+	}
+	p.inChanel <- "1"
+	p.wg.Add(1)
+	go p.pushFromQueue()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	p.wg.Wait()
+	if len(p.inChanel) != 1 {
+		t.Errorf("expected order to stay in queue while breaker is open")
+	}
+}
+
+func TestCollectBatch(t *testing.T) {
+	p := &Pool{
+		orderMap: map[string]*WorkedOrder{
+			"1": {model: &models.Order{Number: "1"}},
+			"2": {model: &models.Order{Number: "2"}, inWork: true},
+			"3": {model: &models.Order{Number: "3"}},
+			"4": {model: &models.Order{Number: "4"}},
+			"5": {model: &models.Order{Number: "5"}},
+		},
+		inChanel:  make(chan string, 5),
+		batchSize: 3,
+	}
+	for _, number := range []string{"2", "3", "4", "5"} {
+		p.inChanel <- number
+	}
+	got := p.collectBatch("1")
+	if len(got) != 3 || got[0] != "1" || got[1] != "3" || got[2] != "4" {
+		t.Errorf("expected batch [1 3 4], got %v", got)
+	}
+	if len(p.inChanel) != 1 {
+		t.Errorf("expected one order to stay in queue, got %d", len(p.inChanel))
+	}
+	// Неполный пакет не ждёт новых заказов, повторный номер в пакет не попадает
+	if got := p.collectBatch("5"); len(got) != 1 || got[0] != "5" {
+		t.Errorf("expected batch [5], got %v", got)
+	}
+}
+
+func TestPoolRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{ctx: ctx}
+	if !p.Running() {
+		t.Error("expected pool to be running")
+	}
+	cancel()
+	if p.Running() {
+		t.Error("expected pool with canceled context not to be running")
+	}
+	p = &Pool{ctx: context.Background()}
+	p.closeFlag.Store(true)
+	if p.Running() {
+		t.Error("expected closed pool not to be running")
+	}
+}
@@ -0,0 +1,53 @@
+package ordercheck
+
+import (
+	"errors"
+	"gofemart/internal/accrual"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff параметры экспоненциальной задержки повторной проверки заказа
+type Backoff struct {
+	Base time.Duration // задержка после первой неудачной проверки
+	Max  time.Duration // максимальная задержка
+}
+
+var (
+	// notRegisteredBackoff заказ ещё не зарегистрирован в системе начислений, ждём дольше
+	notRegisteredBackoff = Backoff{Base: 30 * time.Second, Max: time.Hour}
+	// internalErrorBackoff система начислений временно не работает, пробуем скоро
+	internalErrorBackoff = Backoff{Base: 5 * time.Second, Max: 10 * time.Minute}
+	// defaultBackoff остальные ошибки: сетевые, непредвиденные ответы
+	defaultBackoff = Backoff{Base: 10 * time.Second, Max: 30 * time.Minute}
+)
+
+// Delay задержка перед следующей проверкой после attempt неудачных проверок подряд.
+// Задержка удваивается с каждой попыткой до Max, а затем выбирается случайно от половины до полной,
+// чтобы заказы, упавшие одновременно, не проверялись тоже одновременно.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(delay-half+1)
+}
+
+// backoffFor выбираем задержку повторной проверки по типу ошибки
+func backoffFor(err error) Backoff {
+	switch {
+	case errors.Is(err, accrual.ErrorOrderNotRegistered):
+		return notRegisteredBackoff
+	case errors.Is(err, accrual.ErrorInternalAccrual):
+		return internalErrorBackoff
+	default:
+		return defaultBackoff
+	}
+}
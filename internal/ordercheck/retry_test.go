@@ -0,0 +1,67 @@
+package ordercheck
+
+import (
+	"errors"
+	"fmt"
+	"gofemart/internal/accrual"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Base: time.Second, Max: 10 * time.Second}
+	tests := []struct {
+		attempt int
+		wantMax time.Duration
+	}{
+		{attempt: 1, wantMax: time.Second},
+		{attempt: 2, wantMax: 2 * time.Second},
+		{attempt: 3, wantMax: 4 * time.Second},
+		{attempt: 4, wantMax: 8 * time.Second},
+		{attempt: 5, wantMax: 10 * time.Second},
+		{attempt: 100, wantMax: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt_%d", tt.attempt), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := backoff.Delay(tt.attempt)
+				if got < tt.wantMax/2 || got > tt.wantMax {
+					t.Fatalf("Delay(%d) = %v, want between %v and %v", tt.attempt, got, tt.wantMax/2, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Backoff
+	}{
+		{
+			name: "not_registered",
+			err:  accrual.ErrorOrderNotRegistered,
+			want: notRegisteredBackoff,
+		},
+		{
+			name: "internal_error",
+			err:  fmt.Errorf("check: %w", accrual.ErrorInternalAccrual),
+			want: internalErrorBackoff,
+		},
+		{
+			name: "unknown_error",
+			err:  errors.New("connection refused"),
+			want: defaultBackoff,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backoffFor(tt.err); got != tt.want {
+				t.Errorf("backoffFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 50  // количество записей на странице, если limit не указан
+	MaxLimit     = 500 // максимальное количество записей на странице
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// ErrorInvalidCursor Ошибка, что курсор не удалось разобрать
+var ErrorInvalidCursor = errors.New("invalid cursor")
+
+// Cursor позиция последней выданной записи.
+// At время записи, Key уникальный ключ записи для записей с одинаковым временем.
+type Cursor struct {
+	At  time.Time `json:"a"`
+	Key string    `json:"k"`
+}
+
+// Encode кодируем курсор в непрозрачную для клиента строку
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбираем курсор, полученный от клиента
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Key == "" {
+		return nil, ErrorInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Query параметры выборки списка.
+// Paginated равен true, если клиент передал хотя бы один параметр,
+// иначе отдаётся весь список в прежнем формате.
+type Query struct {
+	Limit     int
+	After     *Cursor
+	Statuses  []string
+	From      time.Time
+	To        time.Time
+	Desc      bool
+	Paginated bool
+}
+
+// ParseQuery разбираем параметры выборки из строки запроса.
+// status может быть передан несколько раз или через запятую, from и to в формате RFC3339.
+func ParseQuery(values url.Values) (*Query, error) {
+	query := &Query{}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		query.Limit = limit
+		query.Paginated = true
+	}
+	if value := values.Get("cursor"); value != "" {
+		cursor, err := DecodeCursor(value)
+		if err != nil {
+			return nil, err
+		}
+		query.After = cursor
+		query.Paginated = true
+	}
+	for _, value := range values["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.ToUpper(strings.TrimSpace(status)); status != "" {
+				query.Statuses = append(query.Statuses, status)
+				query.Paginated = true
+			}
+		}
+	}
+	var err error
+	if query.From, err = parseTime(values, "from"); err != nil {
+		return nil, err
+	}
+	if query.To, err = parseTime(values, "to"); err != nil {
+		return nil, err
+	}
+	if !query.From.IsZero() || !query.To.IsZero() {
+		query.Paginated = true
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, errors.New("to must not be before from")
+	}
+	switch strings.ToLower(values.Get("sort")) {
+	case "":
+	case SortAsc:
+		query.Paginated = true
+	case SortDesc:
+		query.Desc = true
+		query.Paginated = true
+	default:
+		return nil, fmt.Errorf("sort must be %s or %s", SortAsc, SortDesc)
+	}
+	if query.Paginated && query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+	return query, nil
+}
+
+// parseTime разбираем время из параметра запроса.
+// Колонки с временем в базе данных хранятся без часового пояса в локальном времени приложения,
+// поэтому время приводится к локальному часовому поясу, чтобы сравнивалось с записями по тому же времени на часах.
+func parseTime(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be in RFC3339 format", name)
+	}
+	return t.In(time.Local), nil
+}
+
+// Page страница списка с курсором на следующую страницу.
+// NextCursor отсутствует, если страница последняя.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewPage формируем страницу из выборки, запрошенной с лимитом на одну запись больше.
+// cursorOf возвращает курсор записи, от которой будет продолжена выборка.
+func NewPage[T any](items []T, limit int, cursorOf func(item T) Cursor) Page[T] {
+	page := Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if limit > 0 && len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = cursorOf(page.Items[limit-1]).Encode()
+	}
+	return page
+}
@@ -0,0 +1,167 @@
+package pagination
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	cursor := Cursor{At: time.Date(2024, 9, 23, 18, 45, 6, 123456000, time.UTC), Key: "12345678903"}
+	tests := []struct {
+		name    string
+		query   string
+		want    *Query
+		wantErr bool
+	}{
+		{
+			name:  "without_params",
+			query: "",
+			want:  &Query{},
+		},
+		{
+			name:  "limit",
+			query: "limit=10",
+			want:  &Query{Limit: 10, Paginated: true},
+		},
+		{
+			name:  "default_limit",
+			query: "sort=desc",
+			want:  &Query{Limit: DefaultLimit, Desc: true, Paginated: true},
+		},
+		{
+			name:  "statuses",
+			query: "status=new,processing&status=INVALID",
+			want:  &Query{Limit: DefaultLimit, Statuses: []string{"NEW", "PROCESSING", "INVALID"}, Paginated: true},
+		},
+		{
+			name:  "date_range",
+			query: "from=2024-09-01T00:00:00Z&to=2024-09-30T00:00:00Z",
+			want: &Query{
+				Limit:     DefaultLimit,
+				From:      time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC).In(time.Local),
+				To:        time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC).In(time.Local),
+				Paginated: true,
+			},
+		},
+		{
+			name:  "date_range_with_offset",
+			query: "from=2024-09-01T03:00:00%2B03:00",
+			want: &Query{
+				Limit:     DefaultLimit,
+				From:      time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC).In(time.Local),
+				Paginated: true,
+			},
+		},
+		{
+			name:  "cursor",
+			query: "limit=5&cursor=" + cursor.Encode(),
+			want:  &Query{Limit: 5, After: &cursor, Paginated: true},
+		},
+		{
+			name:    "limit_too_big",
+			query:   "limit=100000",
+			wantErr: true,
+		},
+		{
+			name:    "limit_not_number",
+			query:   "limit=ten",
+			wantErr: true,
+		},
+		{
+			name:    "bad_cursor",
+			query:   "cursor=not-a-cursor",
+			wantErr: true,
+		},
+		{
+			name:    "bad_sort",
+			query:   "sort=up",
+			wantErr: true,
+		},
+		{
+			name:    "bad_date",
+			query:   "from=yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "to_before_from",
+			query:   "from=2024-09-30T00:00:00Z&to=2024-09-01T00:00:00Z",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseQuery(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cursorOf := func(item int) Cursor {
+		return Cursor{At: time.Unix(int64(item), 0).UTC(), Key: "key"}
+	}
+	tests := []struct {
+		name       string
+		items      []int
+		limit      int
+		wantItems  []int
+		wantCursor bool
+	}{
+		{
+			name:      "empty",
+			items:     nil,
+			limit:     2,
+			wantItems: []int{},
+		},
+		{
+			name:      "last_page",
+			items:     []int{1, 2},
+			limit:     2,
+			wantItems: []int{1, 2},
+		},
+		{
+			name:       "has_next_page",
+			items:      []int{1, 2, 3},
+			limit:      2,
+			wantItems:  []int{1, 2},
+			wantCursor: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPage(tt.items, tt.limit, cursorOf)
+			if !reflect.DeepEqual(page.Items, tt.wantItems) {
+				t.Errorf("Items = %v, want %v", page.Items, tt.wantItems)
+			}
+			if (page.NextCursor != "") != tt.wantCursor {
+				t.Fatalf("NextCursor = %q, want cursor %v", page.NextCursor, tt.wantCursor)
+			}
+			if !tt.wantCursor {
+				return
+			}
+			cursor, err := DecodeCursor(page.NextCursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *cursor != cursorOf(tt.wantItems[len(tt.wantItems)-1]) {
+				t.Errorf("cursor = %+v, want cursor of last item", cursor)
+			}
+		})
+	}
+}
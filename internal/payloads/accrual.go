@@ -1,13 +1,21 @@
 package payloads
 
+import "gofemart/internal/models"
+
 // Accrual представляет собой структуру ответа системы по начислению.
 // Order — идентификатор заказа в системе.
 // Status указывает на текущий статус заказа в системе начисления.
 // Accrual представляет собой начисленное значение для заказа.
 type Accrual struct {
-	Order   string  `json:"order" valid:"required,type(string)"`
-	Status  string  `json:"status" valid:"required,type(string)"`
-	Accrual float64 `json:"accrual" valid:"required,type(float64)"`
+	Order   string       `json:"order" valid:"required,type(string)"`
+	Status  string       `json:"status" valid:"required,type(string)"`
+	Accrual models.Money `json:"accrual" valid:"required,type(models.Money)" swaggertype:"number"`
+}
+
+// AccrualBatchRequest представляет собой запрос статусов нескольких заказов в системе начисления.
+// Ответ содержит массив Accrual, незарегистрированные заказы в него не попадают.
+type AccrualBatchRequest struct {
+	Orders []string `json:"orders"`
 }
 
 const (
@@ -0,0 +1,18 @@
+package payloads
+
+// RequeueOrders запрос на возврат остановленных заказов в очередь проверки.
+type RequeueOrders struct {
+	Numbers []string `json:"numbers" valid:"required"`
+}
+
+// RequeueResult результат возврата заказов в очередь проверки.
+// Requeued — номера, возвращённые в очередь, Skipped — номера, которые не найдены среди остановленных заказов.
+type RequeueResult struct {
+	Requeued []string `json:"requeued"`
+	Skipped  []string `json:"skipped"`
+}
+
+// UnlockUser запрос на снятие блокировки входа пользователя.
+type UnlockUser struct {
+	Login string `json:"login" valid:"required"`
+}
@@ -0,0 +1,15 @@
+package payloads
+
+// Результаты загрузки номера заказа в пакете
+const (
+	BatchOrderAccepted        = "accepted"         // Номер принят в обработку
+	BatchOrderAlreadyUploaded = "already_uploaded" // Номер уже был загружен этим пользователем
+	BatchOrderAnotherUser     = "owned_by_another" // Номер уже был загружен другим пользователем
+	BatchOrderInvalidNumber   = "invalid_luhn"     // Номер не прошёл проверку алгоритмом Луна
+)
+
+// BatchOrderResult результат загрузки одного номера заказа из пакета.
+type BatchOrderResult struct {
+	Number string `json:"number"`
+	Result string `json:"result"`
+}
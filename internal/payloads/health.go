@@ -0,0 +1,21 @@
+package payloads
+
+// Статусы проверок готовности
+const (
+	HealthStatusOK   = "ok"   // проверка прошла
+	HealthStatusFail = "fail" // проверка не прошла, приложение не готово
+	HealthStatusWarn = "warn" // необязательная проверка не прошла, на готовность не влияет
+)
+
+// HealthCheck результат одной проверки готовности.
+type HealthCheck struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Health результат проверки живости или готовности приложения с разбивкой по проверкам.
+type Health struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
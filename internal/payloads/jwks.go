@@ -0,0 +1,20 @@
+package payloads
+
+// JWK публичный ключ проверки токенов в формате JSON Web Key (RFC 7517).
+// Для RSA заполняются n и e, для EC — crv, x и y, для Ed25519 — crv и x.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS набор публичных ключей проверки токенов.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -1,9 +1,11 @@
 package payloads
 
+import "gofemart/internal/models"
+
 // Withdraw представляет собой запрос на вывод средств с номером заказа и суммой.
 // OrderNumber — обязательное строковое поле, представляющее уникальный идентификатор заказа.
-// Sum — обязательное поле float64, представляющее сумму для вывода.
+// Sum — обязательное положительное поле, представляющее сумму для вывода.
 type Withdraw struct {
-	OrderNumber string  `json:"order" valid:"required,type(string)"`
-	Sum         float64 `json:"sum" valid:"required,type(float64)"`
+	OrderNumber string       `json:"order" valid:"required,type(string)"`
+	Sum         models.Money `json:"sum" valid:"required,type(models.Money)" swaggertype:"number"`
 }
@@ -6,7 +6,32 @@ type Register struct {
 	Password string `json:"password" valid:"required,type(string),minstringlength(6)"`
 }
 
-// Authorization ответ с токеном авторизации
+// Authorization ответ с токеном авторизации.
+// Token — короткоживущий токен доступа, RefreshToken — одноразовый токен для получения новой пары токенов.
 type Authorization struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // время жизни токена доступа в секундах
+}
+
+// RefreshToken запрос на обновление токенов
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" valid:"required"`
+}
+
+// ChangePassword запрос на смену пароля
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" valid:"required,type(string)"`
+	NewPassword     string `json:"new_password" valid:"required,type(string),minstringlength(6)"`
+}
+
+// PasswordResetRequest запрос на отправку токена сброса пароля
+type PasswordResetRequest struct {
+	Login string `json:"login" valid:"required,type(string)"`
+}
+
+// PasswordReset запрос на сброс пароля по токену
+type PasswordReset struct {
+	Token       string `json:"token" valid:"required,type(string)"`
+	NewPassword string `json:"new_password" valid:"required,type(string),minstringlength(6)"`
 }
@@ -11,20 +11,21 @@ import (
 // Использует контекст для управления запросами и пул соединений с БД.
 type AccountRepository struct {
 	// db пул соединений с базой данных, которыми может пользоваться хранилище
-	db SQLExecutor
+	db SQLQueryer
 	// storeCtx контекст, который отвечает за запросы
 	ctx context.Context
 }
 
-// NewAccountRepository creates a new instance of AccountRepository with the provided context and SQLExecutor.
-func NewAccountRepository(ctx context.Context, db SQLExecutor) *AccountRepository { // TODO заменить на интерфейс
+// NewAccountRepository creates a new instance of AccountRepository with the provided context and SQLQueryer.
+func NewAccountRepository(ctx context.Context, db SQLQueryer) *AccountRepository { // TODO заменить на интерфейс
 	return &AccountRepository{
 		ctx: ctx,
 		db:  db,
 	}
 }
 
-// CreateAccount вставляем новую транзакцию на счёт
+// CreateAccount вставляем новую транзакцию на счёт.
+// Баланс пользователя в t_balance обновляется тем же запросом, поэтому всегда меняется атомарно вместе с t_account.
 func (r *AccountRepository) CreateAccount(account *models.Account) error {
 	smth, err := r.db.PrepareNamed(createAccountSQL)
 	if err != nil {
@@ -35,32 +36,70 @@ func (r *AccountRepository) CreateAccount(account *models.Account) error {
 }
 
 // GetSum Получаем текущий баланс пользователя
-func (r *AccountRepository) GetSum(userID int64) (float64, error) {
-	var sum float64
+func (r *AccountRepository) GetSum(userID int64) (models.Money, error) {
+	var sum models.Money
 	row := r.db.QueryRowContext(r.ctx, getSumSQL, userID)
 	if row.Err() != nil {
-		return 0, row.Err()
+		return models.Money{}, row.Err()
 	}
 	err := row.Scan(&sum)
 	if err != nil {
-		return 0, err
+		return models.Money{}, err
 	}
 	return sum, nil
 }
 
+// LockUserBalance блокирует изменение баланса пользователя до конца текущей транзакции.
+// Блокировка строки пользователя в базе данных действует для всех экземпляров приложения,
+// поэтому параллельные списания одного пользователя выполняются строго последовательно.
+// Имеет смысл только для репозитория, созданного внутри транзакции.
+func (r *AccountRepository) LockUserBalance(userID int64) error {
+	var id int64
+	err := r.db.QueryRowContext(r.ctx, lockUserBalanceSQL, userID).Scan(&id)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return ErrorNotExists
+	}
+	return err
+}
+
 // GetBalance рассчитывает и возвращает текущий и снятый баланс для данного пользователя.
-func (r *AccountRepository) GetBalance(userID int64) (*models.Balance, error) { // TODO транзакция для того, чтобы зафиксировать состояние таблицы
+// Если пользователь ещё не совершал транзакций, возвращается нулевой баланс.
+func (r *AccountRepository) GetBalance(userID int64) (*models.Balance, error) {
 	balance := &models.Balance{}
 	row := r.db.QueryRowxContext(r.ctx, getBalanceSQL, userID)
 	if row.Err() != nil {
 		return nil, row.Err()
 	}
-	if err := row.StructScan(balance); err != nil {
+	err := row.StructScan(balance)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return balance, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 	return balance, nil
 }
 
+// GetBalanceMismatches возвращает пользователей, у которых сохранённый баланс не совпадает с рассчитанным по транзакциям.
+func (r *AccountRepository) GetBalanceMismatches() ([]models.BalanceMismatch, error) {
+	var mismatches []models.BalanceMismatch
+	err := r.db.SelectContext(r.ctx, &mismatches, getBalanceMismatchesSQL)
+	return mismatches, err
+}
+
+// RebuildBalance пересчитывает сохранённый баланс пользователя по его транзакциям.
+// Должен вызываться внутри транзакции: строка баланса блокируется, чтобы пересчёт
+// учитывал все транзакции, зафиксированные параллельными запросами до получения блокировки.
+func (r *AccountRepository) RebuildBalance(userID int64) error {
+	var id int64
+	err := r.db.QueryRowContext(r.ctx, lockBalanceSQL, userID).Scan(&id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	_, err = r.db.ExecContext(r.ctx, rebuildBalanceSQL, userID)
+	return err
+}
+
 // GetWithdrawByOrder извлекает запись о снятии средств по номеру заказа.
 func (r *AccountRepository) GetWithdrawByOrder(orderNumber string) (*models.Account, bool, error) {
 	account := &models.Account{}
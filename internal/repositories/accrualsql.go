@@ -1,8 +1,29 @@
 package repositories
 
 const (
-	createAccountSQL      = "INSERT INTO t_account (user_id, difference, order_number, created_at, updated_at) VALUES (:user_id, :difference, :order_number, :created_at, :updated_at) RETURNING id"
-	getSumSQL             = "SELECT COALESCE(SUM(difference), 0) FROM t_account WHERE user_id = $1"
-	getBalanceSQL         = "SELECT COALESCE(sum(difference), 0) current, COALESCE(sum(CASE WHEN difference < 0 THEN abs(difference) ELSE 0 END), 0) withdrawn FROM t_account WHERE user_id = $1"
+	// createAccountSQL вставляет транзакцию и в том же запросе обновляет баланс пользователя в t_balance
+	createAccountSQL = `WITH account AS (
+		INSERT INTO t_account (user_id, difference, order_number, created_at, updated_at) VALUES (:user_id, :difference, :order_number, :created_at, :updated_at) RETURNING id, user_id, difference
+	), balance AS (
+		INSERT INTO t_balance (user_id, current, withdrawn, version, updated_at)
+		SELECT user_id, difference, CASE WHEN difference < 0 THEN abs(difference) ELSE 0 END, 1, now() FROM account
+		ON CONFLICT (user_id) DO UPDATE SET current = t_balance.current + excluded.current, withdrawn = t_balance.withdrawn + excluded.withdrawn, version = t_balance.version + 1, updated_at = excluded.updated_at
+	)
+	SELECT id FROM account`
+	getSumSQL             = "SELECT COALESCE((SELECT current FROM t_balance WHERE user_id = $1), 0)"
+	getBalanceSQL         = "SELECT current, withdrawn FROM t_balance WHERE user_id = $1"
 	getWithdrawByOrderSQL = "SELECT * FROM t_account WHERE order_number = $1 AND difference < 0"
+	lockUserBalanceSQL    = "SELECT id FROM t_user WHERE id = $1 FOR UPDATE"
+	lockBalanceSQL        = "SELECT user_id FROM t_balance WHERE user_id = $1 FOR UPDATE"
+	// getBalanceMismatchesSQL сравнивает t_balance с суммами, рассчитанными по t_account
+	getBalanceMismatchesSQL = `SELECT COALESCE(l.user_id, b.user_id) user_id,
+		COALESCE(b.current, 0) current, COALESCE(b.withdrawn, 0) withdrawn,
+		COALESCE(l.current, 0) ledger_current, COALESCE(l.withdrawn, 0) ledger_withdrawn
+	FROM (SELECT user_id, sum(difference) current, sum(CASE WHEN difference < 0 THEN abs(difference) ELSE 0 END) withdrawn FROM t_account GROUP BY user_id) l
+	FULL JOIN t_balance b ON b.user_id = l.user_id
+	WHERE COALESCE(l.current, 0) <> COALESCE(b.current, 0) OR COALESCE(l.withdrawn, 0) <> COALESCE(b.withdrawn, 0)`
+	// rebuildBalanceSQL пересчитывает баланс пользователя по t_account
+	rebuildBalanceSQL = `INSERT INTO t_balance (user_id, current, withdrawn, version, updated_at)
+	SELECT $1::bigint, COALESCE(sum(difference), 0), COALESCE(sum(CASE WHEN difference < 0 THEN abs(difference) ELSE 0 END), 0), 1, now() FROM t_account WHERE user_id = $1::bigint
+	ON CONFLICT (user_id) DO UPDATE SET current = excluded.current, withdrawn = excluded.withdrawn, version = t_balance.version + 1, updated_at = excluded.updated_at`
 )
@@ -3,3 +3,6 @@ package repositories
 import "errors"
 
 var ErrorNotExists = errors.New("not exists")
+
+// ErrorLeaseLost Ошибка, что заказ больше не закреплён за экземпляром приложения, например аренда истекла и заказ забрал другой экземпляр
+var ErrorLeaseLost = errors.New("order lease lost")
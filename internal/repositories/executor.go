@@ -6,12 +6,20 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-// SQLExecutor интерфейс с нужными функциями из sqlx.DB
-type SQLExecutor interface {
+// SQLQueryer интерфейс с функциями выполнения запросов, общими для sqlx.DB и sqlx.Tx.
+// Репозитории работают через него, поэтому могут использоваться как с пулом, так и внутри транзакции.
+type SQLQueryer interface {
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	PrepareNamed(query string) (*sqlx.NamedStmt, error)
 	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
 	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 	Rebind(query string) string
 }
+
+// SQLExecutor интерфейс с нужными функциями из sqlx.DB
+type SQLExecutor interface {
+	SQLQueryer
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gofemart/internal/models"
+	"time"
+)
+
+// IdempotencyRepository представляет собой хранилище ключей идемпотентности и сохранённых ответов.
+type IdempotencyRepository struct {
+	// db пул соединений с базой данных, которыми может пользоваться хранилище
+	db SQLQueryer
+	// storeCtx контекст, который отвечает за запросы
+	ctx context.Context
+}
+
+// NewIdempotencyRepository создаёт и возвращает новый экземпляр IdempotencyRepository.
+func NewIdempotencyRepository(ctx context.Context, db SQLQueryer) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		ctx: ctx,
+		db:  db,
+	}
+}
+
+// Reserve пытается занять ключ идемпотентности для нового запроса и присваивает ему id.
+// Ключ, срок действия которого истёк к key.CreatedAt, или ответ на который не сохранён до abandonedBefore,
+// занимается заново.
+// Возвращает false, если действующий ключ уже существует.
+func (r *IdempotencyRepository) Reserve(key *models.IdempotencyKey, abandonedBefore time.Time) (bool, error) {
+	err := r.db.QueryRowxContext(r.ctx, reserveIdempotencyKeySQL,
+		key.UserID, key.Key, key.RequestHash, key.CreatedAt, key.ExpiresAt, abandonedBefore).Scan(&key.ID)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetByKey извлекает ключ идемпотентности пользователя.
+// Возвращает ключ, логическое значение, если найден, и ошибку.
+func (r *IdempotencyRepository) GetByKey(userID int64, key string) (*models.IdempotencyKey, bool, error) {
+	var idempotencyKey models.IdempotencyKey
+	err := r.db.QueryRowxContext(r.ctx, getIdempotencyKeySQL, userID, key).StructScan(&idempotencyKey)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &idempotencyKey, true, nil
+}
+
+// SaveResponse сохраняем ответ на запрос с ключом идемпотентности
+func (r *IdempotencyRepository) SaveResponse(key *models.IdempotencyKey) error {
+	_, err := r.db.NamedExecContext(r.ctx, saveIdempotencyResponseSQL, key)
+	return err
+}
+
+// Delete удаляем ключ идемпотентности, чтобы запрос можно было выполнить повторно
+func (r *IdempotencyRepository) Delete(key *models.IdempotencyKey) error {
+	_, err := r.db.ExecContext(r.ctx, deleteIdempotencyKeySQL, key.ID, key.CreatedAt)
+	return err
+}
+
+// DeleteExpired удаляем ключи идемпотентности, срок действия которых истёк к now, и возвращаем их количество
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(r.ctx, deleteExpiredIdempotencyKeySQL, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,15 @@
+package repositories
+
+const (
+	// reserveIdempotencyKeySQL создаёт ключ, либо занимает заново ключ, срок действия которого истёк к $4,
+	// или брошенный ключ, ответ на который не сохранён до $6. Время задаёт приложение, как и при записи ключа.
+	reserveIdempotencyKeySQL = `INSERT INTO t_idempotency_key (user_id, idempotency_key, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id, idempotency_key) DO UPDATE SET request_hash = excluded.request_hash, response_status = NULL, response_body = NULL, created_at = excluded.created_at, expires_at = excluded.expires_at
+	WHERE t_idempotency_key.expires_at <= $4 OR (t_idempotency_key.response_status IS NULL AND t_idempotency_key.created_at <= $6)
+	RETURNING id`
+	getIdempotencyKeySQL = "SELECT * FROM t_idempotency_key WHERE user_id = $1 AND idempotency_key = $2"
+	// saveIdempotencyResponseSQL и deleteIdempotencyKeySQL меняют ключ, только если его не занял заново другой запрос
+	saveIdempotencyResponseSQL     = "UPDATE t_idempotency_key SET response_status = :response_status, response_body = :response_body WHERE id = :id AND created_at = :created_at"
+	deleteIdempotencyKeySQL        = "DELETE FROM t_idempotency_key WHERE id = $1 AND created_at = $2"
+	deleteExpiredIdempotencyKeySQL = "DELETE FROM t_idempotency_key WHERE expires_at <= $1"
+)
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+	"gofemart/internal/pagination"
+	"strings"
+)
+
+// listColumns колонки, по которым выполняется выборка списка.
+// timeColumn задаёт порядок записей, keyColumn делает его однозначным для записей с одинаковым временем.
+type listColumns struct {
+	timeColumn   string
+	keyColumn    string
+	statusColumn string
+}
+
+// buildListSQL дополняем запрос условиями фильтрации, курсором, сортировкой и лимитом из параметров выборки.
+// Запрос должен заканчиваться условием WHERE. Выбирается на одну запись больше лимита,
+// чтобы определить, есть ли следующая страница. cursorKey ключ записи из курсора, приведённый к типу keyColumn.
+func (c listColumns) buildListSQL(base string, args []interface{}, query *pagination.Query, cursorKey interface{}) (string, []interface{}, error) {
+	var sqlStr strings.Builder
+	sqlStr.WriteString(base)
+	if len(query.Statuses) > 0 && c.statusColumn != "" {
+		sqlStr.WriteString(" AND " + c.statusColumn + " IN (?)")
+		args = append(args, query.Statuses)
+	}
+	if !query.From.IsZero() {
+		sqlStr.WriteString(" AND " + c.timeColumn + " >= ?")
+		args = append(args, query.From)
+	}
+	if !query.To.IsZero() {
+		sqlStr.WriteString(" AND " + c.timeColumn + " <= ?")
+		args = append(args, query.To)
+	}
+	direction := "ASC"
+	comparison := ">"
+	if query.Desc {
+		direction = "DESC"
+		comparison = "<"
+	}
+	if query.After != nil {
+		sqlStr.WriteString(" AND (" + c.timeColumn + ", " + c.keyColumn + ") " + comparison + " (?, ?)")
+		args = append(args, query.After.At, cursorKey)
+	}
+	sqlStr.WriteString(" ORDER BY " + c.timeColumn + " " + direction + ", " + c.keyColumn + " " + direction)
+	if query.Limit > 0 {
+		sqlStr.WriteString(" LIMIT ?")
+		args = append(args, query.Limit+1)
+	}
+
+	return sqlx.In(sqlStr.String(), args...)
+}
@@ -6,19 +6,21 @@ import (
 	"errors"
 	"github.com/jmoiron/sqlx"
 	"gofemart/internal/models"
+	"gofemart/internal/pagination"
+	"strconv"
 	"time"
 )
 
 // OrderRepository представляет собой хранилище для работы с заказами в базе данных.
 type OrderRepository struct {
 	// db пул соединений с базой данных, которыми может пользоваться хранилище
-	db SQLExecutor
+	db SQLQueryer
 	// storeCtx контекст, который отвечает за запросы
 	ctx context.Context
 }
 
 // NewOrderRepository создаёт и возвращает новый экземпляр OrderRepository с предоставленным контекстом и интерфейсом выполнения SQL-запросов.
-func NewOrderRepository(ctx context.Context, db SQLExecutor) *OrderRepository { // TODO заменить на интерфейс
+func NewOrderRepository(ctx context.Context, db SQLQueryer) *OrderRepository { // TODO заменить на интерфейс
 	return &OrderRepository{
 		ctx: ctx,
 		db:  db,
@@ -31,25 +33,94 @@ func (r *OrderRepository) CreateOrder(order *models.Order) error {
 	return err
 }
 
-// UpdateOrder обновляем существующий заказ
+// CreateOrders вставляем пакет новых заказов пользователя со статусом StatusNew одним запросом.
+// Номера, которые уже есть в базе, пропускаются. Возвращает номера вставленных заказов.
+func (r *OrderRepository) CreateOrders(userID int64, numbers []string) ([]string, error) {
+	var created []string
+	err := r.db.SelectContext(r.ctx, &created, createOrdersSQL, numbers, userID, models.StatusNew)
+	return created, err
+}
+
+// GetOrdersByNumbers извлекает заказы по списку номеров.
+func (r *OrderRepository) GetOrdersByNumbers(numbers []string) ([]models.Order, error) {
+	if len(numbers) == 0 {
+		return []models.Order{}, nil
+	}
+	sqlStr, args, err := sqlx.In(getOrdersByNumbersSQL, numbers)
+	if err != nil {
+		return nil, err
+	}
+	var orders []models.Order
+	err = r.db.SelectContext(r.ctx, &orders, r.db.Rebind(sqlStr), args...)
+	return orders, err
+}
+
+// parkedListColumns колонки выборки списка остановленных заказов
+var parkedListColumns = listColumns{timeColumn: "parked_at", keyColumn: "number"}
+
+// GetParkedOrders извлекает заказы, проверки которых остановлены, вместе с последней ошибкой.
+// Заказы отсортированы по времени остановки, если в query задан лимит, выбирается на одну запись больше него.
+func (r *OrderRepository) GetParkedOrders(query *pagination.Query) ([]models.ParkedOrder, error) {
+	var cursorKey interface{}
+	if query.After != nil {
+		cursorKey = query.After.Key
+	}
+	sqlStr, args, err := parkedListColumns.buildListSQL(getParkedOrdersSQL, nil, query, cursorKey)
+	if err != nil {
+		return nil, err
+	}
+	var orders []models.ParkedOrder
+	err = r.db.SelectContext(r.ctx, &orders, r.db.Rebind(sqlStr), args...)
+	return orders, err
+}
+
+// RequeueParkedOrders возвращает остановленные заказы в статус StatusNew и сбрасывает счётчик неудачных проверок.
+// Заказы, которые не остановлены, не изменяются. Возвращает изменённые заказы.
+func (r *OrderRepository) RequeueParkedOrders(numbers []string) ([]models.Order, error) {
+	if len(numbers) == 0 {
+		return []models.Order{}, nil
+	}
+	sqlStr, args, err := sqlx.In(requeueParkedOrdersSQL, numbers)
+	if err != nil {
+		return nil, err
+	}
+	var orders []models.Order
+	err = r.db.SelectContext(r.ctx, &orders, r.db.Rebind(sqlStr), args...)
+	return orders, err
+}
+
+// UpdateOrder обновляем заказ, закреплённый за экземпляром приложения order.ClaimedBy.
+// Если заказ закреплён за другим экземпляром, возвращает ErrorLeaseLost.
 func (r *OrderRepository) UpdateOrder(order *models.Order) error {
 	order.UpdatedAt = time.Now()
-	_, err := r.db.NamedExecContext(r.ctx, updateOrderSQL, order)
-	return err
+	res, err := r.db.NamedExecContext(r.ctx, updateOrderSQL, order)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrorLeaseLost
+	}
+	return nil
 }
 
-// GetOrdersExcludeOrdersWhereStatusIn получаем заказы с определёнными статусами
-func (r *OrderRepository) GetOrdersExcludeOrdersWhereStatusIn(limit int, excludedNumbers []string, olderThen time.Time, statuses ...string) ([]models.Order, error) {
-	//wheres := make([]interface{}, 0, len(excludedNumbers)+len(statuses)+3)
-	wheres := make([]interface{}, 0, 5)
+// ClaimOrdersWhereStatusIn закрепляет за экземпляром приложения instance до leaseExpiresAt заказы с определёнными статусами, которые пора проверить.
+// Заказ пора проверить, если наступило время следующей проверки now,
+// а если оно не назначено, то если заказ не проверялся с olderThen. Остановленные заказы и заказы,
+// закреплённые за другим экземпляром с действующей арендой, не выбираются. Возвращает закреплённые заказы.
+func (r *OrderRepository) ClaimOrdersWhereStatusIn(limit int, excludedNumbers []string, instance string, now time.Time, olderThen time.Time, leaseExpiresAt time.Time, statuses ...string) ([]models.Order, error) {
+	wheres := make([]interface{}, 0, 9)
 	var sqlStr string
 	var err error
 	if len(excludedNumbers) > 0 {
-		wheres = append(wheres, statuses, excludedNumbers, olderThen, olderThen, limit)
-		sqlStr, wheres, err = sqlx.In(getOrdersExcludeOrdersWhereStatusInWithNumbersSQL, wheres...)
+		wheres = append(wheres, instance, leaseExpiresAt, statuses, excludedNumbers, now, now, olderThen, olderThen, limit)
+		sqlStr, wheres, err = sqlx.In(claimOrdersWhereStatusInWithNumbersSQL, wheres...)
 	} else {
-		wheres = append(wheres, statuses, olderThen, olderThen, limit)
-		sqlStr, wheres, err = sqlx.In(getOrdersExcludeOrdersWhereStatusInWithoutNumbersSQL, wheres...)
+		wheres = append(wheres, instance, leaseExpiresAt, statuses, now, now, olderThen, olderThen, limit)
+		sqlStr, wheres, err = sqlx.In(claimOrdersWhereStatusInWithoutNumbersSQL, wheres...)
 	}
 	if err != nil {
 		return []models.Order{}, err
@@ -62,6 +133,52 @@ func (r *OrderRepository) GetOrdersExcludeOrdersWhereStatusIn(limit int, exclude
 	return orders, err
 }
 
+// ClaimOrder закрепляет заказ за экземпляром приложения instance до leaseExpiresAt и возвращает его актуальное состояние.
+// Возвращает false, если заказ уже проверен, остановлен или закреплён за другим экземпляром и аренда ещё действует на момент now.
+func (r *OrderRepository) ClaimOrder(number string, instance string, now time.Time, leaseExpiresAt time.Time) (*models.Order, bool, error) {
+	var order models.Order
+	err := r.db.QueryRowxContext(r.ctx, claimOrderSQL, instance, leaseExpiresAt, number, now).StructScan(&order)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &order, true, nil
+}
+
+// RenewLeases продлевает до leaseExpiresAt аренду заказов, закреплённых за экземпляром приложения instance.
+func (r *OrderRepository) RenewLeases(numbers []string, instance string, leaseExpiresAt time.Time) error {
+	if len(numbers) == 0 {
+		return nil
+	}
+	sqlStr, args, err := sqlx.In(renewLeasesSQL, leaseExpiresAt, instance, numbers)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(r.ctx, r.db.Rebind(sqlStr), args...)
+	return err
+}
+
+// ReleaseOrder снимает закрепление заказа за экземпляром приложения instance.
+func (r *OrderRepository) ReleaseOrder(number string, instance string) error {
+	_, err := r.db.ExecContext(r.ctx, releaseOrderSQL, number, instance)
+	return err
+}
+
+// ReleaseOrders снимает закрепление заказов numbers за экземпляром приложения instance.
+func (r *OrderRepository) ReleaseOrders(numbers []string, instance string) error {
+	if len(numbers) == 0 {
+		return nil
+	}
+	sqlStr, args, err := sqlx.In(releaseOrdersSQL, instance, numbers)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(r.ctx, r.db.Rebind(sqlStr), args...)
+	return err
+}
+
 // GetOrderByNumber извлекает заказ из базы данных, используя предоставленный номер заказа.
 // Он возвращает заказ, логическое значение, указывающее, был ли заказ найден, и ошибку, если таковая произошла во время выполнения.
 func (r *OrderRepository) GetOrderByNumber(number string) (*models.Order, bool, error) {
@@ -76,16 +193,93 @@ func (r *OrderRepository) GetOrderByNumber(number string) (*models.Order, bool,
 	return &order, true, nil
 }
 
+// ordersListColumns колонки выборки списка заказов
+var ordersListColumns = listColumns{timeColumn: "t.created_at", keyColumn: "t.number", statusColumn: "t.status_code"}
+
+// withdrawListColumns колонки выборки списка списаний
+var withdrawListColumns = listColumns{timeColumn: "ta.created_at", keyColumn: "ta.id"}
+
 // GetOrdersByUserWithAccrual извлекает заказы вместе с их начислением для конкретного пользователя по его идентификатору пользователя.
-func (r *OrderRepository) GetOrdersByUserWithAccrual(userID int64) ([]models.OrderWithAccrual, error) {
+// Заказы отсортированы по времени загрузки, если в query задан лимит, выбирается на одну запись больше него.
+func (r *OrderRepository) GetOrdersByUserWithAccrual(userID int64, query *pagination.Query) ([]models.OrderWithAccrual, error) {
+	var cursorKey interface{}
+	if query.After != nil {
+		cursorKey = query.After.Key
+	}
+	sqlStr, args, err := ordersListColumns.buildListSQL(getOrdersByUserWithAccrualSQL, []interface{}{userID}, query, cursorKey)
+	if err != nil {
+		return nil, err
+	}
 	var orders []models.OrderWithAccrual
-	err := r.db.SelectContext(r.ctx, &orders, getOrdersByUserWithAccrualSQL, userID)
+	err = r.db.SelectContext(r.ctx, &orders, r.db.Rebind(sqlStr), args...)
 	return orders, err
 }
 
-// GetOrdersByUserWithdraw извлекает все записи о снятии средств для данного пользователя по его идентификатору.
-func (r *OrderRepository) GetOrdersByUserWithdraw(userID int64) ([]models.OrderWithdraw, error) {
+// GetOrdersByUserWithdraw извлекает записи о снятии средств для данного пользователя по его идентификатору.
+// Списания отсортированы по времени, если в query задан лимит, выбирается на одну запись больше него.
+func (r *OrderRepository) GetOrdersByUserWithdraw(userID int64, query *pagination.Query) ([]models.OrderWithdraw, error) {
+	var cursorKey interface{}
+	if query.After != nil {
+		id, err := strconv.ParseInt(query.After.Key, 10, 64)
+		if err != nil {
+			return nil, pagination.ErrorInvalidCursor
+		}
+		cursorKey = id
+	}
+	sqlStr, args, err := withdrawListColumns.buildListSQL(getOrdersByUserWithdrawSQL, []interface{}{userID}, query, cursorKey)
+	if err != nil {
+		return nil, err
+	}
 	var orders []models.OrderWithdraw
-	err := r.db.SelectContext(r.ctx, &orders, getOrdersByUserWithdrawSQL, userID)
+	err = r.db.SelectContext(r.ctx, &orders, r.db.Rebind(sqlStr), args...)
 	return orders, err
 }
+
+// GetOrderAccrual извлекает сумму начисления по заказу.
+// Возвращает nil, если начисления по заказу нет.
+func (r *OrderRepository) GetOrderAccrual(number string) (*models.Money, error) {
+	var accrual models.Money
+	err := r.db.QueryRowContext(r.ctx, getOrderAccrualSQL, number).Scan(&accrual)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &accrual, nil
+}
+
+// GetOrderWithdraw извлекает последнее списание пользователя в счёт заказа.
+// Он возвращает списание, логическое значение, указывающее, было ли оно найдено, и ошибку, если таковая произошла.
+func (r *OrderRepository) GetOrderWithdraw(number string, userID int64) (*models.OrderWithdraw, bool, error) {
+	var withdraw models.OrderWithdraw
+	err := r.db.QueryRowxContext(r.ctx, getOrderWithdrawSQL, number, userID).StructScan(&withdraw)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &withdraw, true, nil
+}
+
+// CreateStatusHistory добавляет запись в историю статусов заказа.
+// Повторные одинаковые ответы системы начислений без изменения статуса заказа не записываются.
+func (r *OrderRepository) CreateStatusHistory(history *models.OrderStatusHistory) error {
+	_, err := r.db.ExecContext(r.ctx, createOrderStatusHistorySQL,
+		history.OrderNumber,
+		history.OldStatus,
+		history.NewStatus,
+		history.AccrualStatus,
+		history.Accrual,
+		history.CreatedAt.Time,
+	)
+	return err
+}
+
+// GetStatusHistory извлекает историю статусов заказа в хронологическом порядке.
+func (r *OrderRepository) GetStatusHistory(number string) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	err := r.db.SelectContext(r.ctx, &history, getOrderStatusHistorySQL, number)
+	return history, err
+}
@@ -1,11 +1,29 @@
 package repositories
 
 const (
-	createOrderSQL                                       = "INSERT INTO t_order (number, user_id, status_code) VALUES (:number, :user_id, :status_code)"
-	updateOrderSQL                                       = "UPDATE t_order SET user_id = :user_id, status_code = :status_code, last_checked_at = :last_checked_at, updated_at = :updated_at WHERE number = :number"
-	getOrdersExcludeOrdersWhereStatusInWithNumbersSQL    = "SELECT * FROM t_order WHERE status_code IN (?) AND number NOT IN (?) AND ((last_checked_at NOTNULL AND last_checked_at <= ?) OR (last_checked_at IS NULL AND created_at <= ?)) LIMIT ?"
-	getOrdersExcludeOrdersWhereStatusInWithoutNumbersSQL = "SELECT * FROM t_order WHERE status_code IN (?) AND ((last_checked_at NOTNULL AND last_checked_at <= ?) OR (last_checked_at IS NULL AND created_at <= ?)) LIMIT ?"
-	getOrderByNumberSQL                                  = "SELECT * FROM t_order WHERE number = $1"
-	getOrdersByUserWithAccrualSQL                        = "SELECT t.*, CASE WHEN ta.difference NOTNULL THEN difference ELSE 0 END accrual FROM t_order t LEFT JOIN t_account ta ON t.number = ta.order_number AND ta.difference > 0 WHERE t.user_id = $1"
-	getOrdersByUserWithdrawSQL                           = "SELECT ta.order_number number, abs(ta.difference) accrual, ta.created_at processed_at FROM  public.t_account ta WHERE ta.user_id = $1 AND ta.difference < 0 AND ta.order_number NOTNULL"
+	createOrderSQL = "INSERT INTO t_order (number, user_id, status_code) VALUES (:number, :user_id, :status_code)"
+	// updateOrderSQL обновляет заказ, только если он всё ещё закреплён за экземпляром приложения, который его проверял
+	updateOrderSQL = "UPDATE t_order SET user_id = :user_id, status_code = :status_code, last_checked_at = :last_checked_at, next_check_at = :next_check_at, attempts = :attempts, parked_at = :parked_at, last_error = :last_error, updated_at = :updated_at WHERE number = :number AND claimed_by = :claimed_by"
+	// claimOrdersWhereStatusIn... закрепляют за экземпляром приложения заказы, которые пора проверить.
+	// Строки, заблокированные другим экземпляром, пропускаются, поэтому несколько экземпляров не выберут один заказ
+	claimOrdersWhereStatusInWithNumbersSQL    = "UPDATE t_order SET claimed_by = ?, lease_expires_at = ? WHERE number IN (SELECT number FROM t_order WHERE status_code IN (?) AND number NOT IN (?) AND parked_at IS NULL AND (claimed_by IS NULL OR lease_expires_at <= ?) AND ((next_check_at NOTNULL AND next_check_at <= ?) OR (next_check_at IS NULL AND ((last_checked_at NOTNULL AND last_checked_at <= ?) OR (last_checked_at IS NULL AND created_at <= ?)))) ORDER BY COALESCE(next_check_at, last_checked_at, created_at) LIMIT ? FOR UPDATE SKIP LOCKED) RETURNING *"
+	claimOrdersWhereStatusInWithoutNumbersSQL = "UPDATE t_order SET claimed_by = ?, lease_expires_at = ? WHERE number IN (SELECT number FROM t_order WHERE status_code IN (?) AND parked_at IS NULL AND (claimed_by IS NULL OR lease_expires_at <= ?) AND ((next_check_at NOTNULL AND next_check_at <= ?) OR (next_check_at IS NULL AND ((last_checked_at NOTNULL AND last_checked_at <= ?) OR (last_checked_at IS NULL AND created_at <= ?)))) ORDER BY COALESCE(next_check_at, last_checked_at, created_at) LIMIT ? FOR UPDATE SKIP LOCKED) RETURNING *"
+	claimOrderSQL                             = "UPDATE t_order SET claimed_by = $1::varchar, lease_expires_at = $2 WHERE number = $3 AND status_code IN ('NEW', 'PROCESSING') AND parked_at IS NULL AND (claimed_by IS NULL OR claimed_by = $1::varchar OR lease_expires_at <= $4) RETURNING *"
+	renewLeasesSQL                            = "UPDATE t_order SET lease_expires_at = ? WHERE claimed_by = ? AND number IN (?)"
+	releaseOrderSQL                           = "UPDATE t_order SET claimed_by = NULL, lease_expires_at = NULL WHERE number = $1 AND claimed_by = $2"
+	releaseOrdersSQL                          = "UPDATE t_order SET claimed_by = NULL, lease_expires_at = NULL WHERE claimed_by = ? AND number IN (?)"
+	getOrdersByNumbersSQL                     = "SELECT * FROM t_order WHERE number IN (?)"
+	createOrdersSQL                           = "INSERT INTO t_order (number, user_id, status_code) SELECT unnest($1::varchar[]), $2, $3 ON CONFLICT (number) DO NOTHING RETURNING number"
+	getParkedOrdersSQL                        = "SELECT number, user_id, attempts, COALESCE(last_error, '') last_error, parked_at, last_checked_at FROM t_order WHERE status_code = 'PARKED'"
+	requeueParkedOrdersSQL                    = "UPDATE t_order SET status_code = 'NEW', attempts = 0, next_check_at = NULL, parked_at = NULL, last_error = NULL, updated_at = now() WHERE status_code = 'PARKED' AND number IN (?) RETURNING *"
+	getOrderByNumberSQL                       = "SELECT * FROM t_order WHERE number = $1"
+	getOrdersByUserWithAccrualSQL             = "SELECT t.*, ta.difference accrual FROM t_order t LEFT JOIN t_account ta ON t.number = ta.order_number AND ta.difference > 0 WHERE t.user_id = ?"
+	// createOrderStatusHistorySQL добавляет запись в историю, только если изменился статус заказа или ответ системы начислений
+	createOrderStatusHistorySQL = `INSERT INTO t_order_status_history (order_number, old_status, new_status, accrual_status, accrual, created_at)
+	SELECT $1::varchar, $2::varchar, $3::varchar, $4::varchar, $5::numeric, $6::timestamp
+	WHERE $2::varchar <> $3::varchar OR $4::varchar IS DISTINCT FROM (SELECT h.accrual_status FROM t_order_status_history h WHERE h.order_number = $1::varchar ORDER BY h.id DESC LIMIT 1)`
+	getOrderStatusHistorySQL   = "SELECT * FROM t_order_status_history WHERE order_number = $1 ORDER BY created_at, id"
+	getOrdersByUserWithdrawSQL = "SELECT ta.id, ta.order_number number, abs(ta.difference) accrual, ta.created_at processed_at FROM  public.t_account ta WHERE ta.user_id = ? AND ta.difference < 0 AND ta.order_number NOTNULL"
+	getOrderAccrualSQL         = "SELECT ta.difference FROM t_account ta WHERE ta.order_number = $1 AND ta.difference > 0 LIMIT 1"
+	getOrderWithdrawSQL        = "SELECT ta.order_number number, abs(ta.difference) accrual, ta.created_at processed_at FROM t_account ta WHERE ta.order_number = $1 AND ta.user_id = $2 AND ta.difference < 0 ORDER BY ta.created_at DESC LIMIT 1"
 )
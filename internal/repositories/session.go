@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gofemart/internal/models"
+	"time"
+)
+
+// SessionRepository представляет собой хранилище сессий пользователей и токенов обновления.
+type SessionRepository struct {
+	// db пул соединений с базой данных, которыми может пользоваться хранилище
+	db SQLQueryer
+	// storeCtx контекст, который отвечает за запросы
+	ctx context.Context
+}
+
+// NewSessionRepository создаёт и возвращает новый экземпляр SessionRepository.
+func NewSessionRepository(ctx context.Context, db SQLQueryer) *SessionRepository {
+	return &SessionRepository{
+		ctx: ctx,
+		db:  db,
+	}
+}
+
+// CreateSession вставляем новую сессию
+func (r *SessionRepository) CreateSession(session *models.Session) error {
+	_, err := r.db.NamedExecContext(r.ctx, createSessionSQL, session)
+	return err
+}
+
+// GetSessionForUpdate извлекает сессию и блокирует её до конца транзакции.
+// Возвращает сессию, логическое значение, если найдена, и ошибку.
+func (r *SessionRepository) GetSessionForUpdate(id string) (*models.Session, bool, error) {
+	var session models.Session
+	err := r.db.QueryRowxContext(r.ctx, getSessionForUpdateSQL, id).StructScan(&session)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &session, true, nil
+}
+
+// RefreshSession отмечаем обновление токенов сессии и продлеваем её до expiresAt
+func (r *SessionRepository) RefreshSession(id string, refreshedAt time.Time, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(r.ctx, refreshSessionSQL, id, refreshedAt, expiresAt)
+	return err
+}
+
+// ActiveSessionExists проверяем, что сессия пользователя не отозвана и не истекла
+func (r *SessionRepository) ActiveSessionExists(id string, userID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(r.ctx, activeSessionExistsSQL, id, userID).Scan(&exists)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// RevokeSession отзываем сессию пользователя. Возвращает false, если активной сессии не было.
+func (r *SessionRepository) RevokeSession(id string, userID int64) (bool, error) {
+	res, err := r.db.ExecContext(r.ctx, revokeSessionSQL, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeUserSessions отзываем все активные сессии пользователя и возвращаем их количество
+func (r *SessionRepository) RevokeUserSessions(userID int64) (int64, error) {
+	res, err := r.db.ExecContext(r.ctx, revokeUserSessionsSQL, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RevokeOtherSessions отзываем все активные сессии пользователя, кроме sessionID, и возвращаем их количество
+func (r *SessionRepository) RevokeOtherSessions(userID int64, sessionID string) (int64, error) {
+	res, err := r.db.ExecContext(r.ctx, revokeOtherSessionsSQL, userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteExpired удаляем истёкшие сессии вместе с их токенами обновления и возвращаем количество сессий
+func (r *SessionRepository) DeleteExpired() (int64, error) {
+	res, err := r.db.ExecContext(r.ctx, deleteExpiredSessionsSQL)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CreateRefreshToken вставляем новый токен обновления
+func (r *SessionRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	_, err := r.db.NamedExecContext(r.ctx, createRefreshTokenSQL, token)
+	return err
+}
+
+// GetRefreshTokenForUpdate извлекает токен обновления по хэшу и блокирует его до конца транзакции.
+// Возвращает токен, логическое значение, если найден, и ошибку.
+func (r *SessionRepository) GetRefreshTokenForUpdate(tokenHash string) (*models.RefreshToken, bool, error) {
+	var token models.RefreshToken
+	err := r.db.QueryRowxContext(r.ctx, getRefreshTokenForUpdateSQL, tokenHash).StructScan(&token)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &token, true, nil
+}
+
+// MarkRefreshTokenUsed отмечаем, что токен обновления обменян на новый
+func (r *SessionRepository) MarkRefreshTokenUsed(tokenHash string, usedAt time.Time) error {
+	_, err := r.db.ExecContext(r.ctx, markRefreshTokenUsedSQL, tokenHash, usedAt)
+	return err
+}
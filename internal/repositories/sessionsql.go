@@ -0,0 +1,15 @@
+package repositories
+
+const (
+	createSessionSQL            = "INSERT INTO t_session (id, user_id, created_at, refreshed_at, expires_at) VALUES (:id, :user_id, :created_at, :refreshed_at, :expires_at)"
+	getSessionForUpdateSQL      = "SELECT * FROM t_session WHERE id = $1 FOR UPDATE"
+	refreshSessionSQL           = "UPDATE t_session SET refreshed_at = $2, expires_at = $3 WHERE id = $1"
+	activeSessionExistsSQL      = "SELECT true FROM t_session WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > now()"
+	revokeSessionSQL            = "UPDATE t_session SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL"
+	revokeUserSessionsSQL       = "UPDATE t_session SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()"
+	revokeOtherSessionsSQL      = "UPDATE t_session SET revoked_at = now() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > now()"
+	deleteExpiredSessionsSQL    = "DELETE FROM t_session WHERE expires_at <= now()"
+	createRefreshTokenSQL       = "INSERT INTO t_refresh_token (token_hash, session_id, created_at, expires_at) VALUES (:token_hash, :session_id, :created_at, :expires_at)"
+	getRefreshTokenForUpdateSQL = "SELECT * FROM t_refresh_token WHERE token_hash = $1 FOR UPDATE"
+	markRefreshTokenUsedSQL     = "UPDATE t_refresh_token SET used_at = $2 WHERE token_hash = $1"
+)
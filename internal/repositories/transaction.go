@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gofemart/internal/logger"
+)
+
+// InTransaction выполняет функцию fn в транзакции базы данных.
+// Если fn вернула ошибку или запаниковала, транзакция откатывается, иначе фиксируется.
+func InTransaction(ctx context.Context, db SQLExecutor, opts *sql.TxOptions, fn func(tx SQLQueryer) error) (err error) {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(tx.Rollback)
+			panic(p)
+		}
+		if err != nil {
+			rollback(tx.Rollback)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rollback откатывает транзакцию, логируя ошибку отката
+func rollback(rollbackFunc func() error) {
+	if err := rollbackFunc(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logger.Log.Error(err)
+	}
+}
+
+// Transactor выполняет функции в транзакциях пула соединений с базой данных
+type Transactor struct {
+	ctx context.Context
+	db  SQLExecutor
+}
+
+// NewTransactor создаёт Transactor для указанного контекста и пула соединений
+func NewTransactor(ctx context.Context, db SQLExecutor) *Transactor {
+	return &Transactor{
+		ctx: ctx,
+		db:  db,
+	}
+}
+
+// InTransaction выполняет функцию fn в транзакции с уровнем изоляции по умолчанию
+func (t *Transactor) InTransaction(fn func(tx SQLQueryer) error) error {
+	return InTransaction(t.ctx, t.db, nil, fn)
+}
@@ -5,18 +5,19 @@ import (
 	"database/sql"
 	"errors"
 	"gofemart/internal/models"
+	"time"
 )
 
 // UserRepository представляет собой хранилище для управления данными пользователя.
 type UserRepository struct {
 	// db пул соединений с базой данных, которыми может пользоваться хранилище
-	db SQLExecutor
+	db SQLQueryer
 	// storeCtx контекст, который отвечает за запросы
 	ctx context.Context
 }
 
-// NewUserRepository initializes and returns a new UserRepository with the given context and SQLExecutor.
-func NewUserRepository(ctx context.Context, db SQLExecutor) *UserRepository { // TODO заменить на интерфейс
+// NewUserRepository initializes and returns a new UserRepository with the given context and SQLQueryer.
+func NewUserRepository(ctx context.Context, db SQLQueryer) *UserRepository { // TODO заменить на интерфейс
 	return &UserRepository{
 		ctx: ctx,
 		db:  db,
@@ -74,3 +75,80 @@ func (r *UserRepository) GetUserByID(id int64) (*models.User, bool, error) {
 	}
 	return &user, true, nil
 }
+
+// UpdatePasswordHash заменяем хэш пароля пользователя
+func (r *UserRepository) UpdatePasswordHash(id int64, passwordHash string) error {
+	_, err := r.db.ExecContext(r.ctx, updatePasswordSQL, id, passwordHash)
+	return err
+}
+
+// RegisterFailedLogin увеличиваем счётчик неудачных входов пользователя.
+// Когда счётчик достигает maxFailures, вход блокируется до lockedUntil, а счётчик сбрасывается. Возвращает, заблокирован ли вход.
+func (r *UserRepository) RegisterFailedLogin(id int64, maxFailures int, lockedUntil time.Time) (bool, error) {
+	var locked bool
+	err := r.db.QueryRowContext(r.ctx, registerFailedLoginSQL, id, maxFailures, lockedUntil).Scan(&locked)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return locked, err
+}
+
+// ResetFailedLogins сбрасываем счётчик неудачных входов и блокировку после успешного входа
+func (r *UserRepository) ResetFailedLogins(id int64) error {
+	_, err := r.db.ExecContext(r.ctx, resetFailedLoginsSQL, id)
+	return err
+}
+
+// UnlockUser снимаем блокировку входа пользователя по логину, возвращает false, если пользователя нет
+func (r *UserRepository) UnlockUser(login string) (bool, error) {
+	result, err := r.db.ExecContext(r.ctx, unlockUserSQL, login)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CreatePasswordResetToken вставляем новый токен сброса пароля
+func (r *UserRepository) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	_, err := r.db.NamedExecContext(r.ctx, createPasswordResetSQL, token)
+	return err
+}
+
+// GetPasswordResetTokenForUpdate извлекает токен сброса пароля по хэшу и блокирует его до конца транзакции.
+// Возвращает токен, логическое значение, если найден, и ошибку.
+func (r *UserRepository) GetPasswordResetTokenForUpdate(tokenHash string) (*models.PasswordResetToken, bool, error) {
+	var token models.PasswordResetToken
+	err := r.db.QueryRowxContext(r.ctx, getPasswordResetForUpdateSQL, tokenHash).StructScan(&token)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &token, true, nil
+}
+
+// MarkPasswordResetTokenUsed отмечаем, что токен сброса пароля использован
+func (r *UserRepository) MarkPasswordResetTokenUsed(tokenHash string, usedAt time.Time) error {
+	_, err := r.db.ExecContext(r.ctx, markPasswordResetUsedSQL, tokenHash, usedAt)
+	return err
+}
+
+// InvalidatePasswordResetTokens отзываем все неиспользованные токены сброса пароля пользователя
+func (r *UserRepository) InvalidatePasswordResetTokens(userID int64) error {
+	_, err := r.db.ExecContext(r.ctx, invalidatePasswordResetsSQL, userID)
+	return err
+}
+
+// DeleteExpiredPasswordResetTokens удаляем истёкшие токены сброса пароля и возвращаем их количество
+func (r *UserRepository) DeleteExpiredPasswordResetTokens() (int64, error) {
+	res, err := r.db.ExecContext(r.ctx, deleteExpiredPasswordResetsSQL)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -1,8 +1,23 @@
 package repositories
 
 const (
-	getUserByIDSQL    = "SELECT id, login, password_hash FROM t_user WHERE id = $1"
-	getUserByLoginSQL = "SELECT id, login, password_hash FROM t_user WHERE login = $1"
+	getUserByIDSQL    = "SELECT id, login, password_hash, failed_logins, locked_until FROM t_user WHERE id = $1"
+	getUserByLoginSQL = "SELECT id, login, password_hash, failed_logins, locked_until FROM t_user WHERE login = $1"
 	createUserSQL     = "INSERT INTO t_user (login, password_hash) VALUES (:login, :password_hash) RETURNING id"
 	userExistsSQL     = "SELECT true FROM t_user WHERE login = $1"
+	updatePasswordSQL = "UPDATE t_user SET password_hash = $2, updated_at = now() WHERE id = $1"
+	// Счётчик сбрасывается при блокировке, поэтому нулевой счётчик после увеличения означает, что пользователь заблокирован
+	registerFailedLoginSQL = `UPDATE t_user
+SET failed_logins = CASE WHEN failed_logins + 1 >= $2 THEN 0 ELSE failed_logins + 1 END,
+    locked_until  = CASE WHEN failed_logins + 1 >= $2 THEN $3 ELSE locked_until END
+WHERE id = $1
+RETURNING failed_logins = 0`
+	resetFailedLoginsSQL = "UPDATE t_user SET failed_logins = 0, locked_until = NULL WHERE id = $1"
+	unlockUserSQL        = "UPDATE t_user SET failed_logins = 0, locked_until = NULL, updated_at = now() WHERE login = $1"
+
+	createPasswordResetSQL         = "INSERT INTO t_password_reset (token_hash, user_id, created_at, expires_at) VALUES (:token_hash, :user_id, :created_at, :expires_at)"
+	getPasswordResetForUpdateSQL   = "SELECT * FROM t_password_reset WHERE token_hash = $1 FOR UPDATE"
+	markPasswordResetUsedSQL       = "UPDATE t_password_reset SET used_at = $2 WHERE token_hash = $1"
+	invalidatePasswordResetsSQL    = "UPDATE t_password_reset SET used_at = now() WHERE user_id = $1 AND used_at IS NULL"
+	deleteExpiredPasswordResetsSQL = "DELETE FROM t_password_reset WHERE expires_at <= now()"
 )
@@ -3,30 +3,42 @@ package router
 import (
 	"github.com/go-chi/chi/v5"
 	cMiddleware "github.com/go-chi/chi/v5/middleware"
+	"gofemart/internal/handlers/admin"
 	"gofemart/internal/handlers/balance"
 	"gofemart/internal/handlers/login"
 	"gofemart/internal/handlers/orders"
+	"gofemart/internal/idempotency"
 
 	//_ "gofemart/api"
 	config "gofemart/internal/configuration"
 	database "gofemart/internal/databse"
+	"gofemart/internal/health"
 	"gofemart/internal/logger"
+	"gofemart/internal/metrics"
 	"gofemart/internal/middlewares"
+	"gofemart/internal/notifier"
+	"gofemart/internal/services"
 	"gofemart/internal/token"
+	"gofemart/internal/tracing"
+	"net/http"
 )
 
 // NewRouter конфигурация роутинга приложение
-func NewRouter(dbPool *database.DBPool, cnf *config.CliConfig) chi.Router {
-	lHandlers := login.NewHandlers(dbPool.DBx, cnf.JWTKeys, cnf.TokenExpiration, cnf.HashKey)
+func NewRouter(dbPool *database.DBPool, cnf *config.CliConfig, probe *health.Probe, keyRing *token.KeyRing, guard *services.LoginGuard, resetLimiter *services.ResetLimiter, resetTasks *services.BackgroundTasks, resetNotifier notifier.Notifier) chi.Router {
+	lHandlers := login.NewHandlers(dbPool.DBx, keyRing, cnf.TokenExpiration, cnf.RefreshTokenExpiration, cnf.HashKey, guard, resetLimiter, resetTasks, resetNotifier, cnf.PasswordResetExpiration)
 	bHandlers := balance.NewHandlers(dbPool.DBx)
 	oHandlers := orders.NewHandlers(dbPool.DBx)
-	authenticator := token.NewAuthenticator(dbPool.DBx, cnf.JWTKeys, cnf.TokenExpiration)
+	aHandlers := admin.NewHandlers(dbPool.DBx, guard)
+	authenticator := token.NewAuthenticator(dbPool.DBx, keyRing, cnf.TokenExpiration)
+	keeper := idempotency.NewKeeper(dbPool.DBx, cnf.IdempotencyKeyTTL, cnf.IdempotencyProcessingTimeout)
 	router := chi.NewRouter()
 	// Устанавливаем мидлваре
 	router.Use(
+		tracing.HTTPTracing, // Продолжаем трассировку вызывающей стороны и создаём спан запроса
 		middlewares.JSONHeaders,
 		cMiddleware.StripSlashes, // Убираем лишние слеши
 		logger.LogRequests,       // Логируем данные запроса
+		metrics.HTTPMetrics,      // Считаем запросы по маршрутам для Prometheus
 	)
 	// Адрес свагера
 	/*router.Get("/swagger/*", httpSwagger.Handler(
@@ -35,21 +47,36 @@ func NewRouter(dbPool *database.DBPool, cnf *config.CliConfig) chi.Router {
 	router.Route("/api/user", func(r chi.Router) {
 		r.Post("/register", lHandlers.RegistrationHandler)
 		r.Post("/login", lHandlers.LoginHandler)
-		r.Group(registerRoutesWithAuth(bHandlers, oHandlers, authenticator))
+		r.Post("/token/refresh", lHandlers.RefreshHandler)
+		r.Post("/password/reset/request", lHandlers.PasswordResetRequestHandler)
+		r.Post("/password/reset", lHandlers.PasswordResetHandler)
+		r.Group(registerRoutesWithAuth(lHandlers, bHandlers, oHandlers, authenticator, keeper))
 	})
+	router.Route("/api/admin", registerAdminRoutes(aHandlers, cnf.AdminToken))
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
+	// Проверки живости и готовности для оркестратора, без аутентификации
+	router.Get("/healthz", probe.LivenessHandler)
+	router.Get("/readyz", probe.ReadinessHandler)
+	// Публичные ключи для проверки токенов другими сервисами
+	router.Get("/.well-known/jwks.json", keyRing.JWKSHandler)
 
 	return router
 }
 
 // registerRoutesWithAuth маршруты с аутентификацией
-func registerRoutesWithAuth(bHandlers *balance.Handlers, oHandlers *orders.Handlers, authenticator *token.Authenticator) func(r chi.Router) {
+// Изменяющие маршруты поддерживают заголовок Idempotency-Key для безопасного повтора запросов клиентами
+func registerRoutesWithAuth(lHandlers *login.Handlers, bHandlers *balance.Handlers, oHandlers *orders.Handlers, authenticator *token.Authenticator, keeper *idempotency.Keeper) func(r chi.Router) {
 	return func(r chi.Router) {
 		r.Use(
 			authenticator.Middleware,
 			cMiddleware.Compress(5, "gzip", "deflate"),
 		)
-		r.Post("/orders", oHandlers.RegisterOrderHandler)
-		r.Post("/balance/withdraw", bHandlers.WithdrawHandler)
+		r.Post("/logout", lHandlers.LogoutHandler)
+		r.Post("/logout/all", lHandlers.LogoutAllHandler)
+		r.Post("/password", lHandlers.ChangePasswordHandler)
+		r.With(keeper.Middleware).Post("/orders", oHandlers.RegisterOrderHandler)
+		r.With(keeper.Middleware).Post("/orders/batch", oHandlers.RegisterOrdersBatchHandler)
+		r.With(keeper.Middleware).Post("/balance/withdraw", bHandlers.WithdrawHandler)
 		r.Get("/balance", bHandlers.GetBalanceHandler)
 		r.Group(registerRoutesWithCompressed(oHandlers))
 	}
@@ -63,6 +90,19 @@ func registerRoutesWithCompressed(oHandlers *orders.Handlers) func(r chi.Router)
 			cMiddleware.Compress(5, "gzip", "deflate"),
 		)
 		r.Get("/orders", oHandlers.GetOrdersHandler)
+		r.Get("/orders/{number}", oHandlers.GetOrderHandler)
+		r.Get("/orders/{number}/history", oHandlers.GetOrderHistoryHandler)
 		r.Get("/withdrawals", oHandlers.GetOrdersWwithdrawalsHandler)
 	}
 }
+
+// registerAdminRoutes маршруты административного API, доступ по статическому токену администратора
+func registerAdminRoutes(aHandlers *admin.Handlers, adminToken string) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middlewares.AdminAuth(adminToken))
+		r.Get("/orders/parked", aHandlers.GetParkedOrdersHandler)
+		r.Post("/orders/requeue", aHandlers.RequeueOrdersHandler)
+		r.Get("/accrual/status", aHandlers.GetAccrualStatusHandler)
+		r.Post("/users/unlock", aHandlers.UnlockUserHandler)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import "sync"
+
+// BackgroundTasks фоновые задачи, которые нужно завершить до остановки приложения, например доставка токенов сброса пароля.
+// После Wait новые задачи выполняются сразу в вызывающей горутине, чтобы не потеряться при остановке.
+type BackgroundTasks struct {
+	mutex  sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewBackgroundTasks создаём группу фоновых задач
+func NewBackgroundTasks() *BackgroundTasks {
+	return &BackgroundTasks{}
+}
+
+// Go выполняем fn в отдельной горутине, которую дождётся Wait
+func (b *BackgroundTasks) Go(fn func()) {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		fn()
+		return
+	}
+	b.wg.Add(1)
+	b.mutex.Unlock()
+	go func() {
+		defer b.wg.Done()
+		fn()
+	}()
+}
+
+// Wait дожидаемся завершения запущенных задач
+func (b *BackgroundTasks) Wait() {
+	b.mutex.Lock()
+	b.closed = true
+	b.mutex.Unlock()
+	b.wg.Wait()
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestBackgroundTasksWait(t *testing.T) {
+	tasks := NewBackgroundTasks()
+	release := make(chan struct{})
+	var done atomic.Int32
+	for i := 0; i < 3; i++ {
+		tasks.Go(func() {
+			<-release
+			done.Add(1)
+		})
+	}
+	close(release)
+	tasks.Wait()
+	if got := done.Load(); got != 3 {
+		t.Fatalf("expected Wait to drain all tasks, got %d done", got)
+	}
+
+	// После Wait задача выполняется сразу, а не теряется
+	tasks.Go(func() {
+		done.Add(1)
+	})
+	if got := done.Load(); got != 4 {
+		t.Errorf("expected task after Wait to run inline, got %d done", got)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"gofemart/internal/logger"
 	"gofemart/internal/models"
 	"gofemart/internal/repositories"
-	"sync"
 	"time"
 )
 
@@ -16,22 +15,22 @@ var ErrorNotEnoughItems = errors.New("there are not enough resources")
 
 // BalanceRepository интерфейс для репозитория для работы с балансом пользователя
 type BalanceRepository interface {
-	GetSum(userID int64) (float64, error)
+	LockUserBalance(userID int64) error
+	GetSum(userID int64) (models.Money, error)
 	CreateAccount(account *models.Account) error
 }
 
-// MutexService интерфейс сервиса для работы с мьютексами пользователя
-type MutexService interface {
-	SetMutex(userID int64) *sync.Mutex
-	GetMutex(userID int64) (*sync.Mutex, bool)
-	DeleteMutex(userID int64) error
+// Transactor интерфейс для выполнения функции внутри одной транзакции базы данных
+type Transactor interface {
+	InTransaction(fn func(tx repositories.SQLQueryer) error) error
 }
 
 // BalanceService безопасный сервис для списания средств
 type BalanceService struct {
 	ctx        context.Context
-	repository BalanceRepository
-	userMutex  MutexService
+	transactor Transactor
+	// newRepository создаёт репозиторий баланса, работающий внутри транзакции
+	newRepository func(tx repositories.SQLQueryer) BalanceRepository
 }
 
 // NewBalanceService получение нового сервиса трат
@@ -39,50 +38,49 @@ func NewBalanceService(ctx context.Context, dbPool repositories.SQLExecutor) *Ba
 	logger.Log.Debug("NewBalanceService")
 	return &BalanceService{
 		ctx:        ctx,
-		repository: getAccountRepository(ctx, dbPool),
-		userMutex:  GetUserMutexInstance(),
+		transactor: repositories.NewTransactor(ctx, dbPool),
+		newRepository: func(tx repositories.SQLQueryer) BalanceRepository {
+			return getAccountRepository(ctx, tx)
+		},
 	}
 }
 
-// Spend списываем средства со счёта
-func (s *BalanceService) Spend(user *models.User, sum float64, order *models.Order) error {
-	logger.Log.Debugw("Spend", "user", user.ID, "sum", sum, "order", order.Number)
-	userMutex, exists := s.userMutex.GetMutex(user.ID)
-	if !exists {
-		userMutex = s.userMutex.SetMutex(user.ID)
-	}
-	userMutex.Lock()
-	defer func() {
-		userMutex.Unlock()
-		if errUM := s.userMutex.DeleteMutex(user.ID); errUM != nil {
-			logger.Log.Info(errUM)
+// Spend списываем средства со счёта.
+// Проверка баланса и списание выполняются в одной транзакции под блокировкой баланса пользователя,
+// поэтому несколько экземпляров приложения не могут списать одни и те же средства дважды.
+func (s *BalanceService) Spend(user *models.User, sum models.Money, order *models.Order) error {
+	logger.Log.Debugw("Spend", "user", user.ID, "sum", sum.String(), "order", order.Number)
+	return s.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+		repository := s.newRepository(tx)
+		if err := repository.LockUserBalance(user.ID); err != nil {
+			return err
 		}
-	}()
 
-	balanceSum, err := s.repository.GetSum(user.ID)
-	if err != nil {
-		return err
-	}
+		balanceSum, err := repository.GetSum(user.ID)
+		if err != nil {
+			return err
+		}
 
-	if balanceSum < sum {
-		return ErrorNotEnoughItems
-	}
+		if balanceSum.LessThan(sum.Decimal) {
+			return ErrorNotEnoughItems
+		}
 
-	newAcc := models.Account{
-		UserID:     user.ID,
-		Difference: -sum,
-		OrderNumber: sql.NullString{
-			String: order.Number,
-			Valid:  true,
-		},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+		newAcc := models.Account{
+			UserID:     user.ID,
+			Difference: sum.Neg(),
+			OrderNumber: sql.NullString{
+				String: order.Number,
+				Valid:  true,
+			},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
 
-	return s.repository.CreateAccount(&newAcc)
+		return repository.CreateAccount(&newAcc)
+	})
 }
 
 // getAccountRepository создаём репозиторий для начислений
-func getAccountRepository(ctx context.Context, dbPool repositories.SQLExecutor) *repositories.AccountRepository {
-	return repositories.NewAccountRepository(ctx, dbPool)
+func getAccountRepository(ctx context.Context, executor repositories.SQLQueryer) *repositories.AccountRepository {
+	return repositories.NewAccountRepository(ctx, executor)
 }
@@ -6,45 +6,43 @@ import (
 	"errors"
 	"github.com/golang/mock/gomock"
 	"gofemart/internal/models"
+	"gofemart/internal/repositories"
 	"gofemart/internal/services/mock"
-	"sync"
 	"testing"
 )
 
 func TestSpend(t *testing.T) {
 	ctrl := gomock.NewController(t)
-	userMutex := mock.NewMockMutexService(ctrl)
-	userMutex.EXPECT().
-		GetMutex(gomock.Any()).
+	transactor := mock.NewMockTransactor(ctrl)
+	transactor.EXPECT().
+		InTransaction(gomock.Any()).
 		AnyTimes().
-		Return(&sync.Mutex{}, true)
-	userMutex.EXPECT().
-		SetMutex(gomock.Any()).
-		AnyTimes().
-		Return(&sync.Mutex{})
-	userMutex.EXPECT().
-		DeleteMutex(gomock.Any()).
-		AnyTimes().
-		Return(nil)
+		DoAndReturn(func(fn func(tx repositories.SQLQueryer) error) error {
+			return fn(nil)
+		})
 
 	tests := []struct {
 		name           string
-		sum            float64
+		sum            models.Money
 		expectationErr error
 		wantErr        bool
 		setup          func() BalanceRepository
 	}{
 		{
 			"enough balance",
-			1000,
+			mustMoney(t, "1000"),
 			nil,
 			false,
 			func() BalanceRepository {
 				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				balanceRepo.EXPECT().
 					GetSum(gomock.Any()).
 					AnyTimes().
-					Return(float64(2000), nil)
+					Return(mustMoney(t, "2000"), nil)
 				balanceRepo.EXPECT().
 					CreateAccount(gomock.Any()).
 					AnyTimes().
@@ -54,15 +52,63 @@ func TestSpend(t *testing.T) {
 		},
 		{
 			"balance exact",
-			1000,
+			mustMoney(t, "1000"),
 			nil,
 			false,
 			func() BalanceRepository {
 				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
+				balanceRepo.EXPECT().
+					GetSum(gomock.Any()).
+					AnyTimes().
+					Return(mustMoney(t, "1000"), nil)
+				balanceRepo.EXPECT().
+					CreateAccount(gomock.Any()).
+					AnyTimes().
+					Return(nil)
+				return balanceRepo
+			},
+		},
+		{
+			"fractional balance exact",
+			mustMoney(t, "0.3"),
+			nil,
+			false,
+			func() BalanceRepository {
+				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
+				balanceRepo.EXPECT().
+					GetSum(gomock.Any()).
+					AnyTimes().
+					Return(mustMoney(t, "0.1").Add(mustMoney(t, "0.2")), nil)
+				balanceRepo.EXPECT().
+					CreateAccount(gomock.Any()).
+					AnyTimes().
+					Return(nil)
+				return balanceRepo
+			},
+		},
+		{
+			"fractional not enough balance",
+			mustMoney(t, "100.01"),
+			ErrorNotEnoughItems,
+			true,
+			func() BalanceRepository {
+				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				balanceRepo.EXPECT().
 					GetSum(gomock.Any()).
 					AnyTimes().
-					Return(float64(1000), nil)
+					Return(mustMoney(t, "100.00"), nil)
 				balanceRepo.EXPECT().
 					CreateAccount(gomock.Any()).
 					AnyTimes().
@@ -72,15 +118,19 @@ func TestSpend(t *testing.T) {
 		},
 		{
 			"not enough balance",
-			2000,
+			mustMoney(t, "2000"),
 			ErrorNotEnoughItems,
 			true,
 			func() BalanceRepository {
 				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				balanceRepo.EXPECT().
 					GetSum(gomock.Any()).
 					AnyTimes().
-					Return(float64(1000), nil)
+					Return(mustMoney(t, "1000"), nil)
 				balanceRepo.EXPECT().
 					CreateAccount(gomock.Any()).
 					AnyTimes().
@@ -90,15 +140,19 @@ func TestSpend(t *testing.T) {
 		},
 		{
 			"balance retrieval error",
-			2000,
+			mustMoney(t, "2000"),
 			sql.ErrNoRows,
 			true,
 			func() BalanceRepository {
 				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				balanceRepo.EXPECT().
 					GetSum(gomock.Any()).
 					AnyTimes().
-					Return(float64(0), sql.ErrNoRows)
+					Return(models.Money{}, sql.ErrNoRows)
 				balanceRepo.EXPECT().
 					CreateAccount(gomock.Any()).
 					AnyTimes().
@@ -106,17 +160,35 @@ func TestSpend(t *testing.T) {
 				return balanceRepo
 			},
 		},
+		{
+			"lock error",
+			mustMoney(t, "1000"),
+			repositories.ErrorNotExists,
+			true,
+			func() BalanceRepository {
+				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(repositories.ErrorNotExists)
+				return balanceRepo
+			},
+		},
 		{
 			"account creation error",
-			1000,
+			mustMoney(t, "1000"),
 			sql.ErrConnDone,
 			true,
 			func() BalanceRepository {
 				balanceRepo := mock.NewMockBalanceRepository(ctrl)
+				balanceRepo.EXPECT().
+					LockUserBalance(gomock.Any()).
+					AnyTimes().
+					Return(nil)
 				balanceRepo.EXPECT().
 					GetSum(gomock.Any()).
 					AnyTimes().
-					Return(float64(2000), nil)
+					Return(mustMoney(t, "2000"), nil)
 				balanceRepo.EXPECT().
 					CreateAccount(gomock.Any()).
 					AnyTimes().
@@ -134,8 +206,10 @@ func TestSpend(t *testing.T) {
 
 			service := &BalanceService{
 				ctx:        context.Background(),
-				repository: repo,
-				userMutex:  userMutex,
+				transactor: transactor,
+				newRepository: func(tx repositories.SQLQueryer) BalanceRepository {
+					return repo
+				},
 			}
 
 			err := service.Spend(user, tt.sum, order)
@@ -147,3 +221,33 @@ func TestSpend(t *testing.T) {
 		})
 	}
 }
+
+func mustMoney(t *testing.T, value string) models.Money {
+	t.Helper()
+	money, err := models.NewMoneyFromString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return money
+}
+
+func TestSpendTransactionError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactor := mock.NewMockTransactor(ctrl)
+	transactor.EXPECT().
+		InTransaction(gomock.Any()).
+		Return(sql.ErrTxDone)
+
+	service := &BalanceService{
+		ctx:        context.Background(),
+		transactor: transactor,
+		newRepository: func(tx repositories.SQLQueryer) BalanceRepository {
+			return mock.NewMockBalanceRepository(ctrl)
+		},
+	}
+
+	err := service.Spend(&models.User{ID: 1}, mustMoney(t, "10"), &models.Order{Number: "2377225624"})
+	if !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("BalanceService.Spend() error = %v, wantErr %v", err, sql.ErrTxDone)
+	}
+}
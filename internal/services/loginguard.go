@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"sync"
+	"time"
+)
+
+// LoginAttemptRepository интерфейс для учёта неудачных входов пользователя в базе данных
+type LoginAttemptRepository interface {
+	RegisterFailedLogin(id int64, maxFailures int, lockedUntil time.Time) (bool, error)
+	ResetFailedLogins(id int64) error
+}
+
+// LoginGuardConfig настройки защиты входа от подбора пароля
+type LoginGuardConfig struct {
+	// Window окно, в котором считаются неудачные попытки входа с одного адреса для одного логина
+	Window time.Duration
+	// FreeAttempts количество неудачных попыток в окне без задержки
+	FreeAttempts int
+	// BaseDelay задержка после первой попытки сверх FreeAttempts, дальше удваивается с каждой попыткой
+	BaseDelay time.Duration
+	// MaxDelay наибольшая задержка между попытками
+	MaxDelay time.Duration
+	// MaxFailures количество неудачных входов подряд, после которого вход пользователя блокируется, 0 отключает блокировку
+	MaxFailures int
+	// LockDuration время блокировки входа пользователя
+	LockDuration time.Duration
+}
+
+// loginAttempts неудачные попытки входа в окне
+type loginAttempts struct {
+	failures []time.Time
+	// nextAttempt время, раньше которого следующая попытка отклоняется
+	nextAttempt time.Time
+}
+
+// LoginGuard защита входа от подбора пароля.
+// Неудачные попытки считаются в памяти по логину и адресу клиента в скользящем окне, каждая попытка сверх допустимых удваивает задержку.
+// Неудачные входы подряд для существующего пользователя считаются в базе данных и после MaxFailures блокируют вход на LockDuration.
+type LoginGuard struct {
+	cnf   LoginGuardConfig
+	mutex sync.Mutex
+	// attempts попытки по логину и адресу клиента
+	attempts map[string]map[string]*loginAttempts
+	now      func() time.Time
+}
+
+// NewLoginGuard создаём защиту входа
+func NewLoginGuard(cnf LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		cnf:      cnf,
+		attempts: make(map[string]map[string]*loginAttempts),
+		now:      time.Now,
+	}
+}
+
+// Wait сколько клиенту с адреса ip нужно подождать перед следующей попыткой входа под login, 0 если попытка разрешена
+func (g *LoginGuard) Wait(login string, ip string) time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	attempts, ok := g.attempts[login][ip]
+	if !ok {
+		return 0
+	}
+	if wait := attempts.nextAttempt.Sub(g.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Failed учитываем неудачную попытку входа.
+// Если пользователь существует, увеличиваем счётчик в базе данных и возвращаем время блокировки, если она наступила.
+func (g *LoginGuard) Failed(repository LoginAttemptRepository, login string, ip string, user *models.User) (time.Duration, error) {
+	now := g.now()
+	g.registerFailure(login, ip, now)
+	if user == nil || g.cnf.MaxFailures <= 0 {
+		return 0, nil
+	}
+	locked, err := repository.RegisterFailedLogin(user.ID, g.cnf.MaxFailures, now.Add(g.cnf.LockDuration))
+	if err != nil || !locked {
+		return 0, err
+	}
+	logger.Log.Warnw("User login locked after failed attempts", "user", user.ID, "ip", ip, "duration", g.cnf.LockDuration)
+	return g.cnf.LockDuration, nil
+}
+
+// Succeeded сбрасываем неудачные попытки после успешного входа
+func (g *LoginGuard) Succeeded(repository LoginAttemptRepository, login string, ip string, user *models.User) error {
+	g.mutex.Lock()
+	if byIP, ok := g.attempts[login]; ok {
+		delete(byIP, ip)
+		if len(byIP) == 0 {
+			delete(g.attempts, login)
+		}
+	}
+	g.mutex.Unlock()
+	if user.FailedLogins == 0 && !user.LockedUntil.Valid {
+		return nil
+	}
+	return repository.ResetFailedLogins(user.ID)
+}
+
+// Unlock сбрасываем задержки входа под login со всех адресов
+func (g *LoginGuard) Unlock(login string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.attempts, login)
+}
+
+// registerFailure добавляем неудачную попытку в окно и назначаем задержку до следующей
+func (g *LoginGuard) registerFailure(login string, ip string, now time.Time) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	byIP, ok := g.attempts[login]
+	if !ok {
+		byIP = make(map[string]*loginAttempts)
+		g.attempts[login] = byIP
+	}
+	attempts, ok := byIP[ip]
+	if !ok {
+		attempts = &loginAttempts{}
+		byIP[ip] = attempts
+	}
+	attempts.failures = append(g.inWindow(attempts.failures, now), now)
+	attempts.nextAttempt = now.Add(g.delay(len(attempts.failures)))
+}
+
+// delay задержка после failures неудачных попыток в окне
+func (g *LoginGuard) delay(failures int) time.Duration {
+	if failures <= g.cnf.FreeAttempts || g.cnf.BaseDelay <= 0 {
+		return 0
+	}
+	delay := g.cnf.BaseDelay
+	for i := g.cnf.FreeAttempts + 1; i < failures && (g.cnf.MaxDelay <= 0 || delay < g.cnf.MaxDelay); i++ {
+		delay *= 2
+	}
+	if g.cnf.MaxDelay > 0 && delay > g.cnf.MaxDelay {
+		return g.cnf.MaxDelay
+	}
+	return delay
+}
+
+// inWindow оставляем попытки, которые попадают в окно
+func (g *LoginGuard) inWindow(failures []time.Time, now time.Time) []time.Time {
+	from := now.Add(-g.cnf.Window)
+	i := 0
+	for i < len(failures) && !failures[i].After(from) {
+		i++
+	}
+	return failures[i:]
+}
+
+// cleanup удаляем попытки, которые вышли из окна и больше не задерживают вход
+func (g *LoginGuard) cleanup() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := g.now()
+	for login, byIP := range g.attempts {
+		for ip, attempts := range byIP {
+			attempts.failures = g.inWindow(attempts.failures, now)
+			if len(attempts.failures) == 0 && !now.Before(attempts.nextAttempt) {
+				delete(byIP, ip)
+			}
+		}
+		if len(byIP) == 0 {
+			delete(g.attempts, login)
+		}
+	}
+}
+
+// RunCleanup периодически удаляем устаревшие попытки входа, пока не завершится ctx
+func (g *LoginGuard) RunCleanup(ctx context.Context) {
+	interval := g.cnf.Window
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.cleanup()
+		}
+	}
+}
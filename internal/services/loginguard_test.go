@@ -0,0 +1,133 @@
+package services
+
+import (
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/models"
+	"gofemart/internal/services/mock"
+	"testing"
+	"time"
+)
+
+// newTestLoginGuard защита входа с управляемым временем
+func newTestLoginGuard(now *time.Time) *LoginGuard {
+	guard := NewLoginGuard(LoginGuardConfig{
+		Window:       10 * time.Minute,
+		FreeAttempts: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Second,
+		MaxFailures:  5,
+		LockDuration: 15 * time.Minute,
+	})
+	guard.now = func() time.Time {
+		return *now
+	}
+	return guard
+}
+
+func TestLoginGuardProgressiveDelay(t *testing.T) {
+	now := time.Now()
+	guard := newTestLoginGuard(&now)
+	// Задержки после каждой неудачной попытки: две без задержки, затем удвоение до предела
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range want {
+		if wait := guard.Wait("user", "10.0.0.1"); wait != 0 {
+			t.Fatalf("attempt %d: expected attempt to be allowed, got wait %s", i+1, wait)
+		}
+		if _, err := guard.Failed(nil, "user", "10.0.0.1", nil); err != nil {
+			t.Fatal(err)
+		}
+		if wait := guard.Wait("user", "10.0.0.1"); wait != delay {
+			t.Errorf("attempt %d: expected delay %s, got %s", i+1, delay, wait)
+		}
+		// Другой адрес и другой логин не задерживаются
+		if guard.Wait("user", "10.0.0.2") != 0 || guard.Wait("other", "10.0.0.1") != 0 {
+			t.Errorf("attempt %d: expected delay only for the same login and address", i+1)
+		}
+		now = now.Add(delay)
+	}
+}
+
+func TestLoginGuardSlidingWindow(t *testing.T) {
+	now := time.Now()
+	guard := newTestLoginGuard(&now)
+	for i := 0; i < 3; i++ {
+		if _, err := guard.Failed(nil, "user", "10.0.0.1", nil); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(4 * time.Minute)
+	}
+	// Первая попытка вышла из окна, поэтому следующая снова третья, а не четвёртая
+	if _, err := guard.Failed(nil, "user", "10.0.0.1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if wait := guard.Wait("user", "10.0.0.1"); wait != time.Second {
+		t.Errorf("expected delay of the third attempt in window, got %s", wait)
+	}
+
+	now = now.Add(11 * time.Minute)
+	guard.cleanup()
+	if len(guard.attempts) != 0 {
+		t.Errorf("expected attempts outside of window to be removed, got %d logins", len(guard.attempts))
+	}
+}
+
+func TestLoginGuardLocksUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repository := mock.NewMockLoginAttemptRepository(ctrl)
+	now := time.Now()
+	guard := newTestLoginGuard(&now)
+	user := &models.User{ID: 1}
+
+	repository.EXPECT().RegisterFailedLogin(int64(1), 5, now.Add(15*time.Minute)).Return(false, nil)
+	locked, err := guard.Failed(repository, "user", "10.0.0.1", user)
+	if err != nil || locked != 0 {
+		t.Fatalf("expected user not to be locked, got %s, %v", locked, err)
+	}
+
+	repository.EXPECT().RegisterFailedLogin(int64(1), 5, now.Add(15*time.Minute)).Return(true, nil)
+	locked, err = guard.Failed(repository, "user", "10.0.0.1", user)
+	if err != nil || locked != 15*time.Minute {
+		t.Fatalf("expected user to be locked for 15m, got %s, %v", locked, err)
+	}
+}
+
+func TestLoginGuardSucceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repository := mock.NewMockLoginAttemptRepository(ctrl)
+	now := time.Now()
+	guard := newTestLoginGuard(&now)
+	for i := 0; i < 3; i++ {
+		if _, err := guard.Failed(nil, "user", "10.0.0.1", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Без неудачных входов в базе данных счётчик не сбрасывается лишним запросом
+	if err := guard.Succeeded(repository, "user", "10.0.0.1", &models.User{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if wait := guard.Wait("user", "10.0.0.1"); wait != 0 {
+		t.Errorf("expected delay to be reset after success, got %s", wait)
+	}
+
+	repository.EXPECT().ResetFailedLogins(int64(1)).Return(nil)
+	user := &models.User{ID: 1, FailedLogins: 2, LockedUntil: sql.NullTime{Time: now.Add(-time.Minute), Valid: true}}
+	if err := guard.Succeeded(repository, "user", "10.0.0.1", user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginGuardUnlock(t *testing.T) {
+	now := time.Now()
+	guard := newTestLoginGuard(&now)
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.1", "10.0.0.2", "10.0.0.2", "10.0.0.2"} {
+		if _, err := guard.Failed(nil, "user", ip, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	guard.Unlock("user")
+	if guard.Wait("user", "10.0.0.1") != 0 || guard.Wait("user", "10.0.0.2") != 0 {
+		t.Error("expected unlock to reset delays from all addresses")
+	}
+}
@@ -6,8 +6,8 @@ package mock
 
 import (
 	models "gofemart/internal/models"
+	repositories "gofemart/internal/repositories"
 	reflect "reflect"
-	sync "sync"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -50,10 +50,10 @@ func (mr *MockBalanceRepositoryMockRecorder) CreateAccount(account interface{})
 }
 
 // GetSum mocks base method.
-func (m *MockBalanceRepository) GetSum(userID int64) (float64, error) {
+func (m *MockBalanceRepository) GetSum(userID int64) (models.Money, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSum", userID)
-	ret0, _ := ret[0].(float64)
+	ret0, _ := ret[0].(models.Money)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -64,68 +64,53 @@ func (mr *MockBalanceRepositoryMockRecorder) GetSum(userID interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSum", reflect.TypeOf((*MockBalanceRepository)(nil).GetSum), userID)
 }
 
-// MockMutexService is a mock of MutexService interface.
-type MockMutexService struct {
+// LockUserBalance mocks base method.
+func (m *MockBalanceRepository) LockUserBalance(userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUserBalance", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUserBalance indicates an expected call of LockUserBalance.
+func (mr *MockBalanceRepositoryMockRecorder) LockUserBalance(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUserBalance", reflect.TypeOf((*MockBalanceRepository)(nil).LockUserBalance), userID)
+}
+
+// MockTransactor is a mock of Transactor interface.
+type MockTransactor struct {
 	ctrl     *gomock.Controller
-	recorder *MockMutexServiceMockRecorder
+	recorder *MockTransactorMockRecorder
 }
 
-// MockMutexServiceMockRecorder is the mock recorder for MockMutexService.
-type MockMutexServiceMockRecorder struct {
-	mock *MockMutexService
+// MockTransactorMockRecorder is the mock recorder for MockTransactor.
+type MockTransactorMockRecorder struct {
+	mock *MockTransactor
 }
 
-// NewMockMutexService creates a new mock instance.
-func NewMockMutexService(ctrl *gomock.Controller) *MockMutexService {
-	mock := &MockMutexService{ctrl: ctrl}
-	mock.recorder = &MockMutexServiceMockRecorder{mock}
+// NewMockTransactor creates a new mock instance.
+func NewMockTransactor(ctrl *gomock.Controller) *MockTransactor {
+	mock := &MockTransactor{ctrl: ctrl}
+	mock.recorder = &MockTransactorMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockMutexService) EXPECT() *MockMutexServiceMockRecorder {
+func (m *MockTransactor) EXPECT() *MockTransactorMockRecorder {
 	return m.recorder
 }
 
-// DeleteMutex mocks base method.
-func (m *MockMutexService) DeleteMutex(userID int64) error {
+// InTransaction mocks base method.
+func (m *MockTransactor) InTransaction(fn func(repositories.SQLQueryer) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMutex", userID)
+	ret := m.ctrl.Call(m, "InTransaction", fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteMutex indicates an expected call of DeleteMutex.
-func (mr *MockMutexServiceMockRecorder) DeleteMutex(userID interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMutex", reflect.TypeOf((*MockMutexService)(nil).DeleteMutex), userID)
-}
-
-// GetMutex mocks base method.
-func (m *MockMutexService) GetMutex(userID int64) (*sync.Mutex, bool) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMutex", userID)
-	ret0, _ := ret[0].(*sync.Mutex)
-	ret1, _ := ret[1].(bool)
-	return ret0, ret1
-}
-
-// GetMutex indicates an expected call of GetMutex.
-func (mr *MockMutexServiceMockRecorder) GetMutex(userID interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMutex", reflect.TypeOf((*MockMutexService)(nil).GetMutex), userID)
-}
-
-// SetMutex mocks base method.
-func (m *MockMutexService) SetMutex(userID int64) *sync.Mutex {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetMutex", userID)
-	ret0, _ := ret[0].(*sync.Mutex)
-	return ret0
-}
-
-// SetMutex indicates an expected call of SetMutex.
-func (mr *MockMutexServiceMockRecorder) SetMutex(userID interface{}) *gomock.Call {
+// InTransaction indicates an expected call of InTransaction.
+func (mr *MockTransactorMockRecorder) InTransaction(fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMutex", reflect.TypeOf((*MockMutexService)(nil).SetMutex), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InTransaction", reflect.TypeOf((*MockTransactor)(nil).InTransaction), fn)
 }
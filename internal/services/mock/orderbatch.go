@@ -0,0 +1,104 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/services/orderbatch.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	models "gofemart/internal/models"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOrderBatchRepository is a mock of OrderBatchRepository interface.
+type MockOrderBatchRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderBatchRepositoryMockRecorder
+}
+
+// MockOrderBatchRepositoryMockRecorder is the mock recorder for MockOrderBatchRepository.
+type MockOrderBatchRepositoryMockRecorder struct {
+	mock *MockOrderBatchRepository
+}
+
+// NewMockOrderBatchRepository creates a new mock instance.
+func NewMockOrderBatchRepository(ctrl *gomock.Controller) *MockOrderBatchRepository {
+	mock := &MockOrderBatchRepository{ctrl: ctrl}
+	mock.recorder = &MockOrderBatchRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderBatchRepository) EXPECT() *MockOrderBatchRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOrders mocks base method.
+func (m *MockOrderBatchRepository) CreateOrders(userID int64, numbers []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrders", userID, numbers)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrders indicates an expected call of CreateOrders.
+func (mr *MockOrderBatchRepositoryMockRecorder) CreateOrders(userID, numbers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrders", reflect.TypeOf((*MockOrderBatchRepository)(nil).CreateOrders), userID, numbers)
+}
+
+// GetOrdersByNumbers mocks base method.
+func (m *MockOrderBatchRepository) GetOrdersByNumbers(numbers []string) ([]models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersByNumbers", numbers)
+	ret0, _ := ret[0].([]models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersByNumbers indicates an expected call of GetOrdersByNumbers.
+func (mr *MockOrderBatchRepositoryMockRecorder) GetOrdersByNumbers(numbers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersByNumbers", reflect.TypeOf((*MockOrderBatchRepository)(nil).GetOrdersByNumbers), numbers)
+}
+
+// MockOrderQueue is a mock of OrderQueue interface.
+type MockOrderQueue struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderQueueMockRecorder
+}
+
+// MockOrderQueueMockRecorder is the mock recorder for MockOrderQueue.
+type MockOrderQueueMockRecorder struct {
+	mock *MockOrderQueue
+}
+
+// NewMockOrderQueue creates a new mock instance.
+func NewMockOrderQueue(ctrl *gomock.Controller) *MockOrderQueue {
+	mock := &MockOrderQueue{ctrl: ctrl}
+	mock.recorder = &MockOrderQueueMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderQueue) EXPECT() *MockOrderQueueMockRecorder {
+	return m.recorder
+}
+
+// Push mocks base method.
+func (m *MockOrderQueue) Push(ctx context.Context, order *models.Order) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Push", ctx, order)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Push indicates an expected call of Push.
+func (mr *MockOrderQueueMockRecorder) Push(ctx, order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Push", reflect.TypeOf((*MockOrderQueue)(nil).Push), ctx, order)
+}
@@ -0,0 +1,191 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/services/password.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	models "gofemart/internal/models"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPasswordRepository is a mock of PasswordRepository interface.
+type MockPasswordRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordRepositoryMockRecorder
+}
+
+// MockPasswordRepositoryMockRecorder is the mock recorder for MockPasswordRepository.
+type MockPasswordRepositoryMockRecorder struct {
+	mock *MockPasswordRepository
+}
+
+// NewMockPasswordRepository creates a new mock instance.
+func NewMockPasswordRepository(ctrl *gomock.Controller) *MockPasswordRepository {
+	mock := &MockPasswordRepository{ctrl: ctrl}
+	mock.recorder = &MockPasswordRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordRepository) EXPECT() *MockPasswordRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreatePasswordResetToken mocks base method.
+func (m *MockPasswordRepository) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordResetToken", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordResetToken indicates an expected call of CreatePasswordResetToken.
+func (mr *MockPasswordRepositoryMockRecorder) CreatePasswordResetToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordResetToken", reflect.TypeOf((*MockPasswordRepository)(nil).CreatePasswordResetToken), token)
+}
+
+// GetPasswordResetTokenForUpdate mocks base method.
+func (m *MockPasswordRepository) GetPasswordResetTokenForUpdate(tokenHash string) (*models.PasswordResetToken, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordResetTokenForUpdate", tokenHash)
+	ret0, _ := ret[0].(*models.PasswordResetToken)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPasswordResetTokenForUpdate indicates an expected call of GetPasswordResetTokenForUpdate.
+func (mr *MockPasswordRepositoryMockRecorder) GetPasswordResetTokenForUpdate(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordResetTokenForUpdate", reflect.TypeOf((*MockPasswordRepository)(nil).GetPasswordResetTokenForUpdate), tokenHash)
+}
+
+// GetUserByLogin mocks base method.
+func (m *MockPasswordRepository) GetUserByLogin(login string) (*models.User, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByLogin", login)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUserByLogin indicates an expected call of GetUserByLogin.
+func (mr *MockPasswordRepositoryMockRecorder) GetUserByLogin(login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockPasswordRepository)(nil).GetUserByLogin), login)
+}
+
+// InvalidatePasswordResetTokens mocks base method.
+func (m *MockPasswordRepository) InvalidatePasswordResetTokens(userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidatePasswordResetTokens", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidatePasswordResetTokens indicates an expected call of InvalidatePasswordResetTokens.
+func (mr *MockPasswordRepositoryMockRecorder) InvalidatePasswordResetTokens(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidatePasswordResetTokens", reflect.TypeOf((*MockPasswordRepository)(nil).InvalidatePasswordResetTokens), userID)
+}
+
+// MarkPasswordResetTokenUsed mocks base method.
+func (m *MockPasswordRepository) MarkPasswordResetTokenUsed(tokenHash string, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkPasswordResetTokenUsed", tokenHash, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkPasswordResetTokenUsed indicates an expected call of MarkPasswordResetTokenUsed.
+func (mr *MockPasswordRepositoryMockRecorder) MarkPasswordResetTokenUsed(tokenHash, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPasswordResetTokenUsed", reflect.TypeOf((*MockPasswordRepository)(nil).MarkPasswordResetTokenUsed), tokenHash, usedAt)
+}
+
+// ResetFailedLogins mocks base method.
+func (m *MockPasswordRepository) ResetFailedLogins(id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetFailedLogins", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetFailedLogins indicates an expected call of ResetFailedLogins.
+func (mr *MockPasswordRepositoryMockRecorder) ResetFailedLogins(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetFailedLogins", reflect.TypeOf((*MockPasswordRepository)(nil).ResetFailedLogins), id)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockPasswordRepository) UpdatePasswordHash(id int64, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", id, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockPasswordRepositoryMockRecorder) UpdatePasswordHash(id, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockPasswordRepository)(nil).UpdatePasswordHash), id, passwordHash)
+}
+
+// MockSessionRevoker is a mock of SessionRevoker interface.
+type MockSessionRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionRevokerMockRecorder
+}
+
+// MockSessionRevokerMockRecorder is the mock recorder for MockSessionRevoker.
+type MockSessionRevokerMockRecorder struct {
+	mock *MockSessionRevoker
+}
+
+// NewMockSessionRevoker creates a new mock instance.
+func NewMockSessionRevoker(ctrl *gomock.Controller) *MockSessionRevoker {
+	mock := &MockSessionRevoker{ctrl: ctrl}
+	mock.recorder = &MockSessionRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionRevoker) EXPECT() *MockSessionRevokerMockRecorder {
+	return m.recorder
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockSessionRevoker) RevokeOtherSessions(userID int64, sessionID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", userID, sessionID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockSessionRevokerMockRecorder) RevokeOtherSessions(userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockSessionRevoker)(nil).RevokeOtherSessions), userID, sessionID)
+}
+
+// RevokeUserSessions mocks base method.
+func (m *MockSessionRevoker) RevokeUserSessions(userID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSessions", userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserSessions indicates an expected call of RevokeUserSessions.
+func (mr *MockSessionRevokerMockRecorder) RevokeUserSessions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSessions", reflect.TypeOf((*MockSessionRevoker)(nil).RevokeUserSessions), userID)
+}
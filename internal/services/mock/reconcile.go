@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/services/reconcile.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	models "gofemart/internal/models"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockReconcileRepository is a mock of ReconcileRepository interface.
+type MockReconcileRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconcileRepositoryMockRecorder
+}
+
+// MockReconcileRepositoryMockRecorder is the mock recorder for MockReconcileRepository.
+type MockReconcileRepositoryMockRecorder struct {
+	mock *MockReconcileRepository
+}
+
+// NewMockReconcileRepository creates a new mock instance.
+func NewMockReconcileRepository(ctrl *gomock.Controller) *MockReconcileRepository {
+	mock := &MockReconcileRepository{ctrl: ctrl}
+	mock.recorder = &MockReconcileRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconcileRepository) EXPECT() *MockReconcileRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetBalanceMismatches mocks base method.
+func (m *MockReconcileRepository) GetBalanceMismatches() ([]models.BalanceMismatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceMismatches")
+	ret0, _ := ret[0].([]models.BalanceMismatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceMismatches indicates an expected call of GetBalanceMismatches.
+func (mr *MockReconcileRepositoryMockRecorder) GetBalanceMismatches() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceMismatches", reflect.TypeOf((*MockReconcileRepository)(nil).GetBalanceMismatches))
+}
+
+// RebuildBalance mocks base method.
+func (m *MockReconcileRepository) RebuildBalance(userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RebuildBalance", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RebuildBalance indicates an expected call of RebuildBalance.
+func (mr *MockReconcileRepositoryMockRecorder) RebuildBalance(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebuildBalance", reflect.TypeOf((*MockReconcileRepository)(nil).RebuildBalance), userID)
+}
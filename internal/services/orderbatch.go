@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"gofemart/internal/logger"
+	"gofemart/internal/luna"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"gofemart/internal/repositories"
+)
+
+// OrderBatchRepository интерфейс для репозитория заказов при пакетной загрузке
+type OrderBatchRepository interface {
+	GetOrdersByNumbers(numbers []string) ([]models.Order, error)
+	CreateOrders(userID int64, numbers []string) ([]string, error)
+}
+
+// OrderQueue очередь заказов на проверку в системе начислений
+type OrderQueue interface {
+	Push(ctx context.Context, order *models.Order) (bool, error)
+}
+
+// OrderBatchService сервис пакетной загрузки заказов
+type OrderBatchService struct {
+	ctx        context.Context
+	repository OrderBatchRepository
+	queue      OrderQueue
+}
+
+// NewOrderBatchService получение нового сервиса пакетной загрузки заказов
+func NewOrderBatchService(ctx context.Context, dbPool repositories.SQLQueryer, queue OrderQueue) *OrderBatchService {
+	logger.Log.Debug("NewOrderBatchService")
+	return &OrderBatchService{
+		ctx:        ctx,
+		repository: repositories.NewOrderRepository(ctx, dbPool),
+		queue:      queue,
+	}
+}
+
+// Upload загружаем пакет номеров заказов пользователя.
+// Каждый номер проверяется алгоритмом Луна, новые номера вставляются одним запросом и отправляются в очередь на проверку.
+// Повторы номеров внутри пакета отбрасываются. Возвращает результат по каждому номеру в порядке первого появления.
+func (s *OrderBatchService) Upload(userID int64, numbers []string) ([]payloads.BatchOrderResult, error) {
+	logger.Log.Debugw("Upload orders batch", "user", userID, "count", len(numbers))
+	results := make([]payloads.BatchOrderResult, 0, len(numbers))
+	resultIndex := make(map[string]int, len(numbers))
+	valid := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		if _, ok := resultIndex[number]; ok {
+			continue
+		}
+		resultIndex[number] = len(results)
+		results = append(results, payloads.BatchOrderResult{Number: number})
+		if ok, err := luna.Check(number); err != nil || !ok {
+			results[resultIndex[number]].Result = payloads.BatchOrderInvalidNumber
+			continue
+		}
+		valid = append(valid, number)
+	}
+
+	if err := s.setExistingResults(userID, valid, results, resultIndex); err != nil {
+		return nil, err
+	}
+	newNumbers := make([]string, 0, len(valid))
+	for _, number := range valid {
+		if results[resultIndex[number]].Result == "" {
+			newNumbers = append(newNumbers, number)
+		}
+	}
+	if len(newNumbers) == 0 {
+		return results, nil
+	}
+
+	created, err := s.repository.CreateOrders(userID, newNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for _, number := range created {
+		results[resultIndex[number]].Result = payloads.BatchOrderAccepted
+		s.sendToQueue(models.NewOrder(number, userID))
+	}
+	// Номера, которые успели загрузить параллельно с нами, не вставились, узнаём их владельца
+	if len(created) < len(newNumbers) {
+		if err := s.setExistingResults(userID, newNumbers, results, resultIndex); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// setExistingResults проставляем результат номерам, которые уже загружены в систему
+func (s *OrderBatchService) setExistingResults(userID int64, numbers []string, results []payloads.BatchOrderResult, resultIndex map[string]int) error {
+	existing, err := s.repository.GetOrdersByNumbers(numbers)
+	if err != nil {
+		return err
+	}
+	for _, order := range existing {
+		i, ok := resultIndex[order.Number]
+		if !ok || results[i].Result == payloads.BatchOrderAccepted {
+			continue
+		}
+		if order.UserID == userID {
+			results[i].Result = payloads.BatchOrderAlreadyUploaded
+		} else {
+			results[i].Result = payloads.BatchOrderAnotherUser
+		}
+	}
+	return nil
+}
+
+// sendToQueue отправляем заказ в очередь на проверку.
+// Заказ уже сохранён, поэтому если очередь заполнена или пул закрыт, его заберёт проверка базы данных.
+func (s *OrderBatchService) sendToQueue(order *models.Order) {
+	if _, err := s.queue.Push(s.ctx, order); err != nil {
+		logger.Log.Warnw("Order not pushed to queue", "order", order.Number, "error", err)
+	}
+}
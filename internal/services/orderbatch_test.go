@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"gofemart/internal/services/mock"
+	"reflect"
+	"testing"
+)
+
+func TestOrderBatchUpload(t *testing.T) {
+	const userID int64 = 1
+	tests := []struct {
+		name    string
+		numbers []string
+		setup   func(repo *mock.MockOrderBatchRepository, queue *mock.MockOrderQueue)
+		want    []payloads.BatchOrderResult
+		wantErr bool
+	}{
+		{
+			name:    "all_results",
+			numbers: []string{"79927398713", "12345678903", "4561261212345467", "12345678900", "abc", "79927398713"},
+			setup: func(repo *mock.MockOrderBatchRepository, queue *mock.MockOrderQueue) {
+				repo.EXPECT().
+					GetOrdersByNumbers([]string{"79927398713", "12345678903", "4561261212345467"}).
+					Return([]models.Order{
+						{Number: "12345678903", UserID: userID},
+						{Number: "4561261212345467", UserID: 2},
+					}, nil)
+				repo.EXPECT().
+					CreateOrders(userID, []string{"79927398713"}).
+					Return([]string{"79927398713"}, nil)
+				queue.EXPECT().
+					Push(gomock.Any(), gomock.Any()).
+					Return(true, nil)
+			},
+			want: []payloads.BatchOrderResult{
+				{Number: "79927398713", Result: payloads.BatchOrderAccepted},
+				{Number: "12345678903", Result: payloads.BatchOrderAlreadyUploaded},
+				{Number: "4561261212345467", Result: payloads.BatchOrderAnotherUser},
+				{Number: "12345678900", Result: payloads.BatchOrderInvalidNumber},
+				{Number: "abc", Result: payloads.BatchOrderInvalidNumber},
+			},
+		},
+		{
+			name:    "only_invalid",
+			numbers: []string{"12345678900"},
+			setup: func(repo *mock.MockOrderBatchRepository, queue *mock.MockOrderQueue) {
+				repo.EXPECT().
+					GetOrdersByNumbers([]string{}).
+					Return([]models.Order{}, nil)
+			},
+			want: []payloads.BatchOrderResult{
+				{Number: "12345678900", Result: payloads.BatchOrderInvalidNumber},
+			},
+		},
+		{
+			name:    "uploaded_concurrently",
+			numbers: []string{"79927398713", "12345678903"},
+			setup: func(repo *mock.MockOrderBatchRepository, queue *mock.MockOrderQueue) {
+				gomock.InOrder(
+					repo.EXPECT().
+						GetOrdersByNumbers([]string{"79927398713", "12345678903"}).
+						Return([]models.Order{}, nil),
+					repo.EXPECT().
+						CreateOrders(userID, []string{"79927398713", "12345678903"}).
+						Return([]string{"79927398713"}, nil),
+					repo.EXPECT().
+						GetOrdersByNumbers([]string{"79927398713", "12345678903"}).
+						Return([]models.Order{
+							{Number: "79927398713", UserID: userID},
+							{Number: "12345678903", UserID: 2},
+						}, nil),
+				)
+				queue.EXPECT().
+					Push(gomock.Any(), gomock.Any()).
+					Return(false, errors.New("pool closed"))
+			},
+			want: []payloads.BatchOrderResult{
+				{Number: "79927398713", Result: payloads.BatchOrderAccepted},
+				{Number: "12345678903", Result: payloads.BatchOrderAnotherUser},
+			},
+		},
+		{
+			name:    "create_error",
+			numbers: []string{"79927398713"},
+			setup: func(repo *mock.MockOrderBatchRepository, queue *mock.MockOrderQueue) {
+				repo.EXPECT().
+					GetOrdersByNumbers(gomock.Any()).
+					Return([]models.Order{}, nil)
+				repo.EXPECT().
+					CreateOrders(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("db error"))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "get_error",
+			numbers: []string{"79927398713"},
+			setup: func(repo *mock.MockOrderBatchRepository, queue *mock.MockOrderQueue) {
+				repo.EXPECT().
+					GetOrdersByNumbers(gomock.Any()).
+					Return(nil, errors.New("db error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockOrderBatchRepository(ctrl)
+			queue := mock.NewMockOrderQueue(ctrl)
+			tt.setup(repo, queue)
+			service := &OrderBatchService{repository: repo, queue: queue}
+
+			got, err := service.Upload(userID, tt.numbers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Upload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Upload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/notifier"
+	"gofemart/internal/repositories"
+	"time"
+)
+
+// ErrorWrongPassword Ошибка, что текущий пароль пользователя указан неверно
+var ErrorWrongPassword = errors.New("current password is incorrect")
+
+// ErrorInvalidResetToken Ошибка, что токен сброса пароля не найден, истёк или уже использован
+var ErrorInvalidResetToken = errors.New("password reset token is invalid")
+
+// ErrorResetNotSent Ошибка, что токен сброса пароля создан, но уведомление не доставлено
+var ErrorResetNotSent = errors.New("password reset notification not sent")
+
+// PasswordRepository интерфейс для репозитория пользователей при смене и сбросе пароля
+type PasswordRepository interface {
+	GetUserByLogin(login string) (*models.User, bool, error)
+	UpdatePasswordHash(id int64, passwordHash string) error
+	ResetFailedLogins(id int64) error
+	CreatePasswordResetToken(token *models.PasswordResetToken) error
+	GetPasswordResetTokenForUpdate(tokenHash string) (*models.PasswordResetToken, bool, error)
+	MarkPasswordResetTokenUsed(tokenHash string, usedAt time.Time) error
+	InvalidatePasswordResetTokens(userID int64) error
+}
+
+// SessionRevoker интерфейс для отзыва сессий пользователя после смены пароля
+type SessionRevoker interface {
+	RevokeUserSessions(userID int64) (int64, error)
+	RevokeOtherSessions(userID int64, sessionID string) (int64, error)
+}
+
+// PasswordService сервис смены и сброса пароля пользователя
+type PasswordService struct {
+	ctx             context.Context
+	transactor      Transactor
+	notifier        notifier.Notifier
+	resetExpiration time.Duration
+	legacyHashKey   string
+	// newRepository создаёт репозиторий пользователей, работающий внутри транзакции или с пулом
+	newRepository func(tx repositories.SQLQueryer) PasswordRepository
+	// newSessionRevoker создаёт репозиторий сессий, работающий внутри транзакции
+	newSessionRevoker func(tx repositories.SQLQueryer) SessionRevoker
+	repository        PasswordRepository
+}
+
+// NewPasswordService получение нового сервиса паролей.
+// Токены сброса действуют resetExpiration и доставляются через resetNotifier, legacyHashKey нужен для проверки устаревших хэшей паролей.
+func NewPasswordService(ctx context.Context, dbPool repositories.SQLExecutor, resetNotifier notifier.Notifier, resetExpiration time.Duration, legacyHashKey string) *PasswordService {
+	logger.Log.Debug("NewPasswordService")
+	return &PasswordService{
+		ctx:             ctx,
+		transactor:      repositories.NewTransactor(ctx, dbPool),
+		notifier:        resetNotifier,
+		resetExpiration: resetExpiration,
+		legacyHashKey:   legacyHashKey,
+		newRepository: func(tx repositories.SQLQueryer) PasswordRepository {
+			return repositories.NewUserRepository(ctx, tx)
+		},
+		newSessionRevoker: func(tx repositories.SQLQueryer) SessionRevoker {
+			return repositories.NewSessionRepository(ctx, tx)
+		},
+		repository: repositories.NewUserRepository(ctx, dbPool),
+	}
+}
+
+// Change меняем пароль пользователя после проверки текущего.
+// Все сессии пользователя, кроме sessionID, и неиспользованные токены сброса отзываются.
+func (s *PasswordService) Change(user *models.User, sessionID string, currentPassword string, newPassword string) error {
+	ok, _, err := user.CheckPassword(currentPassword, s.legacyHashKey)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorWrongPassword
+	}
+	changed := &models.User{ID: user.ID, Password: newPassword}
+	if err = changed.GeneratePasswordHash(); err != nil {
+		return err
+	}
+	return s.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+		repository := s.newRepository(tx)
+		if err := repository.UpdatePasswordHash(user.ID, changed.PasswordHash); err != nil {
+			return err
+		}
+		if err := repository.InvalidatePasswordResetTokens(user.ID); err != nil {
+			return err
+		}
+		revoked, err := s.newSessionRevoker(tx).RevokeOtherSessions(user.ID, sessionID)
+		if err != nil {
+			return err
+		}
+		logger.Log.Infow("Password changed", "user", user.ID, "revokedSessions", revoked)
+		return nil
+	})
+}
+
+// RequestReset создаём токен сброса пароля и отправляем его пользователю.
+// Для несуществующего логина ничего не делаем и не возвращаем ошибку, чтобы по ответу нельзя было проверить логин.
+func (s *PasswordService) RequestReset(login string) error {
+	user, exists, err := s.repository.GetUserByLogin(login)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		logger.Log.Debugw("Password reset requested for unknown login", "login", login)
+		return nil
+	}
+	reset, value, err := models.NewPasswordResetToken(user.ID, s.resetExpiration)
+	if err != nil {
+		return err
+	}
+	if err = s.repository.CreatePasswordResetToken(reset); err != nil {
+		return err
+	}
+	err = s.notifier.SendPasswordReset(s.ctx, notifier.PasswordReset{
+		UserID:    user.ID,
+		Login:     user.Login,
+		Token:     value,
+		ExpiresAt: reset.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrorResetNotSent, err)
+	}
+	return nil
+}
+
+// Reset задаём новый пароль по токену сброса.
+// Токен принимается один раз, после сброса отзываются все сессии и снимается блокировка входа.
+func (s *PasswordService) Reset(resetToken string, newPassword string) error {
+	tokenHash := models.HashPasswordResetToken(resetToken)
+	// Хэш пароля считаем до транзакции, чтобы не держать блокировку токена во время вычисления
+	changed := &models.User{Password: newPassword}
+	if err := changed.GeneratePasswordHash(); err != nil {
+		return err
+	}
+	return s.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+		repository := s.newRepository(tx)
+		stored, ok, err := repository.GetPasswordResetTokenForUpdate(tokenHash)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !ok || !stored.Usable(now) {
+			return ErrorInvalidResetToken
+		}
+		if err = repository.MarkPasswordResetTokenUsed(tokenHash, now); err != nil {
+			return err
+		}
+		if err = repository.UpdatePasswordHash(stored.UserID, changed.PasswordHash); err != nil {
+			return err
+		}
+		if err = repository.InvalidatePasswordResetTokens(stored.UserID); err != nil {
+			return err
+		}
+		if err = repository.ResetFailedLogins(stored.UserID); err != nil {
+			return err
+		}
+		revoked, err := s.newSessionRevoker(tx).RevokeUserSessions(stored.UserID)
+		if err != nil {
+			return err
+		}
+		logger.Log.Infow("Password reset", "user", stored.UserID, "revokedSessions", revoked)
+		return nil
+	})
+}
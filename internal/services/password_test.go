@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/models"
+	"gofemart/internal/notifier"
+	notifiermock "gofemart/internal/notifier/mock"
+	"gofemart/internal/repositories"
+	"gofemart/internal/services/mock"
+	"testing"
+	"time"
+)
+
+// newTestPasswordService сервис паролей с транзакцией, которая просто вызывает функцию
+func newTestPasswordService(ctrl *gomock.Controller, repository PasswordRepository, sessions SessionRevoker, resetNotifier notifier.Notifier) *PasswordService {
+	transactor := mock.NewMockTransactor(ctrl)
+	transactor.EXPECT().
+		InTransaction(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(fn func(tx repositories.SQLQueryer) error) error {
+			return fn(nil)
+		})
+	return &PasswordService{
+		transactor:      transactor,
+		notifier:        resetNotifier,
+		resetExpiration: time.Hour,
+		newRepository: func(tx repositories.SQLQueryer) PasswordRepository {
+			return repository
+		},
+		newSessionRevoker: func(tx repositories.SQLQueryer) SessionRevoker {
+			return sessions
+		},
+		repository: repository,
+	}
+}
+
+// newTestUser пользователь с паролем password
+func newTestUser(t *testing.T, password string) *models.User {
+	t.Helper()
+	user := &models.User{ID: 1, Login: "user", Password: password}
+	if err := user.GeneratePasswordHash(); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestPasswordChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repository := mock.NewMockPasswordRepository(ctrl)
+	sessions := mock.NewMockSessionRevoker(ctrl)
+	service := newTestPasswordService(ctrl, repository, sessions, nil)
+	user := newTestUser(t, "current")
+
+	if err := service.Change(user, "session", "wrong", "changed"); !errors.Is(err, ErrorWrongPassword) {
+		t.Fatalf("expected ErrorWrongPassword, got %v", err)
+	}
+
+	var passwordHash string
+	repository.EXPECT().UpdatePasswordHash(int64(1), gomock.Any()).DoAndReturn(func(id int64, hash string) error {
+		passwordHash = hash
+		return nil
+	})
+	repository.EXPECT().InvalidatePasswordResetTokens(int64(1)).Return(nil)
+	sessions.EXPECT().RevokeOtherSessions(int64(1), "session").Return(int64(2), nil)
+	if err := service.Change(user, "session", "current", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := models.VerifyPassword("changed", passwordHash, ""); !ok {
+		t.Error("expected new password hash to be stored")
+	}
+}
+
+func TestPasswordRequestReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repository := mock.NewMockPasswordRepository(ctrl)
+	resetNotifier := notifiermock.NewMockNotifier(ctrl)
+	service := newTestPasswordService(ctrl, repository, nil, resetNotifier)
+
+	// Для неизвестного логина токен не создаётся и уведомление не отправляется
+	repository.EXPECT().GetUserByLogin("unknown").Return(nil, false, nil)
+	if err := service.RequestReset("unknown"); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored *models.PasswordResetToken
+	repository.EXPECT().GetUserByLogin("user").Return(&models.User{ID: 1, Login: "user"}, true, nil)
+	repository.EXPECT().CreatePasswordResetToken(gomock.Any()).DoAndReturn(func(token *models.PasswordResetToken) error {
+		stored = token
+		return nil
+	})
+	var sent notifier.PasswordReset
+	resetNotifier.EXPECT().SendPasswordReset(gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, reset notifier.PasswordReset) error {
+		sent = reset
+		return nil
+	})
+	if err := service.RequestReset("user"); err != nil {
+		t.Fatal(err)
+	}
+	if stored.UserID != 1 || stored.TokenHash != models.HashPasswordResetToken(sent.Token) || stored.TokenHash == sent.Token {
+		t.Errorf("expected only hash of the sent token to be stored, got %+v and %+v", stored, sent)
+	}
+	if !sent.ExpiresAt.Equal(stored.ExpiresAt) || sent.Login != "user" {
+		t.Errorf("expected notification with token expiration, got %+v", sent)
+	}
+
+	repository.EXPECT().GetUserByLogin("user").Return(&models.User{ID: 1, Login: "user"}, true, nil)
+	repository.EXPECT().CreatePasswordResetToken(gomock.Any()).Return(nil)
+	resetNotifier.EXPECT().SendPasswordReset(gomock.Any(), gomock.Any()).Return(errors.New("delivery failed"))
+	if err := service.RequestReset("user"); !errors.Is(err, ErrorResetNotSent) {
+		t.Errorf("expected ErrorResetNotSent, got %v", err)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name    string
+		token   *models.PasswordResetToken
+		wantErr error
+	}{
+		{
+			name:  "reset",
+			token: &models.PasswordResetToken{UserID: 1, ExpiresAt: now.Add(time.Hour)},
+		},
+		{
+			name:    "unknown_token",
+			wantErr: ErrorInvalidResetToken,
+		},
+		{
+			name:    "expired_token",
+			token:   &models.PasswordResetToken{UserID: 1, ExpiresAt: now.Add(-time.Minute)},
+			wantErr: ErrorInvalidResetToken,
+		},
+		{
+			name:    "used_token",
+			token:   &models.PasswordResetToken{UserID: 1, ExpiresAt: now.Add(time.Hour), UsedAt: sql.NullTime{Time: now, Valid: true}},
+			wantErr: ErrorInvalidResetToken,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repository := mock.NewMockPasswordRepository(ctrl)
+			sessions := mock.NewMockSessionRevoker(ctrl)
+			tokenHash := models.HashPasswordResetToken("reset")
+			repository.EXPECT().GetPasswordResetTokenForUpdate(tokenHash).Return(tc.token, tc.token != nil, nil)
+			if tc.wantErr == nil {
+				repository.EXPECT().MarkPasswordResetTokenUsed(tokenHash, gomock.Any()).Return(nil)
+				repository.EXPECT().UpdatePasswordHash(int64(1), gomock.Any()).Return(nil)
+				repository.EXPECT().InvalidatePasswordResetTokens(int64(1)).Return(nil)
+				repository.EXPECT().ResetFailedLogins(int64(1)).Return(nil)
+				sessions.EXPECT().RevokeUserSessions(int64(1)).Return(int64(3), nil)
+			}
+			err := newTestPasswordService(ctrl, repository, sessions, nil).Reset("reset", "changed")
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
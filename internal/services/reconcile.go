@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/repositories"
+	"time"
+)
+
+// ReconcileRepository интерфейс репозитория для сверки сохранённых балансов с транзакциями
+type ReconcileRepository interface {
+	GetBalanceMismatches() ([]models.BalanceMismatch, error)
+	RebuildBalance(userID int64) error
+}
+
+// BalanceReconciler сверяет таблицу балансов t_balance с транзакциями t_account и пересчитывает расходящиеся балансы
+type BalanceReconciler struct {
+	ctx        context.Context
+	transactor Transactor
+	// newRepository создаёт репозиторий сверки, работающий внутри транзакции
+	newRepository func(tx repositories.SQLQueryer) ReconcileRepository
+}
+
+// NewBalanceReconciler создаёт сервис сверки балансов
+func NewBalanceReconciler(ctx context.Context, dbPool repositories.SQLExecutor) *BalanceReconciler {
+	return &BalanceReconciler{
+		ctx:        ctx,
+		transactor: repositories.NewTransactor(ctx, dbPool),
+		newRepository: func(tx repositories.SQLQueryer) ReconcileRepository {
+			return getAccountRepository(ctx, tx)
+		},
+	}
+}
+
+// Reconcile находит балансы, не совпадающие с транзакциями, пересчитывает их и возвращает найденные расхождения.
+// Каждый баланс пересчитывается в отдельной транзакции, ошибка пересчёта одного пользователя не останавливает остальных.
+func (r *BalanceReconciler) Reconcile() ([]models.BalanceMismatch, error) {
+	var mismatches []models.BalanceMismatch
+	err := r.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+		var err error
+		mismatches, err = r.newRepository(tx).GetBalanceMismatches()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, mismatch := range mismatches {
+		logger.Log.Warnw("Balance mismatch",
+			"userID", mismatch.UserID,
+			"current", mismatch.Current.String(),
+			"withdrawn", mismatch.Withdrawn.String(),
+			"ledgerCurrent", mismatch.LedgerCurrent.String(),
+			"ledgerWithdrawn", mismatch.LedgerWithdrawn.String(),
+		)
+		err := r.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+			return r.newRepository(tx).RebuildBalance(mismatch.UserID)
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return mismatches, errors.Join(errs...)
+}
+
+// Run выполняет сверку периодически с указанным интервалом, пока не будет закрыт контекст.
+// Если onStart, сверка выполняется ещё и сразу. Если интервал не положительный, периодическая сверка отключена.
+func (r *BalanceReconciler) Run(dur time.Duration, onStart bool) {
+	logger.Log.Infow("Run balance reconciler", "duration", dur, "onStart", onStart)
+	if onStart {
+		r.reconcile()
+	}
+	if dur <= 0 {
+		return
+	}
+	ticker := time.NewTicker(dur)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcile()
+		}
+	}
+}
+
+// reconcile выполняет сверку и логирует её результат
+func (r *BalanceReconciler) reconcile() {
+	mismatches, err := r.Reconcile()
+	if err != nil {
+		logger.Log.Error(err)
+	}
+	logger.Log.Infow("Balance reconciliation finished", "mismatches", len(mismatches))
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/models"
+	"gofemart/internal/repositories"
+	"gofemart/internal/services/mock"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mismatches := []models.BalanceMismatch{
+		{UserID: 1, Current: mustMoney(t, "10"), LedgerCurrent: mustMoney(t, "10.01")},
+		{UserID: 2, Withdrawn: mustMoney(t, "5"), LedgerWithdrawn: mustMoney(t, "0")},
+	}
+
+	tests := []struct {
+		name           string
+		wantMismatches int
+		wantErr        error
+		setup          func() ReconcileRepository
+	}{
+		{
+			name:           "no_mismatches",
+			wantMismatches: 0,
+			setup: func() ReconcileRepository {
+				repo := mock.NewMockReconcileRepository(ctrl)
+				repo.EXPECT().GetBalanceMismatches().Return(nil, nil)
+				repo.EXPECT().RebuildBalance(gomock.Any()).Times(0)
+				return repo
+			},
+		},
+		{
+			name:           "mismatches_rebuilt",
+			wantMismatches: 2,
+			setup: func() ReconcileRepository {
+				repo := mock.NewMockReconcileRepository(ctrl)
+				repo.EXPECT().GetBalanceMismatches().Return(mismatches, nil)
+				repo.EXPECT().RebuildBalance(int64(1)).Return(nil)
+				repo.EXPECT().RebuildBalance(int64(2)).Return(nil)
+				return repo
+			},
+		},
+		{
+			name:           "rebuild_error_does_not_stop_others",
+			wantMismatches: 2,
+			wantErr:        sql.ErrConnDone,
+			setup: func() ReconcileRepository {
+				repo := mock.NewMockReconcileRepository(ctrl)
+				repo.EXPECT().GetBalanceMismatches().Return(mismatches, nil)
+				repo.EXPECT().RebuildBalance(int64(1)).Return(sql.ErrConnDone)
+				repo.EXPECT().RebuildBalance(int64(2)).Return(nil)
+				return repo
+			},
+		},
+		{
+			name:           "mismatches_error",
+			wantMismatches: 0,
+			wantErr:        sql.ErrConnDone,
+			setup: func() ReconcileRepository {
+				repo := mock.NewMockReconcileRepository(ctrl)
+				repo.EXPECT().GetBalanceMismatches().Return(nil, sql.ErrConnDone)
+				repo.EXPECT().RebuildBalance(gomock.Any()).Times(0)
+				return repo
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.setup()
+			transactor := mock.NewMockTransactor(ctrl)
+			transactor.EXPECT().
+				InTransaction(gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(fn func(tx repositories.SQLQueryer) error) error {
+					return fn(nil)
+				})
+			reconciler := &BalanceReconciler{
+				ctx:        context.Background(),
+				transactor: transactor,
+				newRepository: func(tx repositories.SQLQueryer) ReconcileRepository {
+					return repo
+				},
+			}
+
+			got, err := reconciler.Reconcile()
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
+			} else if tt.wantErr == nil && err != nil {
+				t.Errorf("Reconcile() error = %v, expect no errors", err)
+			}
+			if len(got) != tt.wantMismatches {
+				t.Errorf("Reconcile() mismatches = %d, want %d", len(got), tt.wantMismatches)
+			}
+		})
+	}
+}
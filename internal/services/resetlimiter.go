@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ResetLimiterConfig настройки ограничения запросов сброса пароля
+type ResetLimiterConfig struct {
+	// Window окно, в котором считаются запросы сброса пароля
+	Window time.Duration
+	// PerLogin количество запросов в окне для одного логина с любых адресов, 0 отключает ограничение
+	PerLogin int
+	// PerIP количество запросов в окне с одного адреса для любых логинов, 0 отключает ограничение
+	PerIP int
+}
+
+// ResetLimiter ограничение запросов сброса пароля.
+// Запросы считаются в памяти отдельно от попыток входа, в скользящем окне по логину и по адресу клиента,
+// поэтому смена адреса не позволяет засыпать пользователя уведомлениями, а запросы сброса не задерживают вход.
+type ResetLimiter struct {
+	cnf   ResetLimiterConfig
+	mutex sync.Mutex
+	// byLogin и byIP время запросов в окне по логину и по адресу клиента
+	byLogin map[string][]time.Time
+	byIP    map[string][]time.Time
+	now     func() time.Time
+}
+
+// NewResetLimiter создаём ограничение запросов сброса пароля
+func NewResetLimiter(cnf ResetLimiterConfig) *ResetLimiter {
+	return &ResetLimiter{
+		cnf:     cnf,
+		byLogin: make(map[string][]time.Time),
+		byIP:    make(map[string][]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Allow учитываем запрос сброса пароля для login с адреса ip.
+// Возвращает 0, если запрос разрешён, иначе сколько нужно подождать, отклонённый запрос не учитывается.
+func (l *ResetLimiter) Allow(login string, ip string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	loginRequests := l.inWindow(l.byLogin[login], now)
+	ipRequests := l.inWindow(l.byIP[ip], now)
+	wait := max(l.wait(loginRequests, l.cnf.PerLogin, now), l.wait(ipRequests, l.cnf.PerIP, now))
+	if wait > 0 {
+		return wait
+	}
+	l.byLogin[login] = append(loginRequests, now)
+	l.byIP[ip] = append(ipRequests, now)
+	return 0
+}
+
+// wait через сколько освободится место для запроса, если в окне уже limit запросов
+func (l *ResetLimiter) wait(requests []time.Time, limit int, now time.Time) time.Duration {
+	if limit <= 0 || len(requests) < limit {
+		return 0
+	}
+	return requests[len(requests)-limit].Add(l.cnf.Window).Sub(now)
+}
+
+// inWindow оставляем запросы, которые попадают в окно
+func (l *ResetLimiter) inWindow(requests []time.Time, now time.Time) []time.Time {
+	from := now.Add(-l.cnf.Window)
+	i := 0
+	for i < len(requests) && !requests[i].After(from) {
+		i++
+	}
+	return requests[i:]
+}
+
+// cleanup удаляем запросы, которые вышли из окна
+func (l *ResetLimiter) cleanup() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	for _, requests := range []map[string][]time.Time{l.byLogin, l.byIP} {
+		for key, times := range requests {
+			if times = l.inWindow(times, now); len(times) == 0 {
+				delete(requests, key)
+			} else {
+				requests[key] = times
+			}
+		}
+	}
+}
+
+// RunCleanup периодически удаляем устаревшие запросы, пока не завершится ctx
+func (l *ResetLimiter) RunCleanup(ctx context.Context) {
+	interval := l.cnf.Window
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestResetLimiter ограничение запросов сброса пароля с управляемым временем
+func newTestResetLimiter(now *time.Time) *ResetLimiter {
+	limiter := NewResetLimiter(ResetLimiterConfig{
+		Window:   time.Hour,
+		PerLogin: 2,
+		PerIP:    3,
+	})
+	limiter.now = func() time.Time {
+		return *now
+	}
+	return limiter
+}
+
+func TestResetLimiterPerLogin(t *testing.T) {
+	now := time.Now()
+	limiter := newTestResetLimiter(&now)
+	// Смена адреса не снимает ограничение для логина
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if wait := limiter.Allow("user", ip); wait != 0 {
+			t.Fatalf("request %d: expected request to be allowed, got wait %s", i+1, wait)
+		}
+		now = now.Add(10 * time.Minute)
+	}
+	if wait := limiter.Allow("user", "10.0.0.3"); wait != 40*time.Minute {
+		t.Errorf("expected wait until the first request leaves window, got %s", wait)
+	}
+	if wait := limiter.Allow("other", "10.0.0.3"); wait != 0 {
+		t.Errorf("expected other login to be allowed, got wait %s", wait)
+	}
+
+	now = now.Add(40 * time.Minute)
+	if wait := limiter.Allow("user", "10.0.0.3"); wait != 0 {
+		t.Errorf("expected request to be allowed after window, got wait %s", wait)
+	}
+}
+
+func TestResetLimiterPerIP(t *testing.T) {
+	now := time.Now()
+	limiter := newTestResetLimiter(&now)
+	// Перебор логинов с одного адреса ограничивается отдельно
+	for i, login := range []string{"a", "b", "c"} {
+		if wait := limiter.Allow(login, "10.0.0.1"); wait != 0 {
+			t.Fatalf("request %d: expected request to be allowed, got wait %s", i+1, wait)
+		}
+	}
+	if wait := limiter.Allow("d", "10.0.0.1"); wait != time.Hour {
+		t.Errorf("expected wait for the address, got %s", wait)
+	}
+	// Отклонённый запрос не учитывается для логина
+	if wait := limiter.Allow("d", "10.0.0.2"); wait != 0 {
+		t.Errorf("expected rejected request not to be counted, got wait %s", wait)
+	}
+
+	now = now.Add(time.Hour)
+	limiter.cleanup()
+	if len(limiter.byLogin) != 0 || len(limiter.byIP) != 0 {
+		t.Errorf("expected requests outside of window to be removed, got %d logins and %d addresses", len(limiter.byLogin), len(limiter.byIP))
+	}
+}
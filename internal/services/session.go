@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gofemart/internal/logger"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"gofemart/internal/repositories"
+	"time"
+)
+
+// sessionCleanupInterval период удаления истёкших сессий
+const sessionCleanupInterval = time.Hour
+
+// ErrorInvalidRefreshToken Ошибка, что токен обновления не найден, истёк или его сессия отозвана
+var ErrorInvalidRefreshToken = errors.New("refresh token is invalid")
+
+// ErrorRefreshTokenReused Ошибка, что токен обновления уже был обменян, сессия отозвана как скомпрометированная
+var ErrorRefreshTokenReused = errors.New("refresh token reused, session revoked")
+
+// SessionRepository интерфейс для репозитория сессий пользователей
+type SessionRepository interface {
+	CreateSession(session *models.Session) error
+	GetSessionForUpdate(id string) (*models.Session, bool, error)
+	RefreshSession(id string, refreshedAt time.Time, expiresAt time.Time) error
+	RevokeSession(id string, userID int64) (bool, error)
+	RevokeUserSessions(userID int64) (int64, error)
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshTokenForUpdate(tokenHash string) (*models.RefreshToken, bool, error)
+	MarkRefreshTokenUsed(tokenHash string, usedAt time.Time) error
+}
+
+// TokenGenerator генератор токенов доступа
+type TokenGenerator interface {
+	Generate(user *models.User, sessionID string) (string, error)
+	Expiration() time.Duration
+}
+
+// SessionService сервис сессий пользователей: выдача, обновление и отзыв токенов
+type SessionService struct {
+	transactor        Transactor
+	generator         TokenGenerator
+	refreshExpiration time.Duration
+	// newRepository создаёт репозиторий сессий, работающий внутри транзакции или с пулом
+	newRepository func(tx repositories.SQLQueryer) SessionRepository
+	repository    SessionRepository
+}
+
+// NewSessionService получение нового сервиса сессий.
+// Токены обновления действуют refreshExpiration, каждое обновление продлевает сессию на этот срок.
+func NewSessionService(ctx context.Context, dbPool repositories.SQLExecutor, generator TokenGenerator, refreshExpiration time.Duration) *SessionService {
+	logger.Log.Debug("NewSessionService")
+	return &SessionService{
+		transactor:        repositories.NewTransactor(ctx, dbPool),
+		generator:         generator,
+		refreshExpiration: refreshExpiration,
+		newRepository: func(tx repositories.SQLQueryer) SessionRepository {
+			return repositories.NewSessionRepository(ctx, tx)
+		},
+		repository: repositories.NewSessionRepository(ctx, dbPool),
+	}
+}
+
+// Start создаём новую сессию пользователя и выдаём токен доступа и токен обновления
+func (s *SessionService) Start(user *models.User) (*payloads.Authorization, error) {
+	logger.Log.Debugw("Start session", "user", user.ID)
+	session, err := models.NewSession(user.ID, s.refreshExpiration)
+	if err != nil {
+		return nil, err
+	}
+	var refreshToken string
+	err = s.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+		repository := s.newRepository(tx)
+		if err := repository.CreateSession(session); err != nil {
+			return err
+		}
+		refreshToken, err = s.createRefreshToken(repository, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.authorization(user, session.ID, refreshToken)
+}
+
+// Refresh обмениваем токен обновления на новую пару токенов.
+// Каждый токен обновления принимается один раз, повторное использование отзывает всю сессию.
+func (s *SessionService) Refresh(refreshToken string) (*payloads.Authorization, error) {
+	tokenHash := models.HashRefreshToken(refreshToken)
+	var session *models.Session
+	var newRefreshToken string
+	reused := false
+	err := s.transactor.InTransaction(func(tx repositories.SQLQueryer) error {
+		repository := s.newRepository(tx)
+		stored, ok, err := repository.GetRefreshTokenForUpdate(tokenHash)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrorInvalidRefreshToken
+		}
+		session, ok, err = repository.GetSessionForUpdate(stored.SessionID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !ok || !session.Active(now) {
+			return ErrorInvalidRefreshToken
+		}
+		if stored.UsedAt.Valid {
+			// Токен уже обменяли, значит его копия у кого-то ещё, отзываем сессию и фиксируем отзыв
+			logger.Log.Warnw("Refresh token reused, revoking session", "user", session.UserID, "session", session.ID)
+			reused = true
+			_, err = repository.RevokeSession(session.ID, session.UserID)
+			return err
+		}
+		if !now.Before(stored.ExpiresAt) {
+			return ErrorInvalidRefreshToken
+		}
+		if err = repository.MarkRefreshTokenUsed(tokenHash, now); err != nil {
+			return err
+		}
+		session.RefreshedAt = now
+		session.ExpiresAt = now.Add(s.refreshExpiration)
+		if err = repository.RefreshSession(session.ID, session.RefreshedAt, session.ExpiresAt); err != nil {
+			return err
+		}
+		newRefreshToken, err = s.createRefreshToken(repository, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrorRefreshTokenReused
+	}
+	return s.authorization(&models.User{ID: session.UserID}, session.ID, newRefreshToken)
+}
+
+// Logout отзываем сессию пользователя
+func (s *SessionService) Logout(userID int64, sessionID string) error {
+	logger.Log.Debugw("Logout", "user", userID, "session", sessionID)
+	_, err := s.repository.RevokeSession(sessionID, userID)
+	return err
+}
+
+// LogoutAll отзываем все сессии пользователя и возвращаем их количество
+func (s *SessionService) LogoutAll(userID int64) (int64, error) {
+	logger.Log.Debugw("Logout everywhere", "user", userID)
+	return s.repository.RevokeUserSessions(userID)
+}
+
+// createRefreshToken создаём токен обновления, действующий до конца сессии
+func (s *SessionService) createRefreshToken(repository SessionRepository, session *models.Session) (string, error) {
+	token, value, err := models.NewRefreshToken(session.ID, session.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	if err = repository.CreateRefreshToken(token); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// authorization собираем ответ с новым токеном доступа
+func (s *SessionService) authorization(user *models.User, sessionID string, refreshToken string) (*payloads.Authorization, error) {
+	accessToken, err := s.generator.Generate(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &payloads.Authorization{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.generator.Expiration().Seconds()),
+	}, nil
+}
+
+// RunSessionCleanup периодически удаляем истёкшие сессии с их токенами обновления и истёкшие токены сброса пароля, пока не завершится ctx
+func RunSessionCleanup(ctx context.Context, dbPool repositories.SQLQueryer) {
+	logger.Log.Infow("Run sessions cleanup", "duration", sessionCleanupInterval)
+	repository := repositories.NewSessionRepository(ctx, dbPool)
+	userRepository := repositories.NewUserRepository(ctx, dbPool)
+	ticker := time.NewTicker(sessionCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := repository.DeleteExpired()
+			if err != nil {
+				logger.Log.Error(err)
+				continue
+			}
+			logger.Log.Infow("Expired sessions deleted", "count", deleted)
+			deleted, err = userRepository.DeleteExpiredPasswordResetTokens()
+			if err != nil {
+				logger.Log.Error(err)
+				continue
+			}
+			logger.Log.Infow("Expired password reset tokens deleted", "count", deleted)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"gofemart/internal/models"
+	"gofemart/internal/repositories"
+	"gofemart/internal/services/mock"
+	"testing"
+	"time"
+)
+
+// newTestSessionService сервис сессий с транзакцией, которая просто вызывает функцию
+func newTestSessionService(ctrl *gomock.Controller, repository SessionRepository) *SessionService {
+	transactor := mock.NewMockTransactor(ctrl)
+	transactor.EXPECT().
+		InTransaction(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(fn func(tx repositories.SQLQueryer) error) error {
+			return fn(nil)
+		})
+	generator := mock.NewMockTokenGenerator(ctrl)
+	generator.EXPECT().
+		Generate(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(user *models.User, sessionID string) (string, error) {
+			return "access-" + sessionID, nil
+		})
+	generator.EXPECT().Expiration().AnyTimes().Return(15 * time.Minute)
+	return &SessionService{
+		transactor:        transactor,
+		generator:         generator,
+		refreshExpiration: time.Hour,
+		newRepository: func(tx repositories.SQLQueryer) SessionRepository {
+			return repository
+		},
+		repository: repository,
+	}
+}
+
+func TestSessionStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repository := mock.NewMockSessionRepository(ctrl)
+	var session *models.Session
+	repository.EXPECT().CreateSession(gomock.Any()).DoAndReturn(func(s *models.Session) error {
+		session = s
+		return nil
+	})
+	var stored *models.RefreshToken
+	repository.EXPECT().CreateRefreshToken(gomock.Any()).DoAndReturn(func(token *models.RefreshToken) error {
+		stored = token
+		return nil
+	})
+
+	authorization, err := newTestSessionService(ctrl, repository).Start(&models.User{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.UserID != 1 || authorization.Token != "access-"+session.ID {
+		t.Errorf("expected access token for session %s, got %+v", session.ID, authorization)
+	}
+	if authorization.ExpiresIn != int64((15 * time.Minute).Seconds()) {
+		t.Errorf("expected expires_in of access token, got %d", authorization.ExpiresIn)
+	}
+	if stored.SessionID != session.ID || stored.TokenHash != models.HashRefreshToken(authorization.RefreshToken) {
+		t.Errorf("expected only refresh token hash to be stored for session, got %+v", stored)
+	}
+	if stored.TokenHash == authorization.RefreshToken {
+		t.Error("expected refresh token not to be stored in plain text")
+	}
+}
+
+func TestSessionRefresh(t *testing.T) {
+	now := time.Now()
+	activeSession := func() *models.Session {
+		return &models.Session{ID: "session", UserID: 1, ExpiresAt: now.Add(time.Hour)}
+	}
+	cases := []struct {
+		name    string
+		setup   func(repository *mock.MockSessionRepository)
+		wantErr error
+	}{
+		{
+			name: "rotated",
+			setup: func(repository *mock.MockSessionRepository) {
+				repository.EXPECT().GetRefreshTokenForUpdate(models.HashRefreshToken("refresh")).
+					Return(&models.RefreshToken{SessionID: "session", ExpiresAt: now.Add(time.Hour)}, true, nil)
+				repository.EXPECT().GetSessionForUpdate("session").Return(activeSession(), true, nil)
+				repository.EXPECT().MarkRefreshTokenUsed(models.HashRefreshToken("refresh"), gomock.Any()).Return(nil)
+				repository.EXPECT().RefreshSession("session", gomock.Any(), gomock.Any()).Return(nil)
+				repository.EXPECT().CreateRefreshToken(gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "unknown_token",
+			setup: func(repository *mock.MockSessionRepository) {
+				repository.EXPECT().GetRefreshTokenForUpdate(gomock.Any()).Return(nil, false, nil)
+			},
+			wantErr: ErrorInvalidRefreshToken,
+		},
+		{
+			name: "expired_token",
+			setup: func(repository *mock.MockSessionRepository) {
+				repository.EXPECT().GetRefreshTokenForUpdate(gomock.Any()).
+					Return(&models.RefreshToken{SessionID: "session", ExpiresAt: now.Add(-time.Minute)}, true, nil)
+				repository.EXPECT().GetSessionForUpdate("session").Return(activeSession(), true, nil)
+			},
+			wantErr: ErrorInvalidRefreshToken,
+		},
+		{
+			name: "revoked_session",
+			setup: func(repository *mock.MockSessionRepository) {
+				session := activeSession()
+				session.RevokedAt = sql.NullTime{Time: now, Valid: true}
+				repository.EXPECT().GetRefreshTokenForUpdate(gomock.Any()).
+					Return(&models.RefreshToken{SessionID: "session", ExpiresAt: now.Add(time.Hour)}, true, nil)
+				repository.EXPECT().GetSessionForUpdate("session").Return(session, true, nil)
+			},
+			wantErr: ErrorInvalidRefreshToken,
+		},
+		{
+			name: "reused_token_revokes_session",
+			setup: func(repository *mock.MockSessionRepository) {
+				repository.EXPECT().GetRefreshTokenForUpdate(gomock.Any()).
+					Return(&models.RefreshToken{SessionID: "session", ExpiresAt: now.Add(time.Hour), UsedAt: sql.NullTime{Time: now, Valid: true}}, true, nil)
+				repository.EXPECT().GetSessionForUpdate("session").Return(activeSession(), true, nil)
+				repository.EXPECT().RevokeSession("session", int64(1)).Return(true, nil)
+			},
+			wantErr: ErrorRefreshTokenReused,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repository := mock.NewMockSessionRepository(ctrl)
+			tc.setup(repository)
+			authorization, err := newTestSessionService(ctrl, repository).Refresh("refresh")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if authorization.Token != "access-session" || authorization.RefreshToken == "" || authorization.RefreshToken == "refresh" {
+				t.Errorf("expected new token pair for the same session, got %+v", authorization)
+			}
+		})
+	}
+}
+
+func TestSessionLogout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repository := mock.NewMockSessionRepository(ctrl)
+	repository.EXPECT().RevokeSession("session", int64(1)).Return(true, nil)
+	repository.EXPECT().RevokeUserSessions(int64(1)).Return(int64(3), nil)
+	service := newTestSessionService(ctrl, repository)
+	if err := service.Logout(1, "session"); err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := service.LogoutAll(1)
+	if err != nil || revoked != 3 {
+		t.Errorf("expected 3 revoked sessions, got %d, %v", revoked, err)
+	}
+}
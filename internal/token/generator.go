@@ -1,7 +1,6 @@
 package token
 
 import (
-	"crypto/rsa"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"gofemart/internal/models"
@@ -11,50 +10,69 @@ import (
 
 // JWTGenerator класс использования jwt токенов
 type JWTGenerator struct {
-	pkey       *rsa.PrivateKey
-	pubKey     *rsa.PublicKey
+	keys       *KeyRing
 	expiration time.Duration
-	method     jwt.SigningMethod
 	issuer     string
 }
 
-// NewJWTGenerator создание нового генератора
-func NewJWTGenerator(pkey *rsa.PrivateKey, pubKey *rsa.PublicKey, expiration time.Duration) *JWTGenerator {
+// NewJWTGenerator создание нового генератора, токены подписываются активным ключом набора keys
+func NewJWTGenerator(keys *KeyRing, expiration time.Duration) *JWTGenerator {
 	return &JWTGenerator{
-		pkey:       pkey,
-		pubKey:     pubKey,
+		keys:       keys,
 		expiration: expiration,
-		method:     jwt.SigningMethodRS256,
 		issuer:     "gofemart",
 	}
 }
 
-// Generate создание нового токена для пользователя
-func (g *JWTGenerator) Generate(user *models.User) (string, error) {
+// Generate создание нового токена доступа для пользователя в сессии sessionID, идентификатор сессии записывается в jti.
+// Идентификатор ключа подписи записывается в заголовок kid.
+func (g *JWTGenerator) Generate(user *models.User, sessionID string) (string, error) {
+	now := time.Now()
 	claims := jwt.RegisteredClaims{
 		Issuer:    g.issuer,
 		Subject:   strconv.FormatInt(user.ID, 10),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(g.expiration)),
+		ID:        sessionID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(g.expiration)),
 	}
 
-	token := jwt.NewWithClaims(g.method, claims)
-	return token.SignedString(g.pkey)
+	key := g.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
 
-// Parse парсим полученный токен
+// Expiration время жизни токена доступа
+func (g *JWTGenerator) Expiration() time.Duration {
+	return g.expiration
+}
+
+// Parse парсим полученный токен, утверждения токена имеют тип *jwt.RegisteredClaims.
+// Ключ проверки выбирается по заголовку kid, токены без kid проверяются активным ключом.
 func (g *JWTGenerator) Parse(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(
+	return jwt.ParseWithClaims(
 		tokenString,
+		&jwt.RegisteredClaims{},
 		func(token *jwt.Token) (interface{}, error) {
-			// Don't forget to validate the alg is what you expect:
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			key := g.keys.Active()
+			if kid, ok := token.Header["kid"]; ok {
+				id, isString := kid.(string)
+				if !isString {
+					return nil, fmt.Errorf("%w: %v", ErrorUnknownKeyID, kid)
+				}
+				if key, ok = g.keys.Get(id); !ok {
+					return nil, fmt.Errorf("%w: %s", ErrorUnknownKeyID, id)
+				}
+			}
+			// Алгоритм токена должен совпадать с алгоритмом ключа, иначе ключ одного типа подставят в другой алгоритм
+			if token.Method.Alg() != key.Method.Alg() {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
 
-			return g.pubKey, nil
+			return key.Public, nil
 		},
 		jwt.WithIssuer(g.issuer),
 		jwt.WithExpirationRequired(),
-		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithValidMethods(g.keys.Methods()),
 	)
 }
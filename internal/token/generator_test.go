@@ -3,6 +3,7 @@ package token
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"github.com/golang-jwt/jwt/v5"
 	"gofemart/internal/models"
 	"strconv"
 	"testing"
@@ -11,7 +12,14 @@ import (
 
 func TestGenerateAndParse(t *testing.T) {
 	pkey, _ := rsa.GenerateKey(rand.Reader, 2048)
-	pubKey := &pkey.PublicKey
+	key, err := NewSigningKey("", pkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeyRing(key.ID, key)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	tests := []struct {
 		name      string
@@ -21,13 +29,13 @@ func TestGenerateAndParse(t *testing.T) {
 	}{
 		{
 			name:      "valid",
-			generator: NewJWTGenerator(pkey, pubKey, time.Hour),
+			generator: NewJWTGenerator(keys, time.Hour),
 			user:      &models.User{ID: 1},
 			wantErr:   false,
 		},
 		{
 			name:      "expired",
-			generator: NewJWTGenerator(pkey, pubKey, -time.Hour),
+			generator: NewJWTGenerator(keys, -time.Hour),
 			user:      &models.User{ID: 2},
 			wantErr:   true,
 		},
@@ -35,7 +43,7 @@ func TestGenerateAndParse(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tokenString, err := tt.generator.Generate(tt.user)
+			tokenString, err := tt.generator.Generate(tt.user, "session")
 			if err != nil {
 				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -59,6 +67,9 @@ func TestGenerateAndParse(t *testing.T) {
 				if intSubject != tt.user.ID {
 					t.Errorf("Parse().Subject = %v, want %v", subject, tt.user.ID)
 				}
+				if claims, ok := token.Claims.(*jwt.RegisteredClaims); !ok || claims.ID != "session" {
+					t.Errorf("Parse().ID = %v, want session", token.Claims)
+				}
 			}
 		})
 	}
@@ -0,0 +1,306 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	config "gofemart/internal/configuration"
+	"gofemart/internal/helpers"
+	"gofemart/internal/logger"
+	"gofemart/internal/payloads"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// keyFileExt расширение файлов ключей в каталоге, имя файла без расширения становится kid
+const keyFileExt = ".pem"
+
+// jwksCacheControl заголовок кэширования набора ключей, новые ключи должны появиться у клиентов до начала подписи ими
+const jwksCacheControl = "public, max-age=300"
+
+// ErrorUnknownKeyID Ошибка, что ключ с идентификатором из токена не найден в наборе
+var ErrorUnknownKeyID = errors.New("unknown key id")
+
+// ErrorNoSigningKey Ошибка, что в наборе нет приватного ключа для подписи
+var ErrorNoSigningKey = errors.New("no signing key")
+
+// ErrorUnsupportedKey Ошибка, что тип ключа не поддерживается
+var ErrorUnsupportedKey = errors.New("unsupported key type")
+
+// SigningKey ключ подписи токенов.
+// Ключ без приватной части принимается только для проверки подписи.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeyRing набор ключей подписи токенов.
+// Новые токены подписываются активным ключом, остальные ключи принимаются для проверки, пока их не уберут из набора.
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewKeyRing создаём набор ключей, активным становится ключ activeID.
+// Если activeID пустой, активным становится ключ с приватной частью и наибольшим идентификатором.
+func NewKeyRing(activeID string, keys ...*SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]*SigningKey, len(keys))}
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := ring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %s", key.ID)
+		}
+		ring.keys[key.ID] = key
+		ids = append(ids, key.ID)
+	}
+	if activeID == "" {
+		sort.Strings(ids)
+		for i := len(ids) - 1; i >= 0; i-- {
+			if ring.keys[ids[i]].Private != nil {
+				activeID = ids[i]
+				break
+			}
+		}
+	}
+	active, ok := ring.keys[activeID]
+	if !ok || active.Private == nil {
+		return nil, fmt.Errorf("%w: %s", ErrorNoSigningKey, activeID)
+	}
+	ring.active = active
+	return ring, nil
+}
+
+// LoadKeyRing загружаем набор ключей.
+// Если каталог keysDir задан, ключи читаются из файлов *.pem в нём, иначе используется единственный RSA ключ из конфигурации.
+func LoadKeyRing(keysDir string, activeID string, legacyKeys *config.JWTKeys) (*KeyRing, error) {
+	if keysDir == "" {
+		key, err := NewSigningKey("", legacyKeys.Private)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyRing(key.ID, key)
+	}
+	files, err := filepath.Glob(filepath.Join(keysDir, "*"+keyFileExt))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*SigningKey, 0, len(files))
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseSigningKey(strings.TrimSuffix(filepath.Base(file), keyFileExt), body)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", file, err)
+		}
+		keys = append(keys, key)
+	}
+	ring, err := NewKeyRing(activeID, keys...)
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Infow("JWT keys loaded", "dir", keysDir, "count", len(keys), "active", ring.active.ID)
+	return ring, nil
+}
+
+// ParseSigningKey разбираем ключ в формате PEM: приватный PKCS#8, PKCS#1, SEC 1 или публичный PKIX, PKCS#1
+func ParseSigningKey(id string, body []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrorUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(id, parsed)
+}
+
+// NewSigningKey создаём ключ из приватного или публичного ключа RSA, ECDSA P-256 или Ed25519.
+// Алгоритм подписи определяется типом ключа, пустой id заменяется отпечатком ключа по RFC 7638.
+func NewSigningKey(id string, parsed any) (*SigningKey, error) {
+	key := &SigningKey{ID: id}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		parsed = signer.Public()
+	}
+	switch public := parsed.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: ECDSA curve %s", ErrorUnsupportedKey, public.Curve.Params().Name)
+		}
+		key.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrorUnsupportedKey, parsed)
+	}
+	key.Public = parsed
+	if key.ID == "" {
+		thumbprint, err := key.thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = thumbprint
+	}
+	return key, nil
+}
+
+// Active ключ для подписи новых токенов
+func (r *KeyRing) Active() *SigningKey {
+	return r.active
+}
+
+// Get ключ для проверки подписи по идентификатору из заголовка kid
+func (r *KeyRing) Get(id string) (*SigningKey, bool) {
+	key, ok := r.keys[id]
+	return key, ok
+}
+
+// Methods алгоритмы подписи ключей набора
+func (r *KeyRing) Methods() []string {
+	methods := make([]string, 0, 3)
+	seen := make(map[string]bool, 3)
+	for _, key := range r.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS публичные ключи набора в формате JSON Web Key Set, отсортированные по идентификатору
+func (r *KeyRing) JWKS() payloads.JWKS {
+	jwks := payloads.JWKS{Keys: make([]payloads.JWK, 0, len(r.keys))}
+	for _, key := range r.keys {
+		jwk := key.jwk()
+		jwk.ID = key.ID
+		jwk.Use = "sig"
+		jwk.Algorithm = key.Method.Alg()
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].ID < jwks.Keys[j].ID
+	})
+	return jwks
+}
+
+// JWKSHandler отдаёт публичные ключи для проверки токенов другими сервисами.
+// @Summary Набор ключей проверки токенов
+// @Description Публичные ключи в формате JWKS, ключ для проверки токена выбирается по заголовку kid.
+// @Tags Служебные
+// @Produce json
+// @Success 200 {object} payloads.JWKS
+// @Router /.well-known/jwks.json [get]
+func (r *KeyRing) JWKSHandler(response http.ResponseWriter, _ *http.Request) {
+	body, err := json.Marshal(r.JWKS())
+	if err != nil {
+		helpers.SetInternalError(err, response)
+		return
+	}
+	response.Header().Set("Cache-Control", jwksCacheControl)
+	if err := helpers.SetHTTPResponse(response, http.StatusOK, body); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// jwk публичная часть ключа в формате JWK без общих полей
+func (k *SigningKey) jwk() payloads.JWK {
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		return payloads.JWK{
+			KeyType:  "RSA",
+			Modulus:  encodeBase64(public.N.Bytes()),
+			Exponent: encodeBase64(big.NewInt(int64(public.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		// Координаты дополняются нулями до размера кривой, как требует RFC 7518
+		size := (public.Curve.Params().BitSize + 7) / 8
+		return payloads.JWK{
+			KeyType: "EC",
+			Curve:   public.Curve.Params().Name,
+			X:       encodeBase64(public.X.FillBytes(make([]byte, size))),
+			Y:       encodeBase64(public.Y.FillBytes(make([]byte, size))),
+		}
+	case ed25519.PublicKey:
+		return payloads.JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       encodeBase64(public),
+		}
+	}
+	return payloads.JWK{}
+}
+
+// thumbprint отпечаток ключа по RFC 7638: SHA-256 от обязательных полей JWK в лексикографическом порядке
+func (k *SigningKey) thumbprint() (string, error) {
+	jwk := k.jwk()
+	var fields any
+	switch jwk.KeyType {
+	case "RSA":
+		fields = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.Exponent, jwk.KeyType, jwk.Modulus}
+	case "EC":
+		fields = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	default:
+		fields = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return encodeBase64(sum[:]), nil
+}
+
+// encodeBase64 кодируем в base64url без дополнения, как принято в JOSE
+func encodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
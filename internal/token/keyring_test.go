@@ -0,0 +1,220 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"gofemart/internal/models"
+	"gofemart/internal/payloads"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestKey ключ подписи заданного алгоритма для тестов
+func newTestKey(t *testing.T, id string, alg string) *SigningKey {
+	t.Helper()
+	var private any
+	var err error
+	switch alg {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewSigningKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestGenerateSignsWithActiveKey(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			keys, err := NewKeyRing("", newTestKey(t, "2026-01", "RS256"), newTestKey(t, "2026-02", alg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			generator := NewJWTGenerator(keys, time.Hour)
+			tokenString, err := generator.Generate(&models.User{ID: 1}, "session")
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := generator.Parse(tokenString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Header["kid"] != "2026-02" || token.Method.Alg() != alg {
+				t.Errorf("expected token signed by 2026-02 with %s, got kid %v alg %s", alg, token.Header["kid"], token.Method.Alg())
+			}
+		})
+	}
+}
+
+func TestParseAfterRotation(t *testing.T) {
+	oldKey := newTestKey(t, "old", "RS256")
+	newKey := newTestKey(t, "new", "ES256")
+	before, err := NewKeyRing("old", oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := NewJWTGenerator(before, time.Hour).Generate(&models.User{ID: 1}, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Новый ключ стал активным, старый остался для проверки
+	rotated, err := NewKeyRing("new", oldKey, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewJWTGenerator(rotated, time.Hour).Parse(issued); err != nil {
+		t.Errorf("expected token of the previous key to be accepted, got %v", err)
+	}
+
+	// Старый ключ убрали из набора
+	retired, err := NewKeyRing("new", newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewJWTGenerator(retired, time.Hour).Parse(issued); err == nil {
+		t.Error("expected token of the retired key to be rejected")
+	}
+}
+
+func TestParseRejectsUnknownKeyID(t *testing.T) {
+	issuer, err := NewKeyRing("", newTestKey(t, "other", "ES256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := NewJWTGenerator(issuer, time.Hour).Generate(&models.User{ID: 1}, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeyRing("", newTestKey(t, "current", "ES256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewJWTGenerator(keys, time.Hour).Parse(issued); !errors.Is(err, ErrorUnknownKeyID) {
+		t.Errorf("expected ErrorUnknownKeyID, got %v", err)
+	}
+}
+
+func TestParseRejectsAlgorithmOfAnotherKey(t *testing.T) {
+	rsaKey := newTestKey(t, "rsa", "RS256")
+	edKey := newTestKey(t, "ed", "EdDSA")
+	keys, err := NewKeyRing("rsa", rsaKey, edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Токен подписан ключом Ed25519, но в kid указан ключ RSA
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{
+		Issuer:    "gofemart",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	token.Header["kid"] = "rsa"
+	tokenString, err := token.SignedString(edKey.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewJWTGenerator(keys, time.Hour).Parse(tokenString); err == nil {
+		t.Error("expected token with mismatched algorithm to be rejected")
+	}
+}
+
+func TestNewKeyRingRequiresSigningKey(t *testing.T) {
+	public := newTestKey(t, "public", "ES256")
+	public.Private = nil
+	if _, err := NewKeyRing("", public); !errors.Is(err, ErrorNoSigningKey) {
+		t.Errorf("expected ErrorNoSigningKey, got %v", err)
+	}
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(name string, blockType string, der []byte) {
+		body := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), body, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey("2026-01.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	writeKey("2026-02.pem", "PRIVATE KEY", ecDER)
+	edPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKIXPublicKey(edPublic)
+	writeKey("2026-03.pem", "PUBLIC KEY", edDER)
+	writeKey("README", "PUBLIC KEY", edDER)
+
+	keys, err := LoadKeyRing(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ключ 2026-03 без приватной части, поэтому подписывает 2026-02
+	if keys.Active().ID != "2026-02" {
+		t.Errorf("expected 2026-02 to be active, got %s", keys.Active().ID)
+	}
+
+	response := httptest.NewRecorder()
+	keys.JWKSHandler(response, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", response.Code)
+	}
+	var jwks payloads.JWKS
+	if err = json.Unmarshal(response.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	want := []payloads.JWK{
+		{KeyType: "RSA", ID: "2026-01", Algorithm: "RS256"},
+		{KeyType: "EC", ID: "2026-02", Algorithm: "ES256", Curve: "P-256"},
+		{KeyType: "OKP", ID: "2026-03", Algorithm: "EdDSA", Curve: "Ed25519"},
+	}
+	if len(jwks.Keys) != len(want) {
+		t.Fatalf("expected %d keys, got %+v", len(want), jwks.Keys)
+	}
+	for i, key := range jwks.Keys {
+		if key.KeyType != want[i].KeyType || key.ID != want[i].ID || key.Algorithm != want[i].Algorithm || key.Curve != want[i].Curve || key.Use != "sig" {
+			t.Errorf("expected %+v, got %+v", want[i], key)
+		}
+		if key.X == "" && key.Modulus == "" {
+			t.Errorf("expected public key material in %+v", key)
+		}
+	}
+}
+
+func TestSigningKeyThumbprint(t *testing.T) {
+	// Пример из RFC 7638, раздел 3.1
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: 65537}
+	key, err := NewSigningKey("", public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("unexpected thumbprint %s", key.ID)
+	}
+}
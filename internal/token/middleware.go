@@ -2,7 +2,7 @@ package token
 
 import (
 	"context"
-	config "gofemart/internal/configuration"
+	"github.com/golang-jwt/jwt/v5"
 	"gofemart/internal/helpers"
 	"gofemart/internal/logger"
 	"gofemart/internal/repositories"
@@ -18,18 +18,21 @@ type Key string
 // UserKey ключ авторизованного пользователя в контексте
 var UserKey Key = "user"
 
+// SessionKey ключ идентификатора сессии авторизованного пользователя в контексте
+var SessionKey Key = "session"
+
 // Authenticator выполняет аутентификацию и авторизацию пользователей с использованием токенов JWT и пула баз данных SQL
 type Authenticator struct {
 	dbPool          repositories.SQLExecutor
-	jwtKeys         *config.JWTKeys
+	keys            *KeyRing
 	tokenExpiration time.Duration
 }
 
-// NewAuthenticator создает и возвращает новый экземпляр Authenticator с указанными параметрами подключения к базе данных и токенам JWT.
-func NewAuthenticator(dbPool repositories.SQLExecutor, jwtKeys *config.JWTKeys, tokenExpiration time.Duration) *Authenticator {
+// NewAuthenticator создает и возвращает новый экземпляр Authenticator с указанными параметрами подключения к базе данных и набором ключей JWT.
+func NewAuthenticator(dbPool repositories.SQLExecutor, keys *KeyRing, tokenExpiration time.Duration) *Authenticator {
 	return &Authenticator{
 		dbPool:          dbPool,
-		jwtKeys:         jwtKeys,
+		keys:            keys,
 		tokenExpiration: tokenExpiration,
 	}
 }
@@ -47,13 +50,18 @@ func (a *Authenticator) Middleware(next http.Handler) http.Handler {
 			return
 		}
 		tknString = strings.TrimPrefix(tknString, "Bearer ")
-		generator := NewJWTGenerator(a.jwtKeys.Private, a.jwtKeys.Public, a.tokenExpiration)
+		generator := NewJWTGenerator(a.keys, a.tokenExpiration)
 		tkn, err := generator.Parse(tknString)
 		if err != nil {
 			logger.Log.Info(err)
 			helpers.ProcessResponseWithStatus("token is not valid", http.StatusUnauthorized, w)
 			return
 		}
+		claims, ok := tkn.Claims.(*jwt.RegisteredClaims)
+		if !ok || claims.ID == "" {
+			helpers.ProcessResponseWithStatus("token doesnt has session id", http.StatusUnauthorized, w)
+			return
+		}
 		idStr, err := tkn.Claims.GetSubject()
 		if err != nil {
 			logger.Log.Info(err)
@@ -67,6 +75,17 @@ func (a *Authenticator) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Токены отозванной сессии не принимаем, даже если они ещё не истекли
+		sessionRepository := repositories.NewSessionRepository(r.Context(), a.dbPool)
+		active, err := sessionRepository.ActiveSessionExists(claims.ID, userID)
+		if err != nil {
+			helpers.SetInternalError(err, w)
+			return
+		}
+		if !active {
+			helpers.ProcessResponseWithStatus("session is revoked", http.StatusUnauthorized, w)
+			return
+		}
 		userRepository := repositories.NewUserRepository(r.Context(), a.dbPool)
 		user, exists, err := userRepository.GetUserByID(userID)
 		if err != nil {
@@ -78,7 +97,8 @@ func (a *Authenticator) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		newR := r.WithContext(context.WithValue(r.Context(), UserKey, user))
+		ctx := context.WithValue(r.Context(), UserKey, user)
+		newR := r.WithContext(context.WithValue(ctx, SessionKey, claims.ID))
 		next.ServeHTTP(w, newR)
 	})
 }
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// HTTPTracing мидлваре, которое продолжает трассировку из заголовков запроса и создаёт спан запроса.
+// После маршрутизации спан переименовывается по шаблону маршрута chi, чтобы номера заказов не попадали в имя.
+func HTTPTracing(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		next.ServeHTTP(response, request)
+		routeContext := chi.RouteContext(request.Context())
+		if routeContext == nil {
+			return
+		}
+		if pattern := routeContext.RoutePattern(); pattern != "" {
+			span := trace.SpanFromContext(request.Context())
+			span.SetName(request.Method + " " + pattern)
+			span.SetAttributes(semconv.HTTPRoute(pattern))
+		}
+	})
+	return otelhttp.NewHandler(named, "HTTP", otelhttp.WithSpanNameFormatter(func(_ string, request *http.Request) string {
+		return request.Method
+	}))
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofemart/internal/logger"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры трассировки
+const (
+	ExporterNone   = "none"   // трассировка выключена
+	ExporterStdout = "stdout" // вывод спанов в стандартный вывод для локальной разработки
+	ExporterOTLP   = "otlp"   // отправка спанов по OTLP/HTTP
+)
+
+// tracerName имя трассировщика приложения
+const tracerName = "gofemart"
+
+// OrderNumberKey атрибут спана с номером заказа
+const OrderNumberKey = attribute.Key("order.number")
+
+// ErrorUnknownExporter Ошибка, что в конфигурации указан неизвестный экспортёр трассировки
+var ErrorUnknownExporter = errors.New("unknown tracing exporter")
+
+// Config Конфигурация трассировки
+type Config struct {
+	Exporter    string  // none, stdout или otlp
+	Endpoint    string  // адрес OTLP/HTTP коллектора, например http://localhost:4318
+	ServiceName string  // имя сервиса в трассировке
+	SampleRatio float64 // доля трассируемых запросов от 0 до 1
+}
+
+// Init настраиваем глобальный провайдер трассировки и распространение контекста трассировки.
+// Возвращает функцию, которая отправляет оставшиеся спаны и останавливает провайдер.
+func Init(ctx context.Context, cnf Config) (func(context.Context) error, error) {
+	// Распространение контекста нужно и без экспорта, чтобы не терять трассировку вызывающей стороны
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cnf.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cnf.Endpoint))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownExporter, cnf.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cnf.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cnf.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	logger.Log.Infow("Tracing enabled", "exporter", cnf.Exporter, "endpoint", cnf.Endpoint, "sampleRatio", cnf.SampleRatio)
+	return provider.Shutdown, nil
+}
+
+// Tracer трассировщик приложения из глобального провайдера
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start начинаем спан этапа обработки заказа с номером заказа в атрибутах
+func Start(ctx context.Context, name string, number string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(append(attributes, OrderNumberKey.String(number))...))
+}
+
+// End завершаем спан, отмечая ошибку, если она есть
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInitUnknownExporter(t *testing.T) {
+	if _, err := Init(context.Background(), Config{Exporter: "jaeger"}); !errors.Is(err, ErrorUnknownExporter) {
+		t.Errorf("expected error %v, got %v", ErrorUnknownExporter, err)
+	}
+}
+
+func TestInitNone(t *testing.T) {
+	shutdown, err := Init(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPTracingRoutePattern(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	router := chi.NewRouter()
+	router.Use(HTTPTracing)
+	router.Get("/api/user/orders/{number}", func(response http.ResponseWriter, request *http.Request) {
+		_, span := Start(request.Context(), "orders.get", chi.URLParam(request, "number"))
+		span.End()
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/user/orders/12345678903", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "GET /api/user/orders/{number}" {
+		t.Errorf("expected span name by route pattern, got %s", server.Name())
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("expected handler span to be a child of request span")
+	}
+	found := false
+	for _, attribute := range child.Attributes() {
+		if attribute.Key == OrderNumberKey && attribute.Value.AsString() == "12345678903" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected order number attribute, got %v", child.Attributes())
+	}
+}